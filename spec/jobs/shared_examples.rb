@@ -36,5 +36,234 @@ shared_examples_for 'common config.json' do
         end
       end
     end
+
+    context 'recursor_hedge_delay' do
+      it 'defaults to 100ms' do
+        expect(rendered['recursor_hedge_delay']).to eq('100ms')
+      end
+
+      context 'configured' do
+        let(:properties) { {'recursor_selection' => 'race', 'recursor_hedge_delay' => '250ms'} }
+
+        it 'writes recursor_hedge_delay' do
+          expect(rendered['recursor_selection']).to eq('race')
+          expect(rendered['recursor_hedge_delay']).to eq('250ms')
+        end
+      end
+    end
+
+    context 'cache' do
+      it 'does not serve stale answers by default' do
+        expect(rendered['cache']).to eq(
+          'enabled' => false,
+          'success_capacity' => 10000,
+          'denial_capacity' => 10000,
+          'min_ttl' => nil,
+          'max_ttl' => nil,
+          'prefetch' => {
+            'amount' => 0,
+            'duration' => '1m',
+            'percentage' => 10,
+          },
+          'serve_stale' => {
+            'enabled' => false,
+            'max_stale' => '24h',
+            'ttl' => '30s',
+          },
+          'nxdomain' => { 'enabled' => true, 'ttl' => nil },
+          'nodata' => { 'enabled' => true, 'ttl' => nil },
+          'servfail' => { 'enabled' => true, 'ttl' => '5s' },
+        )
+      end
+
+      context 'with sizing and prefetch configured' do
+        let(:properties) do
+          {
+            'cache' => {
+              'enabled' => true,
+              'success_capacity' => 1000,
+              'denial_capacity' => 100,
+              'min_ttl' => '10s',
+              'max_ttl' => '10m',
+              'prefetch' => { 'amount' => 5, 'duration' => '30s', 'percentage' => 20 },
+            },
+          }
+        end
+
+        it 'writes them' do
+          expect(rendered['cache']).to include(
+            'success_capacity' => 1000,
+            'denial_capacity' => 100,
+            'min_ttl' => '10s',
+            'max_ttl' => '10m',
+            'prefetch' => { 'amount' => 5, 'duration' => '30s', 'percentage' => 20 },
+          )
+        end
+      end
+
+      context 'with serve_stale configured' do
+        let(:properties) do
+          {
+            'cache' => {
+              'enabled' => true,
+              'serve_stale' => { 'enabled' => true, 'max_stale' => '1h', 'ttl' => '5s' },
+            },
+          }
+        end
+
+        it 'writes serve_stale' do
+          expect(rendered['cache']['serve_stale']).to eq(
+            'enabled' => true,
+            'max_stale' => '1h',
+            'ttl' => '5s',
+          )
+        end
+      end
+
+      context 'with negative caching configured' do
+        let(:properties) do
+          {
+            'cache' => {
+              'enabled' => true,
+              'nxdomain' => { 'ttl' => '1m' },
+              'nodata' => { 'enabled' => false },
+              'servfail' => { 'ttl' => '2s' },
+            },
+          }
+        end
+
+        it 'writes the policies' do
+          expect(rendered['cache']).to include(
+            'nxdomain' => { 'enabled' => true, 'ttl' => '1m' },
+            'nodata' => { 'enabled' => false, 'ttl' => nil },
+            'servfail' => { 'enabled' => true, 'ttl' => '2s' },
+          )
+        end
+      end
+    end
+
+    context 'recursor_probe' do
+      it 'is disabled by default' do
+        expect(rendered['recursor_probe']).to eq(
+          'enabled' => false,
+          'interval' => '10s',
+          'domain' => '.',
+          'query_type' => 'NS',
+        )
+      end
+
+      context 'configured' do
+        let(:properties) do
+          {
+            'recursor_probe' => {
+              'enabled' => true,
+              'interval' => '3s',
+              'domain' => 'example.com.',
+              'query_type' => 'A',
+            },
+          }
+        end
+
+        it 'writes recursor_probe' do
+          expect(rendered['recursor_probe']).to eq(
+            'enabled' => true,
+            'interval' => '3s',
+            'domain' => 'example.com.',
+            'query_type' => 'A',
+          )
+        end
+      end
+    end
+
+    context 'internal_ttl' do
+      it 'defaults to a zero ttl' do
+        expect(rendered['internal_ttl']).to eq('default' => 0, 'domains' => {})
+      end
+
+      context 'configured' do
+        let(:properties) { {'internal_ttl' => {'default' => 5, 'domains' => {'bosh.' => 30}}} }
+
+        it 'writes internal_ttl' do
+          expect(rendered['internal_ttl']).to eq('default' => 5, 'domains' => {'bosh.' => 30})
+        end
+      end
+    end
+
+    context 'dns_over_tls' do
+      it 'is disabled by default' do
+        expect(rendered['dns_over_tls']['enabled']).to eq(false)
+        expect(rendered['dns_over_tls']['port']).to eq(853)
+      end
+
+      context 'configured' do
+        let(:properties) { {'dns_over_tls' => {'enabled' => true, 'address' => '10.0.0.5', 'port' => 8853}} }
+
+        it 'writes dns_over_tls' do
+          expect(rendered['dns_over_tls']['enabled']).to eq(true)
+          expect(rendered['dns_over_tls']['address']).to eq('10.0.0.5')
+          expect(rendered['dns_over_tls']['port']).to eq(8853)
+          expect(rendered['dns_over_tls']['certificate_file']).to end_with('certs/dns_over_tls/server.crt')
+          expect(rendered['dns_over_tls']['private_key_file']).to end_with('certs/dns_over_tls/server.key')
+        end
+      end
+    end
+
+    context 'dns_over_https' do
+      it 'is disabled by default' do
+        expect(rendered['dns_over_https']['enabled']).to eq(false)
+        expect(rendered['dns_over_https']['port']).to eq(443)
+        expect(rendered['dns_over_https']['ca_file']).to eq('')
+      end
+
+      context 'configured with a client CA' do
+        let(:properties) { {'dns_over_https' => {'enabled' => true, 'port' => 8443, 'server' => {'tls' => {'ca' => 'some-ca'}}}} }
+
+        it 'writes dns_over_https' do
+          expect(rendered['dns_over_https']['enabled']).to eq(true)
+          expect(rendered['dns_over_https']['port']).to eq(8443)
+          expect(rendered['dns_over_https']['ca_file']).to end_with('certs/dns_over_https/server_ca.crt')
+        end
+      end
+    end
+
+    context 'recursor_tls' do
+      it 'is empty by default' do
+        expect(rendered['recursor_tls']['server_name']).to eq('')
+        expect(rendered['recursor_tls']['ca_file']).to eq('')
+      end
+
+      context 'configured' do
+        let(:properties) { {'recursor_tls' => {'server_name' => 'dns.example.com', 'ca' => 'some-ca'}} }
+
+        it 'writes recursor_tls' do
+          expect(rendered['recursor_tls']['server_name']).to eq('dns.example.com')
+          expect(rendered['recursor_tls']['ca_file']).to end_with('certs/recursor_tls/ca.crt')
+        end
+      end
+    end
+
+    context 'link_files_glob' do
+      it 'defaults to the links files of the jobs' do
+        expect(rendered['link_files_glob']).to end_with('links.json')
+      end
+    end
+  end
+
+  describe 'dns/links.json' do
+    let(:template) { job.template('dns/links.json') }
+    let(:properties) { {} }
+    let(:rendered) { JSON.parse(template.render(properties)) }
+
+    it 'defaults to no links' do
+      expect(rendered).to eq([])
+    end
+
+    context 'configured' do
+      let(:properties) { {'links' => [{'group_id' => '7', 'name' => 'http', 'port' => 8080}]} }
+
+      it 'writes links' do
+        expect(rendered).to eq([{'group_id' => '7', 'name' => 'http', 'port' => 8080}])
+      end
+    end
   end
 end
@@ -0,0 +1,315 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package apifakes
+
+import (
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/server/handlers"
+	"sync"
+)
+
+type FakeCacheInspector struct {
+	EntriesStub        func() []handlers.CacheEntry
+	entriesMutex       sync.RWMutex
+	entriesArgsForCall []struct {
+	}
+	entriesReturns struct {
+		result1 []handlers.CacheEntry
+	}
+	entriesReturnsOnCall map[int]struct {
+		result1 []handlers.CacheEntry
+	}
+	FlushStub        func() int
+	flushMutex       sync.RWMutex
+	flushArgsForCall []struct {
+	}
+	flushReturns struct {
+		result1 int
+	}
+	flushReturnsOnCall map[int]struct {
+		result1 int
+	}
+	FlushDomainStub        func(string) int
+	flushDomainMutex       sync.RWMutex
+	flushDomainArgsForCall []struct {
+		arg1 string
+	}
+	flushDomainReturns struct {
+		result1 int
+	}
+	flushDomainReturnsOnCall map[int]struct {
+		result1 int
+	}
+	FlushNameStub        func(string) int
+	flushNameMutex       sync.RWMutex
+	flushNameArgsForCall []struct {
+		arg1 string
+	}
+	flushNameReturns struct {
+		result1 int
+	}
+	flushNameReturnsOnCall map[int]struct {
+		result1 int
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeCacheInspector) Entries() []handlers.CacheEntry {
+	fake.entriesMutex.Lock()
+	ret, specificReturn := fake.entriesReturnsOnCall[len(fake.entriesArgsForCall)]
+	fake.entriesArgsForCall = append(fake.entriesArgsForCall, struct {
+	}{})
+	stub := fake.EntriesStub
+	fakeReturns := fake.entriesReturns
+	fake.recordInvocation("Entries", []interface{}{})
+	fake.entriesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCacheInspector) EntriesCallCount() int {
+	fake.entriesMutex.RLock()
+	defer fake.entriesMutex.RUnlock()
+	return len(fake.entriesArgsForCall)
+}
+
+func (fake *FakeCacheInspector) EntriesCalls(stub func() []handlers.CacheEntry) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = stub
+}
+
+func (fake *FakeCacheInspector) EntriesReturns(result1 []handlers.CacheEntry) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = nil
+	fake.entriesReturns = struct {
+		result1 []handlers.CacheEntry
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) EntriesReturnsOnCall(i int, result1 []handlers.CacheEntry) {
+	fake.entriesMutex.Lock()
+	defer fake.entriesMutex.Unlock()
+	fake.EntriesStub = nil
+	if fake.entriesReturnsOnCall == nil {
+		fake.entriesReturnsOnCall = make(map[int]struct {
+			result1 []handlers.CacheEntry
+		})
+	}
+	fake.entriesReturnsOnCall[i] = struct {
+		result1 []handlers.CacheEntry
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) Flush() int {
+	fake.flushMutex.Lock()
+	ret, specificReturn := fake.flushReturnsOnCall[len(fake.flushArgsForCall)]
+	fake.flushArgsForCall = append(fake.flushArgsForCall, struct {
+	}{})
+	stub := fake.FlushStub
+	fakeReturns := fake.flushReturns
+	fake.recordInvocation("Flush", []interface{}{})
+	fake.flushMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCacheInspector) FlushCallCount() int {
+	fake.flushMutex.RLock()
+	defer fake.flushMutex.RUnlock()
+	return len(fake.flushArgsForCall)
+}
+
+func (fake *FakeCacheInspector) FlushCalls(stub func() int) {
+	fake.flushMutex.Lock()
+	defer fake.flushMutex.Unlock()
+	fake.FlushStub = stub
+}
+
+func (fake *FakeCacheInspector) FlushReturns(result1 int) {
+	fake.flushMutex.Lock()
+	defer fake.flushMutex.Unlock()
+	fake.FlushStub = nil
+	fake.flushReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) FlushReturnsOnCall(i int, result1 int) {
+	fake.flushMutex.Lock()
+	defer fake.flushMutex.Unlock()
+	fake.FlushStub = nil
+	if fake.flushReturnsOnCall == nil {
+		fake.flushReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.flushReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) FlushDomain(arg1 string) int {
+	fake.flushDomainMutex.Lock()
+	ret, specificReturn := fake.flushDomainReturnsOnCall[len(fake.flushDomainArgsForCall)]
+	fake.flushDomainArgsForCall = append(fake.flushDomainArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.FlushDomainStub
+	fakeReturns := fake.flushDomainReturns
+	fake.recordInvocation("FlushDomain", []interface{}{arg1})
+	fake.flushDomainMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCacheInspector) FlushDomainCallCount() int {
+	fake.flushDomainMutex.RLock()
+	defer fake.flushDomainMutex.RUnlock()
+	return len(fake.flushDomainArgsForCall)
+}
+
+func (fake *FakeCacheInspector) FlushDomainCalls(stub func(string) int) {
+	fake.flushDomainMutex.Lock()
+	defer fake.flushDomainMutex.Unlock()
+	fake.FlushDomainStub = stub
+}
+
+func (fake *FakeCacheInspector) FlushDomainArgsForCall(i int) string {
+	fake.flushDomainMutex.RLock()
+	defer fake.flushDomainMutex.RUnlock()
+	argsForCall := fake.flushDomainArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCacheInspector) FlushDomainReturns(result1 int) {
+	fake.flushDomainMutex.Lock()
+	defer fake.flushDomainMutex.Unlock()
+	fake.FlushDomainStub = nil
+	fake.flushDomainReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) FlushDomainReturnsOnCall(i int, result1 int) {
+	fake.flushDomainMutex.Lock()
+	defer fake.flushDomainMutex.Unlock()
+	fake.FlushDomainStub = nil
+	if fake.flushDomainReturnsOnCall == nil {
+		fake.flushDomainReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.flushDomainReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) FlushName(arg1 string) int {
+	fake.flushNameMutex.Lock()
+	ret, specificReturn := fake.flushNameReturnsOnCall[len(fake.flushNameArgsForCall)]
+	fake.flushNameArgsForCall = append(fake.flushNameArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.FlushNameStub
+	fakeReturns := fake.flushNameReturns
+	fake.recordInvocation("FlushName", []interface{}{arg1})
+	fake.flushNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCacheInspector) FlushNameCallCount() int {
+	fake.flushNameMutex.RLock()
+	defer fake.flushNameMutex.RUnlock()
+	return len(fake.flushNameArgsForCall)
+}
+
+func (fake *FakeCacheInspector) FlushNameCalls(stub func(string) int) {
+	fake.flushNameMutex.Lock()
+	defer fake.flushNameMutex.Unlock()
+	fake.FlushNameStub = stub
+}
+
+func (fake *FakeCacheInspector) FlushNameArgsForCall(i int) string {
+	fake.flushNameMutex.RLock()
+	defer fake.flushNameMutex.RUnlock()
+	argsForCall := fake.flushNameArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCacheInspector) FlushNameReturns(result1 int) {
+	fake.flushNameMutex.Lock()
+	defer fake.flushNameMutex.Unlock()
+	fake.FlushNameStub = nil
+	fake.flushNameReturns = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) FlushNameReturnsOnCall(i int, result1 int) {
+	fake.flushNameMutex.Lock()
+	defer fake.flushNameMutex.Unlock()
+	fake.FlushNameStub = nil
+	if fake.flushNameReturnsOnCall == nil {
+		fake.flushNameReturnsOnCall = make(map[int]struct {
+			result1 int
+		})
+	}
+	fake.flushNameReturnsOnCall[i] = struct {
+		result1 int
+	}{result1}
+}
+
+func (fake *FakeCacheInspector) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.entriesMutex.RLock()
+	defer fake.entriesMutex.RUnlock()
+	fake.flushMutex.RLock()
+	defer fake.flushMutex.RUnlock()
+	fake.flushDomainMutex.RLock()
+	defer fake.flushDomainMutex.RUnlock()
+	fake.flushNameMutex.RLock()
+	defer fake.flushNameMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeCacheInspector) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ api.CacheInspector = new(FakeCacheInspector)
@@ -0,0 +1,102 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package apifakes
+
+import (
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/server/handlers"
+	"sync"
+)
+
+type FakeRecursorRanker struct {
+	RankingsStub        func() []handlers.RecursorRanking
+	rankingsMutex       sync.RWMutex
+	rankingsArgsForCall []struct {
+	}
+	rankingsReturns struct {
+		result1 []handlers.RecursorRanking
+	}
+	rankingsReturnsOnCall map[int]struct {
+		result1 []handlers.RecursorRanking
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRecursorRanker) Rankings() []handlers.RecursorRanking {
+	fake.rankingsMutex.Lock()
+	ret, specificReturn := fake.rankingsReturnsOnCall[len(fake.rankingsArgsForCall)]
+	fake.rankingsArgsForCall = append(fake.rankingsArgsForCall, struct {
+	}{})
+	stub := fake.RankingsStub
+	fakeReturns := fake.rankingsReturns
+	fake.recordInvocation("Rankings", []interface{}{})
+	fake.rankingsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecursorRanker) RankingsCallCount() int {
+	fake.rankingsMutex.RLock()
+	defer fake.rankingsMutex.RUnlock()
+	return len(fake.rankingsArgsForCall)
+}
+
+func (fake *FakeRecursorRanker) RankingsCalls(stub func() []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = stub
+}
+
+func (fake *FakeRecursorRanker) RankingsReturns(result1 []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = nil
+	fake.rankingsReturns = struct {
+		result1 []handlers.RecursorRanking
+	}{result1}
+}
+
+func (fake *FakeRecursorRanker) RankingsReturnsOnCall(i int, result1 []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = nil
+	if fake.rankingsReturnsOnCall == nil {
+		fake.rankingsReturnsOnCall = make(map[int]struct {
+			result1 []handlers.RecursorRanking
+		})
+	}
+	fake.rankingsReturnsOnCall[i] = struct {
+		result1 []handlers.RecursorRanking
+	}{result1}
+}
+
+func (fake *FakeRecursorRanker) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.rankingsMutex.RLock()
+	defer fake.rankingsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRecursorRanker) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ api.RecursorRanker = new(FakeRecursorRanker)
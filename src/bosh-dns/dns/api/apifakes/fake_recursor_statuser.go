@@ -0,0 +1,102 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package apifakes
+
+import (
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/server/handlers"
+	"sync"
+)
+
+type FakeRecursorStatuser struct {
+	StatusesStub        func() []handlers.RecursorStatus
+	statusesMutex       sync.RWMutex
+	statusesArgsForCall []struct {
+	}
+	statusesReturns struct {
+		result1 []handlers.RecursorStatus
+	}
+	statusesReturnsOnCall map[int]struct {
+		result1 []handlers.RecursorStatus
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRecursorStatuser) Statuses() []handlers.RecursorStatus {
+	fake.statusesMutex.Lock()
+	ret, specificReturn := fake.statusesReturnsOnCall[len(fake.statusesArgsForCall)]
+	fake.statusesArgsForCall = append(fake.statusesArgsForCall, struct {
+	}{})
+	stub := fake.StatusesStub
+	fakeReturns := fake.statusesReturns
+	fake.recordInvocation("Statuses", []interface{}{})
+	fake.statusesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecursorStatuser) StatusesCallCount() int {
+	fake.statusesMutex.RLock()
+	defer fake.statusesMutex.RUnlock()
+	return len(fake.statusesArgsForCall)
+}
+
+func (fake *FakeRecursorStatuser) StatusesCalls(stub func() []handlers.RecursorStatus) {
+	fake.statusesMutex.Lock()
+	defer fake.statusesMutex.Unlock()
+	fake.StatusesStub = stub
+}
+
+func (fake *FakeRecursorStatuser) StatusesReturns(result1 []handlers.RecursorStatus) {
+	fake.statusesMutex.Lock()
+	defer fake.statusesMutex.Unlock()
+	fake.StatusesStub = nil
+	fake.statusesReturns = struct {
+		result1 []handlers.RecursorStatus
+	}{result1}
+}
+
+func (fake *FakeRecursorStatuser) StatusesReturnsOnCall(i int, result1 []handlers.RecursorStatus) {
+	fake.statusesMutex.Lock()
+	defer fake.statusesMutex.Unlock()
+	fake.StatusesStub = nil
+	if fake.statusesReturnsOnCall == nil {
+		fake.statusesReturnsOnCall = make(map[int]struct {
+			result1 []handlers.RecursorStatus
+		})
+	}
+	fake.statusesReturnsOnCall[i] = struct {
+		result1 []handlers.RecursorStatus
+	}{result1}
+}
+
+func (fake *FakeRecursorStatuser) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.statusesMutex.RLock()
+	defer fake.statusesMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRecursorStatuser) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ api.RecursorStatuser = new(FakeRecursorStatuser)
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"bosh-dns/dns/server/handlers"
+)
+
+//counterfeiter:generate . CacheInspector
+
+type CacheInspector interface {
+	Entries() []handlers.CacheEntry
+	Flush() int
+	FlushName(name string) int
+	FlushDomain(domain string) int
+}
+
+// CacheInspectors inspects and flushes several caches as one.
+type CacheInspectors []CacheInspector
+
+func (c CacheInspectors) Entries() []handlers.CacheEntry {
+	entries := []handlers.CacheEntry{}
+	for _, inspector := range c {
+		entries = append(entries, inspector.Entries()...)
+	}
+	return entries
+}
+
+func (c CacheInspectors) Flush() int {
+	flushed := 0
+	for _, inspector := range c {
+		flushed += inspector.Flush()
+	}
+	return flushed
+}
+
+func (c CacheInspectors) FlushName(name string) int {
+	flushed := 0
+	for _, inspector := range c {
+		flushed += inspector.FlushName(name)
+	}
+	return flushed
+}
+
+func (c CacheInspectors) FlushDomain(domain string) int {
+	flushed := 0
+	for _, inspector := range c {
+		flushed += inspector.FlushDomain(domain)
+	}
+	return flushed
+}
+
+// CacheHandler lists the cached answers on GET, optionally only those for
+// the name given by the name parameter. On DELETE it flushes the answers for
+// the name or domain parameter, or every answer when neither is given.
+type CacheHandler struct {
+	inspector CacheInspector
+}
+
+func NewCacheHandler(inspector CacheInspector) *CacheHandler {
+	return &CacheHandler{
+		inspector: inspector,
+	}
+}
+
+func (h *CacheHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.list(w, r)
+	case http.MethodDelete:
+		h.flush(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *CacheHandler) list(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name != "" {
+		name = strings.ToLower(dns.Fqdn(name))
+	}
+
+	entries := h.inspector.Entries()
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Type < entries[j].Type
+	})
+
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if name != "" && entry.Name != name {
+			continue
+		}
+
+		records := []string{}
+		for _, rr := range entry.Records {
+			records = append(records, rr.String())
+		}
+
+		encoder.Encode(CacheEntry{ //nolint:errcheck
+			Name:    entry.Name,
+			Type:    dns.TypeToString[entry.Type],
+			Rcode:   dns.RcodeToString[entry.Rcode],
+			TTL:     int(entry.TTL.Seconds()),
+			Stale:   entry.Stale,
+			Records: records,
+		})
+	}
+}
+
+func (h *CacheHandler) flush(w http.ResponseWriter, r *http.Request) {
+	name, domain := r.URL.Query().Get("name"), r.URL.Query().Get("domain")
+
+	var flushed int
+	switch {
+	case name != "" && domain != "":
+		http.Error(w, "only one of name and domain may be given", http.StatusBadRequest)
+		return
+	case name != "":
+		flushed = h.inspector.FlushName(name)
+	case domain != "":
+		flushed = h.inspector.FlushDomain(domain)
+	default:
+		flushed = h.inspector.Flush()
+	}
+
+	json.NewEncoder(w).Encode(CacheFlush{Flushed: flushed}) //nolint:errcheck
+}
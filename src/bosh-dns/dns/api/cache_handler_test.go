@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/api/apifakes"
+	"bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("CacheHandler", func() {
+	var (
+		fakeInspector *apifakes.FakeCacheInspector
+		handler       *api.CacheHandler
+
+		w *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		fakeInspector = &apifakes.FakeCacheInspector{}
+		handler = api.NewCacheHandler(fakeInspector)
+		w = httptest.NewRecorder()
+	})
+
+	decodeEntries := func() []api.CacheEntry {
+		entries := []api.CacheEntry{}
+		decoder := json.NewDecoder(w.Result().Body)
+		for decoder.More() {
+			var entry api.CacheEntry
+			Expect(decoder.Decode(&entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	decodeFlush := func() api.CacheFlush {
+		var flush api.CacheFlush
+		Expect(json.NewDecoder(w.Result().Body).Decode(&flush)).To(Succeed())
+		return flush
+	}
+
+	Describe("GET", func() {
+		BeforeEach(func() {
+			fakeInspector.EntriesReturns([]handlers.CacheEntry{
+				{
+					Name:  "www.example.com.",
+					Type:  dns.TypeA,
+					Rcode: dns.RcodeSuccess,
+					TTL:   42500 * time.Millisecond,
+					Records: []dns.RR{&dns.A{
+						Hdr: dns.RR_Header{Name: "www.example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+						A:   net.ParseIP("10.0.0.1"),
+					}},
+				},
+				{Name: "missing.example.com.", Type: dns.TypeAAAA, Rcode: dns.RcodeNameError, TTL: 5 * time.Second},
+				{Name: "www.example.com.", Type: dns.TypeAAAA, Rcode: dns.RcodeSuccess, Stale: true},
+			})
+		})
+
+		It("returns a json line for each cached answer sorted by name and type", func() {
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/cache", nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			Expect(decodeEntries()).To(Equal([]api.CacheEntry{
+				{Name: "missing.example.com.", Type: "AAAA", Rcode: "NXDOMAIN", TTL: 5, Records: []string{}},
+				{Name: "www.example.com.", Type: "A", Rcode: "NOERROR", TTL: 42, Records: []string{"www.example.com.\t60\tIN\tA\t10.0.0.1"}},
+				{Name: "www.example.com.", Type: "AAAA", Rcode: "NOERROR", TTL: 0, Stale: true, Records: []string{}},
+			}))
+		})
+
+		It("looks up a single name", func() {
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/cache?name=WWW.example.com", nil))
+
+			entries := decodeEntries()
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Name).To(Equal("www.example.com."))
+			Expect(entries[1].Name).To(Equal("www.example.com."))
+		})
+	})
+
+	Describe("DELETE", func() {
+		It("flushes everything", func() {
+			fakeInspector.FlushReturns(3)
+			handler.ServeHTTP(w, httptest.NewRequest("DELETE", "/cache", nil))
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(decodeFlush()).To(Equal(api.CacheFlush{Flushed: 3}))
+			Expect(fakeInspector.FlushCallCount()).To(Equal(1))
+		})
+
+		It("flushes a single name", func() {
+			fakeInspector.FlushNameReturns(1)
+			handler.ServeHTTP(w, httptest.NewRequest("DELETE", "/cache?name=www.example.com", nil))
+
+			Expect(decodeFlush()).To(Equal(api.CacheFlush{Flushed: 1}))
+			Expect(fakeInspector.FlushNameArgsForCall(0)).To(Equal("www.example.com"))
+			Expect(fakeInspector.FlushCallCount()).To(Equal(0))
+		})
+
+		It("flushes a domain", func() {
+			fakeInspector.FlushDomainReturns(2)
+			handler.ServeHTTP(w, httptest.NewRequest("DELETE", "/cache?domain=example.com", nil))
+
+			Expect(decodeFlush()).To(Equal(api.CacheFlush{Flushed: 2}))
+			Expect(fakeInspector.FlushDomainArgsForCall(0)).To(Equal("example.com"))
+			Expect(fakeInspector.FlushCallCount()).To(Equal(0))
+		})
+
+		It("rejects both a name and a domain", func() {
+			handler.ServeHTTP(w, httptest.NewRequest("DELETE", "/cache?name=www.example.com&domain=example.com", nil))
+
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(fakeInspector.FlushCallCount()).To(Equal(0))
+			Expect(fakeInspector.FlushNameCallCount()).To(Equal(0))
+			Expect(fakeInspector.FlushDomainCallCount()).To(Equal(0))
+		})
+	})
+
+	It("rejects other methods", func() {
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/cache", nil))
+
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+var _ = Describe("CacheInspectors", func() {
+	var (
+		first, second *apifakes.FakeCacheInspector
+		inspectors    api.CacheInspectors
+	)
+
+	BeforeEach(func() {
+		first = &apifakes.FakeCacheInspector{}
+		second = &apifakes.FakeCacheInspector{}
+		inspectors = api.CacheInspectors{first, second}
+	})
+
+	It("lists the entries of every cache", func() {
+		first.EntriesReturns([]handlers.CacheEntry{{Name: "example.com."}})
+		second.EntriesReturns([]handlers.CacheEntry{{Name: "example.org."}, {Name: "example.net."}})
+
+		Expect(inspectors.Entries()).To(Equal([]handlers.CacheEntry{
+			{Name: "example.com."},
+			{Name: "example.org."},
+			{Name: "example.net."},
+		}))
+	})
+
+	It("flushes every cache", func() {
+		first.FlushReturns(1)
+		second.FlushReturns(2)
+		Expect(inspectors.Flush()).To(Equal(3))
+
+		first.FlushNameReturns(1)
+		second.FlushNameReturns(0)
+		Expect(inspectors.FlushName("example.com.")).To(Equal(1))
+		Expect(first.FlushNameArgsForCall(0)).To(Equal("example.com."))
+		Expect(second.FlushNameArgsForCall(0)).To(Equal("example.com."))
+
+		first.FlushDomainReturns(2)
+		second.FlushDomainReturns(3)
+		Expect(inspectors.FlushDomain("example.com.")).To(Equal(5))
+		Expect(second.FlushDomainArgsForCall(0)).To(Equal("example.com."))
+	})
+})
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+const dnsMessageContentType = "application/dns-message"
+
+// DNSOverHTTPSHandler serves RFC 8484 DNS queries by passing them on to a
+// DNS handler, typically the same mux serving the plain listeners.
+type DNSOverHTTPSHandler struct {
+	handler dns.Handler
+	logger  logger.Logger
+	logTag  string
+}
+
+func NewDNSOverHTTPSHandler(handler dns.Handler, logger logger.Logger) *DNSOverHTTPSHandler {
+	return &DNSOverHTTPSHandler{
+		handler: handler,
+		logger:  logger,
+		logTag:  "DNSOverHTTPSHandler",
+	}
+}
+
+func (h *DNSOverHTTPSHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var packed []byte
+	var err error
+
+	switch r.Method {
+	case http.MethodGet:
+		packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		if err != nil || len(packed) == 0 {
+			http.Error(w, "missing or malformed dns parameter", http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != dnsMessageContentType {
+			http.Error(w, fmt.Sprintf("expected content type %s", dnsMessageContentType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		packed, err = io.ReadAll(io.LimitReader(r.Body, dns.MaxMsgSize+1))
+		if err != nil || len(packed) > dns.MaxMsgSize {
+			http.Error(w, "malformed dns message", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	requestMsg := &dns.Msg{}
+	if err := requestMsg.Unpack(packed); err != nil {
+		http.Error(w, "malformed dns message", http.StatusBadRequest)
+		return
+	}
+
+	localAddr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		localAddr = &net.TCPAddr{}
+	}
+
+	responseWriter := &httpResponseWriter{
+		localAddr:  localAddr,
+		remoteAddr: tcpAddr(r.RemoteAddr),
+	}
+	h.handler.ServeDNS(responseWriter, requestMsg)
+
+	if responseWriter.msg == nil {
+		h.logger.Error(h.logTag, "no response for request %d", requestMsg.Id)
+		http.Error(w, "no response", http.StatusInternalServerError)
+		return
+	}
+
+	responseBytes, err := responseWriter.msg.Pack()
+	if err != nil {
+		h.logger.Error(h.logTag, "failed to pack response: %s", err.Error())
+		http.Error(w, "failed to pack response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dnsMessageContentType)
+	w.Header().Set("Cache-Control", "max-age="+strconv.FormatUint(uint64(minimumTTL(responseWriter.msg)), 10))
+	w.Write(responseBytes) //nolint:errcheck
+}
+
+// minimumTTL is the freshness lifetime of a response per RFC 8484 section 5.1.
+func minimumTTL(msg *dns.Msg) uint32 {
+	var ttl uint32
+	found := false
+
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+
+			if !found || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+				found = true
+			}
+		}
+	}
+
+	return ttl
+}
+
+func tcpAddr(hostPort string) net.Addr {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return &net.TCPAddr{IP: net.ParseIP(hostPort)}
+	}
+
+	portNumber, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(host), Port: portNumber}
+}
+
+// httpResponseWriter collects the response of a DNS handler for a query
+// received over HTTP. Its addresses are TCP addresses, so that responses are
+// never truncated to fit a datagram.
+type httpResponseWriter struct {
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	msg        *dns.Msg
+}
+
+func (w *httpResponseWriter) LocalAddr() net.Addr  { return w.localAddr }
+func (w *httpResponseWriter) RemoteAddr() net.Addr { return w.remoteAddr }
+
+func (w *httpResponseWriter) WriteMsg(msg *dns.Msg) error {
+	w.msg = msg
+	return nil
+}
+
+func (w *httpResponseWriter) Write(packed []byte) (int, error) {
+	msg := &dns.Msg{}
+	if err := msg.Unpack(packed); err != nil {
+		return 0, err
+	}
+
+	w.msg = msg
+	return len(packed), nil
+}
+
+func (w *httpResponseWriter) Close() error        { return nil }
+func (w *httpResponseWriter) TsigStatus() error   { return nil }
+func (w *httpResponseWriter) TsigTimersOnly(bool) {}
+func (w *httpResponseWriter) Hijack()             {}
@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/api"
+)
+
+var _ = Describe("DNSOverHTTPSHandler", func() {
+	var (
+		handler    *api.DNSOverHTTPSHandler
+		fakeLogger *loggerfakes.FakeLogger
+		remoteAddr net.Addr
+		requests   []*dns.Msg
+		respond    func(dns.ResponseWriter, *dns.Msg)
+
+		w *httptest.ResponseRecorder
+	)
+
+	packedQuery := func() []byte {
+		m := &dns.Msg{}
+		m.SetQuestion("my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
+		packed, err := m.Pack()
+		Expect(err).NotTo(HaveOccurred())
+		return packed
+	}
+
+	unpackResponse := func() *dns.Msg {
+		m := &dns.Msg{}
+		Expect(m.Unpack(w.Body.Bytes())).To(Succeed())
+		return m
+	}
+
+	BeforeEach(func() {
+		fakeLogger = &loggerfakes.FakeLogger{}
+		requests = []*dns.Msg{}
+		w = httptest.NewRecorder()
+
+		respond = func(writer dns.ResponseWriter, req *dns.Msg) {
+			resp := &dns.Msg{}
+			resp.SetReply(req)
+			resp.Answer = []dns.RR{
+				&dns.A{Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP("10.0.0.1")},
+				&dns.A{Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 10}, A: net.ParseIP("10.0.0.2")},
+			}
+			Expect(writer.WriteMsg(resp)).To(Succeed())
+		}
+
+		handler = api.NewDNSOverHTTPSHandler(dns.HandlerFunc(func(writer dns.ResponseWriter, req *dns.Msg) {
+			requests = append(requests, req)
+			remoteAddr = writer.RemoteAddr()
+			respond(writer, req)
+		}), fakeLogger)
+	})
+
+	It("answers GET requests", func() {
+		r := httptest.NewRequest("GET", "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(packedQuery()), nil)
+		r.RemoteAddr = "192.168.0.1:4567"
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/dns-message"))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Question[0].Name).To(Equal("my-instance.my-group.my-network.my-deployment.bosh."))
+		Expect(remoteAddr).To(Equal(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 4567}))
+
+		resp := unpackResponse()
+		Expect(resp.Answer).To(HaveLen(2))
+	})
+
+	It("answers POST requests", func() {
+		r := httptest.NewRequest("POST", "/dns-query", bytes.NewReader(packedQuery()))
+		r.Header.Set("Content-Type", "application/dns-message")
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(requests).To(HaveLen(1))
+		Expect(unpackResponse().Answer).To(HaveLen(2))
+	})
+
+	It("answers POST requests whose content type has parameters", func() {
+		r := httptest.NewRequest("POST", "/dns-query", bytes.NewReader(packedQuery()))
+		r.Header.Set("Content-Type", "Application/DNS-Message; charset=binary")
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("sets the freshness lifetime to the lowest TTL of the response", func() {
+		r := httptest.NewRequest("GET", "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(packedQuery()), nil)
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Header().Get("Cache-Control")).To(Equal("max-age=10"))
+	})
+
+	It("rejects GET requests without a valid dns parameter", func() {
+		r := httptest.NewRequest("GET", "/dns-query?dns=not+base64", nil)
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("rejects POST requests of another content type", func() {
+		r := httptest.NewRequest("POST", "/dns-query", bytes.NewReader(packedQuery()))
+		r.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("rejects malformed dns messages", func() {
+		r := httptest.NewRequest("POST", "/dns-query", bytes.NewReader([]byte{1, 2, 3}))
+		r.Header.Set("Content-Type", "application/dns-message")
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("rejects other methods", func() {
+		r := httptest.NewRequest("PUT", "/dns-query", nil)
+		handler.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(w.Header().Get("Allow")).To(Equal("GET, POST"))
+	})
+
+	Context("when the dns handler does not respond", func() {
+		BeforeEach(func() {
+			respond = func(dns.ResponseWriter, *dns.Msg) {}
+		})
+
+		It("returns an internal server error", func() {
+			r := httptest.NewRequest("GET", "/dns-query?dns="+base64.RawURLEncoding.EncodeToString(packedQuery()), nil)
+			handler.ServeHTTP(w, r)
+
+			body, err := io.ReadAll(w.Result().Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(string(body)).To(ContainSubstring("no response"))
+			Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+		})
+	})
+})
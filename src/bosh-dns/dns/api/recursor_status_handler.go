@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"bosh-dns/dns/server/handlers"
+)
+
+//counterfeiter:generate . RecursorStatuser
+
+type RecursorStatuser interface {
+	Statuses() []handlers.RecursorStatus
+}
+
+type RecursorStatusHandler struct {
+	statuser RecursorStatuser
+}
+
+func NewRecursorStatusHandler(statuser RecursorStatuser) *RecursorStatusHandler {
+	return &RecursorStatusHandler{
+		statuser: statuser,
+	}
+}
+
+func (h *RecursorStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoder := json.NewEncoder(w)
+
+	for _, status := range h.statuser.Statuses() {
+		record := RecursorStatus{
+			Address: status.Recursor,
+			Up:      status.Up,
+			Error:   status.Error,
+		}
+		if !status.LastProbe.IsZero() {
+			record.LastProbe = status.LastProbe.UTC().Format(time.RFC3339)
+		}
+
+		encoder.Encode(record) //nolint:errcheck
+	}
+}
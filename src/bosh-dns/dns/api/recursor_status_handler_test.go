@@ -0,0 +1,68 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/api/apifakes"
+	"bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("RecursorStatusHandler", func() {
+	var (
+		fakeStatuser *apifakes.FakeRecursorStatuser
+		handler      *api.RecursorStatusHandler
+
+		w *httptest.ResponseRecorder
+		r *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeStatuser = &apifakes.FakeRecursorStatuser{}
+		handler = api.NewRecursorStatusHandler(fakeStatuser)
+		r = httptest.NewRequest("GET", "/", nil)
+		w = httptest.NewRecorder()
+	})
+
+	It("returns status ok", func() {
+		handler.ServeHTTP(w, r)
+		response := w.Result()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	Context("when recursors have been probed", func() {
+		BeforeEach(func() {
+			lastProbe := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+			fakeStatuser.StatusesReturns([]handlers.RecursorStatus{
+				{Recursor: "10.0.0.1:53", Up: true, LastProbe: lastProbe},
+				{Recursor: "10.0.0.2:53", Up: false, LastProbe: lastProbe, Error: "i/o timeout"},
+				{Recursor: "10.0.0.3:53", Up: true},
+			})
+		})
+
+		It("returns a json line for each recursor", func() {
+			handler.ServeHTTP(w, r)
+			decoder := json.NewDecoder(w.Result().Body)
+
+			var status api.RecursorStatus
+			Expect(decoder.Decode(&status)).To(Succeed())
+			Expect(status).To(Equal(api.RecursorStatus{Address: "10.0.0.1:53", Up: true, LastProbe: "2020-05-17T10:30:00Z"}))
+
+			status = api.RecursorStatus{}
+			Expect(decoder.Decode(&status)).To(Succeed())
+			Expect(status).To(Equal(api.RecursorStatus{Address: "10.0.0.2:53", Up: false, LastProbe: "2020-05-17T10:30:00Z", Error: "i/o timeout"}))
+
+			status = api.RecursorStatus{}
+			Expect(decoder.Decode(&status)).To(Succeed())
+			Expect(status).To(Equal(api.RecursorStatus{Address: "10.0.0.3:53", Up: true}))
+
+			Expect(decoder.More()).To(BeFalse())
+		})
+	})
+})
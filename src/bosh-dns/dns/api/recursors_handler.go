@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"bosh-dns/dns/server/handlers"
+)
+
+//counterfeiter:generate . RecursorRanker
+
+type RecursorRanker interface {
+	Rankings() []handlers.RecursorRanking
+}
+
+type RecursorsHandler struct {
+	ranker RecursorRanker
+}
+
+func NewRecursorsHandler(ranker RecursorRanker) *RecursorsHandler {
+	return &RecursorsHandler{
+		ranker: ranker,
+	}
+}
+
+func (h *RecursorsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoder := json.NewEncoder(w)
+
+	for _, ranking := range h.ranker.Rankings() {
+		encoder.Encode(Recursor{ //nolint:errcheck
+			Address:     ranking.Recursor,
+			Rank:        ranking.Rank,
+			LatencyMS:   float64(ranking.Latency.Microseconds()) / 1000,
+			FailureRate: ranking.FailureRate,
+			Samples:     ranking.Samples,
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/api"
+	"bosh-dns/dns/api/apifakes"
+	"bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("RecursorsHandler", func() {
+	var (
+		fakeRanker *apifakes.FakeRecursorRanker
+		handler    *api.RecursorsHandler
+
+		w *httptest.ResponseRecorder
+		r *http.Request
+	)
+
+	BeforeEach(func() {
+		fakeRanker = &apifakes.FakeRecursorRanker{}
+		handler = api.NewRecursorsHandler(fakeRanker)
+		r = httptest.NewRequest("GET", "/", nil)
+		w = httptest.NewRecorder()
+	})
+
+	It("returns status ok", func() {
+		handler.ServeHTTP(w, r)
+		response := w.Result()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	Context("when recursors have been ranked", func() {
+		BeforeEach(func() {
+			fakeRanker.RankingsReturns([]handlers.RecursorRanking{
+				{Recursor: "10.0.0.2:53", Rank: 1, Latency: 1500 * time.Microsecond, FailureRate: 0.1, Samples: 12},
+				{Recursor: "10.0.0.1:53", Rank: 2, Latency: 20 * time.Millisecond, FailureRate: 0.9, Samples: 3},
+			})
+		})
+
+		It("returns a json line for each recursor in rank order", func() {
+			handler.ServeHTTP(w, r)
+			decoder := json.NewDecoder(w.Result().Body)
+
+			var recursor api.Recursor
+			Expect(decoder.Decode(&recursor)).To(Succeed())
+			Expect(recursor).To(Equal(api.Recursor{
+				Address:     "10.0.0.2:53",
+				Rank:        1,
+				LatencyMS:   1.5,
+				FailureRate: 0.1,
+				Samples:     12,
+			}))
+
+			Expect(decoder.Decode(&recursor)).To(Succeed())
+			Expect(recursor).To(Equal(api.Recursor{
+				Address:     "10.0.0.1:53",
+				Rank:        2,
+				LatencyMS:   20,
+				FailureRate: 0.9,
+				Samples:     3,
+			}))
+
+			Expect(decoder.More()).To(BeFalse())
+		})
+	})
+})
@@ -19,3 +19,31 @@ type Group struct {
 	GroupID     string `json:"group_id"`
 	HealthState string `json:"health_state"`
 }
+
+type Recursor struct {
+	Address     string  `json:"address"`
+	Rank        int     `json:"rank"`
+	LatencyMS   float64 `json:"latency_ms"`
+	FailureRate float64 `json:"failure_rate"`
+	Samples     int     `json:"samples"`
+}
+
+type RecursorStatus struct {
+	Address   string `json:"address"`
+	Up        bool   `json:"up"`
+	LastProbe string `json:"last_probe,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type CacheEntry struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Rcode   string   `json:"rcode"`
+	TTL     int      `json:"ttl"`
+	Stale   bool     `json:"stale,omitempty"`
+	Records []string `json:"records"`
+}
+
+type CacheFlush struct {
+	Flushed int `json:"flushed"`
+}
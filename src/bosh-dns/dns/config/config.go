@@ -5,45 +5,65 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
 )
 
 const (
-	SmartRecursorSelection  = "smart"
-	SerialRecursorSelection = "serial"
-	RFCFormatting           = "rfc3339"
+	SmartRecursorSelection   = "smart"
+	SerialRecursorSelection  = "serial"
+	RaceRecursorSelection    = "race"
+	LatencyRecursorSelection = "latency"
+	RFCFormatting            = "rfc3339"
+
+	TLSRecursorScheme   = "tls://"
+	HTTPSRecursorScheme = "https://"
+
+	NXDomainPolicyAction = "nxdomain"
+	NoDataPolicyAction   = "nodata"
+	SinkholePolicyAction = "sinkhole"
 )
 
 type Config struct {
-	Address            string       `json:"address"`
-	Port               int          `json:"port"`
-	BindTimeout        DurationJSON `json:"timeout,omitempty"`
-	RecursorMaxRetries int          `json:"recursor_max_retries,omitempty"`
-	RequestTimeout     DurationJSON `json:"request_timeout,omitempty"`
-	RecursorTimeout    DurationJSON `json:"recursor_timeout,omitempty"`
-	Recursors          []string     `json:"recursors,omitempty"`
-	ExcludedRecursors  []string     `json:"excluded_recursors,omitempty"`
-	RecordsFile        string       `json:"records_file,omitempty"`
-	RecursorSelection  string       `json:"recursor_selection"`
-	AliasFilesGlob     string       `json:"alias_files_glob,omitempty"`
-	HandlersFilesGlob  string       `json:"handlers_files_glob,omitempty"`
-	AddressesFilesGlob string       `json:"addresses_files_glob,omitempty"`
-	UpcheckDomains     []string     `json:"upcheck_domains,omitempty"`
-	JobsDir            string       `json:"jobs_dir,omitempty"`
+	Address            string              `json:"address"`
+	Port               int                 `json:"port"`
+	BindTimeout        DurationJSON        `json:"timeout,omitempty"`
+	RecursorMaxRetries int                 `json:"recursor_max_retries,omitempty"`
+	RequestTimeout     DurationJSON        `json:"request_timeout,omitempty"`
+	RecursorTimeout    DurationJSON        `json:"recursor_timeout,omitempty"`
+	Recursors          []string            `json:"recursors,omitempty"`
+	ExcludedRecursors  []string            `json:"excluded_recursors,omitempty"`
+	RecordsFile        string              `json:"records_file,omitempty"`
+	RecursorSelection  string              `json:"recursor_selection"`
+	RecursorHedgeDelay DurationJSON        `json:"recursor_hedge_delay,omitempty"`
+	RecursorTLS        RecursorTLSConfig   `json:"recursor_tls"`
+	RecursorProbe      RecursorProbeConfig `json:"recursor_probe"`
+	AliasFilesGlob     string              `json:"alias_files_glob,omitempty"`
+	LinkFilesGlob      string              `json:"link_files_glob,omitempty"`
+	HostsFilesGlob     string              `json:"hosts_files_glob,omitempty"`
+	HandlersFilesGlob  string              `json:"handlers_files_glob,omitempty"`
+	AddressesFilesGlob string              `json:"addresses_files_glob,omitempty"`
+	UpcheckDomains     []string            `json:"upcheck_domains,omitempty"`
+	JobsDir            string              `json:"jobs_dir,omitempty"`
 
 	LogLevel string `json:"log_level,omitempty"`
 
-	API APIConfig `json:"api"`
+	API          APIConfig          `json:"api"`
+	DNSOverTLS   DNSOverTLSConfig   `json:"dns_over_tls"`
+	DNSOverHTTPS DNSOverHTTPSConfig `json:"dns_over_https"`
 
 	Health                HealthConfig          `json:"health"`
 	Metrics               MetricsConfig         `json:"metrics"`
 	Cache                 Cache                 `json:"cache"`
 	InternalUpcheckDomain InternalUpcheckDomain `json:"internal_upcheck_domain"`
+	InternalTTL           InternalTTLConfig     `json:"internal_ttl"`
 	Logging               LoggingConfig         `json:"logging,omitempty"`
+	Policy                PolicyConfig          `json:"policy"`
 }
 
 func (c Config) GetLogLevel() (boshlog.LogLevel, error) {
@@ -81,6 +101,80 @@ type APIConfig struct {
 	CAFile          string `json:"ca_file"`
 }
 
+type DNSOverTLSConfig struct {
+	Enabled         bool   `json:"enabled"`
+	Address         string `json:"address,omitempty"`
+	Port            int    `json:"port"`
+	CertificateFile string `json:"certificate_file"`
+	PrivateKeyFile  string `json:"private_key_file"`
+}
+
+type DNSOverHTTPSConfig struct {
+	Enabled         bool   `json:"enabled"`
+	Address         string `json:"address,omitempty"`
+	Port            int    `json:"port"`
+	CertificateFile string `json:"certificate_file"`
+	PrivateKeyFile  string `json:"private_key_file"`
+	CAFile          string `json:"ca_file,omitempty"`
+}
+
+// RecursorTLSConfig applies to DNS over TLS and DNS over HTTPS recursors.
+// ServerName is the name sent for SNI and verified against the certificate
+// of the recursors addressed by IP which do not name one after a '#' in their
+// address; without either, the certificate is verified against the recursor
+// IP. Recursors addressed by name are verified against that name.
+type RecursorTLSConfig struct {
+	ServerName string `json:"server_name,omitempty"`
+	CAFile     string `json:"ca_file,omitempty"`
+}
+
+// RecursorOptions override how a handler domain selects and queries its
+// recursors. They mirror the global options of the same name, which apply to
+// the options that are not set. RecursorMaxRetries is a pointer so that zero
+// retries can be set explicitly.
+type RecursorOptions struct {
+	RecursorSelection  string       `json:"recursor_selection,omitempty"`
+	RecursorHedgeDelay DurationJSON `json:"recursor_hedge_delay,omitempty"`
+	RecursorTimeout    DurationJSON `json:"recursor_timeout,omitempty"`
+	RecursorMaxRetries *int         `json:"recursor_max_retries,omitempty"`
+	ExcludedRecursors  []string     `json:"excluded_recursors,omitempty"`
+}
+
+// HTTPSourceConfig configures the requests of a handler to its HTTP JSON
+// sources. A client certificate enables mutual TLS and requires a CA. The
+// bearer token, or the password of a username, is read from a file and sent
+// as the Authorization header of every request.
+type HTTPSourceConfig struct {
+	CAFile          string       `json:"ca_file,omitempty"`
+	CertificateFile string       `json:"certificate_file,omitempty"`
+	PrivateKeyFile  string       `json:"private_key_file,omitempty"`
+	ServerName      string       `json:"server_name,omitempty"`
+	BearerTokenFile string       `json:"bearer_token_file,omitempty"`
+	Username        string       `json:"username,omitempty"`
+	PasswordFile    string       `json:"password_file,omitempty"`
+	Timeout         DurationJSON `json:"timeout,omitempty"`
+	MaxRetries      int          `json:"max_retries,omitempty"`
+}
+
+// PolicyConfig configures the block and allow lists applied to queries
+// before they are forwarded. Action applies to the names blocked by plain
+// domain lists; RPZ files carry their own action for each name. An empty
+// action blocks with NXDOMAIN.
+type PolicyConfig struct {
+	FilesGlob   string   `json:"files_glob,omitempty"`
+	Action      string   `json:"action,omitempty"`
+	SinkholeIPs []string `json:"sinkhole_ips,omitempty"`
+}
+
+// RecursorProbeConfig configures the background queries which are sent to
+// every recursor to detect whether it is answering before clients depend on it.
+type RecursorProbeConfig struct {
+	Enabled   bool         `json:"enabled"`
+	Interval  DurationJSON `json:"interval,omitempty"`
+	Domain    string       `json:"domain,omitempty"`
+	QueryType string       `json:"query_type,omitempty"`
+}
+
 type HealthConfig struct {
 	Enabled                 bool         `json:"enabled"`
 	Port                    int          `json:"port"`
@@ -98,8 +192,87 @@ type MetricsConfig struct {
 	Port    int    `json:"port"`
 }
 
+// Cache configures caching of answers. Capacities and TTL bounds which are
+// not set keep the defaults of the CoreDNS cache plugin. MinTTL and MaxTTL
+// clamp the time successful and denial answers are kept in the cache.
+// NXDomain, NoData and ServFail decide whether and for how long answers
+// with these outcomes are cached; all of them are cached by default.
 type Cache struct {
-	Enabled bool `json:"enabled"`
+	Enabled         bool                `json:"enabled"`
+	SuccessCapacity int                 `json:"success_capacity,omitempty"`
+	DenialCapacity  int                 `json:"denial_capacity,omitempty"`
+	MinTTL          DurationJSON        `json:"min_ttl,omitempty"`
+	MaxTTL          DurationJSON        `json:"max_ttl,omitempty"`
+	Prefetch        CachePrefetchConfig `json:"prefetch"`
+	ServeStale      ServeStaleConfig    `json:"serve_stale"`
+	NXDomain        CacheRcodePolicy    `json:"nxdomain"`
+	NoData          CacheRcodePolicy    `json:"nodata"`
+	ServFail        CacheRcodePolicy    `json:"servfail"`
+}
+
+func (c *Cache) UnmarshalJSON(b []byte) error {
+	type plainCache Cache
+
+	cache := plainCache{
+		NXDomain: CacheRcodePolicy{Enabled: true},
+		NoData:   CacheRcodePolicy{Enabled: true},
+		ServFail: CacheRcodePolicy{Enabled: true, TTL: DurationJSON(DefaultServFailCacheTTL)},
+	}
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return err
+	}
+
+	*c = Cache(cache)
+	return nil
+}
+
+// DefaultServFailCacheTTL is how long SERVFAIL answers are cached unless
+// configured otherwise. It is short enough for recursors to recover quickly
+// while stopping clients which retry in a loop from reaching them.
+const DefaultServFailCacheTTL = 5 * time.Second
+
+// CacheRcodePolicy configures caching of answers with one outcome. TTL
+// overrides how long they are kept; when it is not set NXDOMAIN and NODATA
+// answers are kept for the TTL of their SOA record within the TTL bounds of
+// the cache, and SERVFAIL answers for DefaultServFailCacheTTL.
+type CacheRcodePolicy struct {
+	Enabled bool         `json:"enabled"`
+	TTL     DurationJSON `json:"ttl,omitempty"`
+}
+
+// CachePrefetchConfig configures refreshing popular answers before they
+// expire. An answer is popular when it was requested at least Amount times
+// without Duration passing between two requests, and it is refreshed once
+// Percentage percent of its TTL remains.
+type CachePrefetchConfig struct {
+	Amount     int          `json:"amount,omitempty"`
+	Duration   DurationJSON `json:"duration,omitempty"`
+	Percentage int          `json:"percentage,omitempty"`
+}
+
+// ServeStaleConfig configures answering from expired cache entries when no
+// recursor is able to answer (RFC 8767). MaxStale limits how long after
+// expiring an answer may still be served, and TTL is the TTL of the records
+// of such answers.
+type ServeStaleConfig struct {
+	Enabled  bool         `json:"enabled"`
+	MaxStale DurationJSON `json:"max_stale,omitempty"`
+	TTL      DurationJSON `json:"ttl,omitempty"`
+}
+
+func (c *ServeStaleConfig) UnmarshalJSON(b []byte) error {
+	type plainServeStaleConfig ServeStaleConfig
+
+	serveStale := plainServeStaleConfig{
+		MaxStale: DurationJSON(24 * time.Hour),
+		TTL:      DurationJSON(30 * time.Second),
+	}
+	if err := json.Unmarshal(b, &serveStale); err != nil {
+		return err
+	}
+
+	*c = ServeStaleConfig(serveStale)
+	return nil
 }
 
 type InternalUpcheckDomain struct {
@@ -107,6 +280,29 @@ type InternalUpcheckDomain struct {
 	DNSQuery string `json:"dns_query"`
 }
 
+type InternalTTLConfig struct {
+	Default uint32            `json:"default,omitempty"`
+	Domains map[string]uint32 `json:"domains,omitempty"`
+}
+
+// ForDomain returns the TTL of the most specific configured domain containing
+// name, falling back to the default TTL.
+func (c InternalTTLConfig) ForDomain(name string) uint32 {
+	name = dns.Fqdn(strings.ToLower(name))
+
+	ttl := c.Default
+	matchedDomain := ""
+	for domain, domainTTL := range c.Domains {
+		domain = dns.Fqdn(strings.ToLower(domain))
+		if dns.IsSubDomain(domain, name) && len(domain) > len(matchedDomain) {
+			matchedDomain = domain
+			ttl = domainTTL
+		}
+	}
+
+	return ttl
+}
+
 type LogTag struct {
 	Name     string `json:"name"`
 	LogLevel string `json:"log_level"`
@@ -124,6 +320,11 @@ type FormatConfig struct {
 type DurationJSON time.Duration
 
 func (t *DurationJSON) UnmarshalJSON(b []byte) error {
+	// like other types, keep the current value for null
+	if string(b) == "null" {
+		return nil
+	}
+
 	var s string
 
 	if err := json.Unmarshal(b, &s); err != nil {
@@ -147,10 +348,16 @@ func (t DurationJSON) MarshalJSON() (b []byte, err error) {
 
 func NewDefaultConfig() Config {
 	return Config{
-		BindTimeout:       DurationJSON(5 * time.Second),
-		RequestTimeout:    DurationJSON(5 * time.Second),
-		RecursorTimeout:   DurationJSON(2 * time.Second),
-		RecursorSelection: "smart",
+		BindTimeout:        DurationJSON(5 * time.Second),
+		RequestTimeout:     DurationJSON(5 * time.Second),
+		RecursorTimeout:    DurationJSON(2 * time.Second),
+		RecursorSelection:  "smart",
+		RecursorHedgeDelay: DurationJSON(100 * time.Millisecond),
+		RecursorProbe: RecursorProbeConfig{
+			Interval:  DurationJSON(10 * time.Second),
+			Domain:    ".",
+			QueryType: "NS",
+		},
 		Health: HealthConfig{
 			MaxTrackedQueries:       2000,
 			CheckInterval:           DurationJSON(20 * time.Second),
@@ -180,6 +387,46 @@ func LoadFromFile(configFilePath string) (Config, error) {
 		return Config{}, errors.New("port is required")
 	}
 
+	if c.DNSOverTLS.Enabled {
+		if c.DNSOverTLS.Port == 0 {
+			return Config{}, errors.New("dns_over_tls.port is required")
+		}
+
+		if c.DNSOverTLS.CertificateFile == "" || c.DNSOverTLS.PrivateKeyFile == "" {
+			return Config{}, errors.New("dns_over_tls.certificate_file and dns_over_tls.private_key_file are required")
+		}
+
+		if c.DNSOverTLS.Address == "" {
+			c.DNSOverTLS.Address = c.Address
+		}
+	}
+
+	if c.DNSOverHTTPS.Enabled {
+		if c.DNSOverHTTPS.Port == 0 {
+			return Config{}, errors.New("dns_over_https.port is required")
+		}
+
+		if c.DNSOverHTTPS.CertificateFile == "" || c.DNSOverHTTPS.PrivateKeyFile == "" {
+			return Config{}, errors.New("dns_over_https.certificate_file and dns_over_https.private_key_file are required")
+		}
+
+		if c.DNSOverHTTPS.Address == "" {
+			c.DNSOverHTTPS.Address = c.Address
+		}
+	}
+
+	if c.RecursorProbe.Enabled {
+		if time.Duration(c.RecursorProbe.Interval) <= 0 {
+			return Config{}, errors.New("recursor_probe.interval must be positive")
+		}
+
+		if _, ok := dns.StringToType[strings.ToUpper(c.RecursorProbe.QueryType)]; !ok {
+			return Config{}, fmt.Errorf("invalid value for recursor_probe.query_type: %s", c.RecursorProbe.QueryType)
+		}
+
+		c.RecursorProbe.Domain = dns.Fqdn(c.RecursorProbe.Domain)
+	}
+
 	c.Recursors, err = AppendDefaultDNSPortIfMissing(c.Recursors)
 	if err != nil {
 		return Config{}, err
@@ -190,11 +437,29 @@ func LoadFromFile(configFilePath string) (Config, error) {
 		return Config{}, err
 	}
 
+	switch c.Policy.Action {
+	case "", NXDomainPolicyAction, NoDataPolicyAction:
+	case SinkholePolicyAction:
+		if len(c.Policy.SinkholeIPs) == 0 {
+			return Config{}, errors.New("policy.sinkhole_ips is required when policy.action is sinkhole")
+		}
+	default:
+		return Config{}, errors.New("invalid value for policy.action; expected 'nxdomain', 'nodata' or 'sinkhole'")
+	}
+
+	for _, ip := range c.Policy.SinkholeIPs {
+		if net.ParseIP(ip) == nil {
+			return Config{}, fmt.Errorf("invalid IP address in policy.sinkhole_ips: %s", ip)
+		}
+	}
+
 	switch c.RecursorSelection {
 	case "smart":
 	case "serial":
+	case "race":
+	case "latency":
 	default:
-		return Config{}, errors.New("invalid value for recursor_selection; expected 'serial' or 'smart'")
+		return Config{}, errors.New("invalid value for recursor_selection; expected 'serial', 'smart', 'race' or 'latency'")
 	}
 
 	return c, nil
@@ -203,6 +468,31 @@ func LoadFromFile(configFilePath string) (Config, error) {
 func AppendDefaultDNSPortIfMissing(recursors []string) ([]string, error) {
 	recursorsWithPort := []string{}
 	for _, recursor := range recursors {
+		if strings.HasPrefix(recursor, TLSRecursorScheme) {
+			address, serverName := SplitRecursorServerName(strings.TrimPrefix(recursor, TLSRecursorScheme))
+			address, ok := appendPortIfMissing(address, "853")
+			if !ok {
+				return []string{}, fmt.Errorf("Invalid recursor address %s", recursor)
+			}
+
+			if serverName != "" {
+				address += "#" + serverName
+			}
+
+			recursorsWithPort = append(recursorsWithPort, TLSRecursorScheme+address)
+			continue
+		}
+
+		if strings.HasPrefix(recursor, HTTPSRecursorScheme) {
+			recursorURL, err := url.Parse(recursor)
+			if err != nil || recursorURL.Host == "" {
+				return []string{}, fmt.Errorf("Invalid recursor URL %s", recursor)
+			}
+
+			recursorsWithPort = append(recursorsWithPort, recursor)
+			continue
+		}
+
 		_, _, err := net.SplitHostPort(recursor)
 		cleanedUpRecursor := recursor
 
@@ -223,3 +513,28 @@ func AppendDefaultDNSPortIfMissing(recursors []string) ([]string, error) {
 	}
 	return recursorsWithPort, nil
 }
+
+// SplitRecursorServerName splits the server name to verify a DNS over TLS or
+// DNS over HTTPS recursor against from its address, as in
+// "tls://1.1.1.1:853#cloudflare-dns.com". The server name is empty when the
+// recursor does not name one.
+func SplitRecursorServerName(recursor string) (string, string) {
+	address, serverName, _ := strings.Cut(recursor, "#")
+	return address, serverName
+}
+
+func appendPortIfMissing(address, defaultPort string) (string, bool) {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address, true
+	}
+
+	if ip := net.ParseIP(address); ip != nil {
+		return net.JoinHostPort(ip.String(), defaultPort), true
+	}
+
+	if address == "" || strings.ContainsAny(address, ":/[]") {
+		return "", false
+	}
+
+	return net.JoinHostPort(address, defaultPort), true
+}
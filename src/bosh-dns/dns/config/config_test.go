@@ -74,6 +74,8 @@ var _ = Describe("Config", func() {
 			"address":              listenAddress,
 			"addresses_files_glob": addressesFileGlob,
 			"alias_files_glob":     aliasesFileGlob,
+			"link_files_glob":      "/links/*/glob",
+			"hosts_files_glob":     "/hosts/*/glob",
 			"handlers_files_glob":  handlersFileGlob,
 			"port":                 listenPort,
 			"log_level":            logLevel,
@@ -164,8 +166,16 @@ var _ = Describe("Config", func() {
 			Recursors:          []string{},
 			ExcludedRecursors:  []string{"169.254.169.254:53", "169.10.10.10:1234"},
 			RecursorSelection:  "smart",
+			RecursorHedgeDelay: config.DurationJSON(100 * time.Millisecond),
+			RecursorProbe: config.RecursorProbeConfig{
+				Interval:  config.DurationJSON(10 * time.Second),
+				Domain:    ".",
+				QueryType: "NS",
+			},
 			UpcheckDomains:     []string{"upcheck.domain.", "health2.bosh."},
 			AliasFilesGlob:     aliasesFileGlob,
+			LinkFilesGlob:      "/links/*/glob",
+			HostsFilesGlob:     "/hosts/*/glob",
 			HandlersFilesGlob:  handlersFileGlob,
 			AddressesFilesGlob: addressesFileGlob,
 			JobsDir:            "/var/vcap/jobs",
@@ -185,7 +195,10 @@ var _ = Describe("Config", func() {
 				Port:    metricsPort,
 			},
 			Cache: config.Cache{
-				Enabled: true,
+				Enabled:  true,
+				NXDomain: config.CacheRcodePolicy{Enabled: true},
+				NoData:   config.CacheRcodePolicy{Enabled: true},
+				ServFail: config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(5 * time.Second)},
 			},
 			InternalUpcheckDomain: config.InternalUpcheckDomain{
 				Enabled:  true,
@@ -268,11 +281,39 @@ var _ = Describe("Config", func() {
 			Expect(dnsConfig.RecursorSelection).To(Equal("smart"))
 		})
 
-		It("complains if you configure something besides smart or serial", func() {
+		It("allows configuring recursor selection to be race", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_selection": "race", "recursor_hedge_delay": "250ms"}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RecursorSelection).To(Equal("race"))
+			Expect(dnsConfig.RecursorHedgeDelay).To(Equal(config.DurationJSON(250 * time.Millisecond)))
+		})
+
+		It("defaults the recursor hedge delay", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_selection": "race"}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RecursorHedgeDelay).To(Equal(config.DurationJSON(100 * time.Millisecond)))
+		})
+
+		It("allows configuring recursor selection to be latency", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_selection": "latency"}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RecursorSelection).To(Equal("latency"))
+		})
+
+		It("complains if you configure something besides smart, serial, race or latency", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_selection": "wrong" }`)
 
 			_, err := config.LoadFromFile(configFilePath)
-			Expect(err).To(MatchError("invalid value for recursor_selection; expected 'serial' or 'smart'"))
+			Expect(err).To(MatchError("invalid value for recursor_selection; expected 'serial', 'smart', 'race' or 'latency'"))
 		})
 
 		It("recursor_max_retries default", func() {
@@ -292,6 +333,42 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("policy", func() {
+		It("allows configuring block lists with a sinkhole", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "policy": {"files_glob": "/policy/*", "action": "sinkhole", "sinkhole_ips": ["0.0.0.0", "::"]}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Policy).To(Equal(config.PolicyConfig{
+				FilesGlob:   "/policy/*",
+				Action:      config.SinkholePolicyAction,
+				SinkholeIPs: []string{"0.0.0.0", "::"},
+			}))
+		})
+
+		It("complains if you configure something besides nxdomain, nodata or sinkhole", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "policy": {"action": "refused"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("invalid value for policy.action; expected 'nxdomain', 'nodata' or 'sinkhole'"))
+		})
+
+		It("complains if the sinkhole has no addresses", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "policy": {"action": "sinkhole"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("policy.sinkhole_ips is required when policy.action is sinkhole"))
+		})
+
+		It("complains if a sinkhole address is invalid", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "policy": {"action": "sinkhole", "sinkhole_ips": ["blackhole"]}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("invalid IP address in policy.sinkhole_ips: blackhole"))
+		})
+	})
+
 	Context("health.max_tracked_queries", func() {
 		It("defaults to 2000", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
@@ -371,6 +448,259 @@ var _ = Describe("Config", func() {
 		Expect(len(dnsConfig.Recursors)).To(Equal(0))
 	})
 
+	Context("internal_ttl", func() {
+		It("defaults to a zero TTL", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.InternalTTL.ForDomain("q-s0.group.network.deployment.bosh.")).To(Equal(uint32(0)))
+		})
+
+		It("returns the TTL of the most specific configured domain", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "internal_ttl": {"default": 5, "domains": {"bosh": 10, "Deployment.bosh.": 20}}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.InternalTTL.ForDomain("q-s0.group.network.deployment.bosh.")).To(Equal(uint32(20)))
+			Expect(dnsConfig.InternalTTL.ForDomain("q-s0.group.network.other.bosh.")).To(Equal(uint32(10)))
+			Expect(dnsConfig.InternalTTL.ForDomain("alias.internal.")).To(Equal(uint32(5)))
+			Expect(dnsConfig.InternalTTL.ForDomain("notbosh.")).To(Equal(uint32(5)))
+		})
+	})
+
+	Context("dns_over_tls", func() {
+		It("is disabled by default", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DNSOverTLS).To(Equal(config.DNSOverTLSConfig{}))
+		})
+
+		It("defaults the address to the listen address", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_tls": {"enabled": true, "port": 853, "certificate_file": "/cert", "private_key_file": "/key"}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DNSOverTLS).To(Equal(config.DNSOverTLSConfig{
+				Enabled:         true,
+				Address:         "127.0.0.1",
+				Port:            853,
+				CertificateFile: "/cert",
+				PrivateKeyFile:  "/key",
+			}))
+		})
+
+		It("returns error if the port is missing", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_tls": {"enabled": true, "certificate_file": "/cert", "private_key_file": "/key"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("dns_over_tls.port is required"))
+		})
+
+		It("returns error if the certificate is missing", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_tls": {"enabled": true, "port": 853, "private_key_file": "/key"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError(ContainSubstring("dns_over_tls.certificate_file")))
+		})
+	})
+
+	Context("dns_over_https", func() {
+		It("defaults the address to the listen address", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_https": {"enabled": true, "port": 443, "certificate_file": "/cert", "private_key_file": "/key", "ca_file": "/ca"}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.DNSOverHTTPS).To(Equal(config.DNSOverHTTPSConfig{
+				Enabled:         true,
+				Address:         "127.0.0.1",
+				Port:            443,
+				CertificateFile: "/cert",
+				PrivateKeyFile:  "/key",
+				CAFile:          "/ca",
+			}))
+		})
+
+		It("returns error if the port is missing", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_https": {"enabled": true, "certificate_file": "/cert", "private_key_file": "/key"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("dns_over_https.port is required"))
+		})
+
+		It("returns error if the private key is missing", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "dns_over_https": {"enabled": true, "port": 443, "certificate_file": "/cert"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError(ContainSubstring("dns_over_https.private_key_file")))
+		})
+	})
+
+	Context("cache", func() {
+		It("loads capacities, ttl bounds and prefetch settings", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {
+				"enabled": true,
+				"success_capacity": 1000,
+				"denial_capacity": 100,
+				"min_ttl": "10s",
+				"max_ttl": "10m",
+				"prefetch": {"amount": 5, "duration": "30s", "percentage": 20}
+			}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache).To(Equal(config.Cache{
+				Enabled:         true,
+				SuccessCapacity: 1000,
+				DenialCapacity:  100,
+				MinTTL:          config.DurationJSON(10 * time.Second),
+				MaxTTL:          config.DurationJSON(10 * time.Minute),
+				Prefetch: config.CachePrefetchConfig{
+					Amount:     5,
+					Duration:   config.DurationJSON(30 * time.Second),
+					Percentage: 20,
+				},
+				NXDomain: config.CacheRcodePolicy{Enabled: true},
+				NoData:   config.CacheRcodePolicy{Enabled: true},
+				ServFail: config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(5 * time.Second)},
+			}))
+		})
+
+		It("leaves unset ttl bounds at zero", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {"enabled": true, "min_ttl": null, "max_ttl": null}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache.MinTTL).To(BeZero())
+			Expect(dnsConfig.Cache.MaxTTL).To(BeZero())
+		})
+	})
+
+	Context("cache rcode policies", func() {
+		It("caches every outcome by default", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {"enabled": true}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache.NXDomain).To(Equal(config.CacheRcodePolicy{Enabled: true}))
+			Expect(dnsConfig.Cache.NoData).To(Equal(config.CacheRcodePolicy{Enabled: true}))
+			Expect(dnsConfig.Cache.ServFail).To(Equal(config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(5 * time.Second)}))
+		})
+
+		It("loads the policies", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {
+				"enabled": true,
+				"nxdomain": {"enabled": true, "ttl": "1m"},
+				"nodata": {"enabled": false},
+				"servfail": {"enabled": true, "ttl": "2s"}
+			}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache.NXDomain).To(Equal(config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(time.Minute)}))
+			Expect(dnsConfig.Cache.NoData).To(Equal(config.CacheRcodePolicy{Enabled: false}))
+			Expect(dnsConfig.Cache.ServFail).To(Equal(config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(2 * time.Second)}))
+		})
+	})
+
+	Context("cache.serve_stale", func() {
+		It("defaults the maximum staleness and ttl", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {"enabled": true, "serve_stale": {"enabled": true}}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache.ServeStale).To(Equal(config.ServeStaleConfig{
+				Enabled:  true,
+				MaxStale: config.DurationJSON(24 * time.Hour),
+				TTL:      config.DurationJSON(30 * time.Second),
+			}))
+		})
+
+		It("loads the maximum staleness and ttl", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "cache": {"serve_stale": {"enabled": true, "max_stale": "1h", "ttl": "5s"}}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Cache.ServeStale).To(Equal(config.ServeStaleConfig{
+				Enabled:  true,
+				MaxStale: config.DurationJSON(time.Hour),
+				TTL:      config.DurationJSON(5 * time.Second),
+			}))
+		})
+	})
+
+	Context("recursor_probe", func() {
+		It("is disabled by default", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RecursorProbe).To(Equal(config.RecursorProbeConfig{
+				Enabled:   false,
+				Interval:  config.DurationJSON(10 * time.Second),
+				Domain:    ".",
+				QueryType: "NS",
+			}))
+		})
+
+		It("loads the probe query", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_probe": {"enabled": true, "interval": "3s", "domain": "example.com", "query_type": "a"}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.RecursorProbe).To(Equal(config.RecursorProbeConfig{
+				Enabled:   true,
+				Interval:  config.DurationJSON(3 * time.Second),
+				Domain:    "example.com.",
+				QueryType: "a",
+			}))
+		})
+
+		It("complains about an unknown query type", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_probe": {"enabled": true, "query_type": "BOGUS"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("invalid value for recursor_probe.query_type: BOGUS"))
+		})
+
+		It("complains about a zero interval", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursor_probe": {"enabled": true, "interval": "0s"}}`)
+
+			_, err := config.LoadFromFile(configFilePath)
+			Expect(err).To(MatchError("recursor_probe.interval must be positive"))
+		})
+	})
+
+	Context("recursor_tls", func() {
+		It("loads encrypted recursors and their tls settings", func() {
+			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "recursors": ["tls://1.1.1.1#cloudflare-dns.com", "https://8.8.8.8/dns-query"], "recursor_tls": {"server_name": "dns.example.com", "ca_file": "/ca"}}`)
+
+			dnsConfig, err := config.LoadFromFile(configFilePath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dnsConfig.Recursors).To(Equal([]string{"tls://1.1.1.1:853#cloudflare-dns.com", "https://8.8.8.8/dns-query"}))
+			Expect(dnsConfig.RecursorTLS).To(Equal(config.RecursorTLSConfig{
+				ServerName: "dns.example.com",
+				CAFile:     "/ca",
+			}))
+		})
+	})
+
 	Context("LoggingFormat", func() {
 		It("is case insensitive", func() {
 			configFilePath := writeConfigFile(`{"address": "127.0.0.1", "port": 53, "logging":{"format": {"timestamp": "Rfc3339"}} }`)
@@ -415,6 +745,35 @@ var _ = Describe("Config", func() {
 			Expect(recursors).To(ContainElement("[2001:db8::1]:1234"))
 		})
 
+		It("appends the default DNS over TLS port to tls recursors", func() {
+			recursors, err := config.AppendDefaultDNSPortIfMissing([]string{"tls://1.1.1.1", "tls://1.1.1.1#cloudflare-dns.com", "tls://[2001:db8::1]:8853", "tls://2001:db8::1"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(recursors).To(Equal([]string{"tls://1.1.1.1:853", "tls://1.1.1.1:853#cloudflare-dns.com", "tls://[2001:db8::1]:8853", "tls://[2001:db8::1]:853"}))
+		})
+
+		It("leaves https recursors unchanged", func() {
+			recursors, err := config.AppendDefaultDNSPortIfMissing([]string{"https://8.8.8.8/dns-query#dns.google", "https://1.1.1.1:8443/dns-query"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(recursors).To(Equal([]string{"https://8.8.8.8/dns-query#dns.google", "https://1.1.1.1:8443/dns-query"}))
+		})
+
+		It("accepts tls and https recursors addressed by name", func() {
+			recursors, err := config.AppendDefaultDNSPortIfMissing([]string{"tls://dns.example.com", "tls://dns.example.com:8853#dns.example.org", "https://dns.example.com/dns-query"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(recursors).To(Equal([]string{"tls://dns.example.com:853", "tls://dns.example.com:8853#dns.example.org", "https://dns.example.com/dns-query"}))
+		})
+
+		It("returns an error if a tls or https recursor is malformed", func() {
+			_, err := config.AppendDefaultDNSPortIfMissing([]string{"tls://"})
+			Expect(err).To(MatchError("Invalid recursor address tls://"))
+
+			_, err = config.AppendDefaultDNSPortIfMissing([]string{"https:///dns-query"})
+			Expect(err).To(MatchError("Invalid recursor URL https:///dns-query"))
+		})
+
 		It("returns an error if the recursor address is malformed", func() {
 			_, err := config.AppendDefaultDNSPortIfMissing([]string{"::::::::::::"})
 			Expect(err).To(HaveOccurred())
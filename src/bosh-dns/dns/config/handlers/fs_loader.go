@@ -30,6 +30,13 @@ func (l FSLoader) Load(filename string) (HandlerConfigs, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		if len(handlers[i].Source.ExcludedRecursors) > 0 {
+			handlers[i].Source.ExcludedRecursors, err = config.AppendDefaultDNSPortIfMissing(handlers[i].Source.ExcludedRecursors)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return handlers, nil
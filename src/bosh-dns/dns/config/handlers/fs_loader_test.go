@@ -1,6 +1,8 @@
 package handlers_test
 
 import (
+	"time"
+
 	boshsysfakes "github.com/cloudfoundry/bosh-utils/system/fakes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -41,12 +43,22 @@ var _ = Describe("FSLoader", func() {
 				config := HandlerConfigs{
 					{
 						Domain: "local.internal.",
-						Cache:  config.Cache{Enabled: true},
+						Cache: config.Cache{
+							Enabled:  true,
+							NXDomain: config.CacheRcodePolicy{Enabled: true},
+							NoData:   config.CacheRcodePolicy{Enabled: true},
+							ServFail: config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(config.DefaultServFailCacheTTL)},
+						},
 						Source: Source{Type: "http", URL: "http://some.endpoint.local", Recursors: []string{}},
 					},
 					{
 						Domain: "local.internal2.",
-						Cache:  config.Cache{Enabled: false},
+						Cache: config.Cache{
+							Enabled:  false,
+							NXDomain: config.CacheRcodePolicy{Enabled: true},
+							NoData:   config.CacheRcodePolicy{Enabled: true},
+							ServFail: config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(config.DefaultServFailCacheTTL)},
+						},
 						Source: Source{Type: "dns", Recursors: []string{"127.0.0.1:42"}},
 					},
 				}
@@ -70,6 +82,53 @@ var _ = Describe("FSLoader", func() {
 				Expect(config[0].Source.Recursors).To(ContainElement("8.8.8.8:53"))
 				Expect(config[0].Source.Recursors).To(ContainElement("10.244.4.4:9700"))
 			})
+
+			It("loads encrypted recursors with their tls settings", func() {
+				Expect(fs.WriteFileString("/test/handlers.json",
+					`[
+					{
+						"domain": "local.internal2.",
+						"source": {
+							"type": "dns",
+							"recursors": [ "tls://1.1.1.1", "https://8.8.8.8/dns-query#dns.google" ],
+							"tls": { "server_name": "dns.example.com", "ca_file": "/some/ca.crt" }
+						}
+					}
+				]`)).To(Succeed())
+
+				config, err := parser.Load("/test/handlers.json")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config[0].Source.Recursors).To(Equal([]string{"tls://1.1.1.1:853", "https://8.8.8.8/dns-query#dns.google"}))
+				Expect(config[0].Source.TLS.ServerName).To(Equal("dns.example.com"))
+				Expect(config[0].Source.TLS.CAFile).To(Equal("/some/ca.crt"))
+			})
+
+			It("loads recursor options overriding the global ones", func() {
+				Expect(fs.WriteFileString("/test/handlers.json",
+					`[
+					{
+						"domain": "corp.example.com.",
+						"source": {
+							"type": "dns",
+							"recursors": [ "10.0.0.1", "10.0.0.2" ],
+							"recursor_selection": "serial",
+							"recursor_timeout": "10s",
+							"recursor_max_retries": 3,
+							"excluded_recursors": [ "10.0.0.2" ]
+						}
+					}
+				]`)).To(Succeed())
+
+				config, err := parser.Load("/test/handlers.json")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config[0].Source.RecursorSelection).To(Equal("serial"))
+				Expect(time.Duration(config[0].Source.RecursorTimeout)).To(Equal(10 * time.Second))
+				Expect(config[0].Source.RecursorMaxRetries).NotTo(BeNil())
+				Expect(*config[0].Source.RecursorMaxRetries).To(Equal(3))
+				Expect(config[0].Source.ExcludedRecursors).To(Equal([]string{"10.0.0.2:53"}))
+			})
 		})
 
 		Context("missing file", func() {
@@ -12,8 +12,10 @@ import (
 
 //counterfeiter:generate . HandlerFactory
 type HandlerFactory interface {
-	CreateHTTPJSONHandler(string, bool) dns.Handler
-	CreateForwardHandler([]string, bool) dns.Handler
+	CreateHTTPJSONHandler([]string, config.HTTPSourceConfig, config.Cache) (dns.Handler, error)
+	CreateForwardHandler([]string, config.RecursorTLSConfig, config.RecursorOptions, config.Cache) (dns.Handler, error)
+	CreateZoneHandler(string, string, config.Cache) (dns.Handler, error)
+	CreateHostsHandler(string, config.Cache) (dns.Handler, error)
 }
 
 type HandlerConfigs []HandlerConfig
@@ -25,9 +27,15 @@ type HandlerConfig struct {
 }
 
 type Source struct {
-	Type      string   `json:"type"`
-	URL       string   `json:"url,omitempty"`
-	Recursors []string `json:"recursors,omitempty"`
+	Type      string                   `json:"type"`
+	URL       string                   `json:"url,omitempty"`
+	URLs      []string                 `json:"urls,omitempty"`
+	HTTP      config.HTTPSourceConfig  `json:"http,omitempty"`
+	Recursors []string                 `json:"recursors,omitempty"`
+	TLS       config.RecursorTLSConfig `json:"tls,omitempty"`
+	File      string                   `json:"file,omitempty"`
+
+	config.RecursorOptions
 }
 
 func (c HandlerConfigs) GenerateHandlers(factory HandlerFactory) (map[string]dns.Handler, error) {
@@ -36,18 +44,52 @@ func (c HandlerConfigs) GenerateHandlers(factory HandlerFactory) (map[string]dns
 		var handler dns.Handler
 
 		if handlerConfig.Source.Type == "http" {
-			url := handlerConfig.Source.URL
-			if url == "" {
+			// url is queried first, then urls in order
+			urls := []string{}
+			for _, url := range append([]string{handlerConfig.Source.URL}, handlerConfig.Source.URLs...) {
+				if url != "" {
+					urls = append(urls, url)
+				}
+			}
+			if len(urls) == 0 {
 				return nil, fmt.Errorf(`Configuring handler for "%s": HTTP handler must receive a URL`, handlerConfig.Domain)
 			}
 
-			handler = factory.CreateHTTPJSONHandler(url, handlerConfig.Cache.Enabled)
+			var err error
+			handler, err = factory.CreateHTTPJSONHandler(urls, handlerConfig.Source.HTTP, handlerConfig.Cache)
+			if err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err.Error())
+			}
 		} else if handlerConfig.Source.Type == "dns" {
 			if len(handlerConfig.Source.Recursors) == 0 {
 				return nil, fmt.Errorf(`Configuring handler for "%s": No recursors present`, handlerConfig.Domain)
 			}
 
-			handler = factory.CreateForwardHandler(handlerConfig.Source.Recursors, handlerConfig.Cache.Enabled)
+			var err error
+			handler, err = factory.CreateForwardHandler(handlerConfig.Source.Recursors, handlerConfig.Source.TLS, handlerConfig.Source.RecursorOptions, handlerConfig.Cache)
+			if err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err.Error())
+			}
+		} else if handlerConfig.Source.Type == "zone" {
+			if handlerConfig.Source.File == "" {
+				return nil, fmt.Errorf(`Configuring handler for "%s": Zone handler must receive a file`, handlerConfig.Domain)
+			}
+
+			var err error
+			handler, err = factory.CreateZoneHandler(handlerConfig.Domain, handlerConfig.Source.File, handlerConfig.Cache)
+			if err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err.Error())
+			}
+		} else if handlerConfig.Source.Type == "hosts" {
+			if handlerConfig.Source.File == "" {
+				return nil, fmt.Errorf(`Configuring handler for "%s": Hosts handler must receive a file`, handlerConfig.Domain)
+			}
+
+			var err error
+			handler, err = factory.CreateHostsHandler(handlerConfig.Source.File, handlerConfig.Cache)
+			if err != nil {
+				return nil, fmt.Errorf(`Configuring handler for "%s": %s`, handlerConfig.Domain, err.Error())
+			}
 		} else {
 			return nil, fmt.Errorf(`Configuring handler for "%s": Unexpected handler source type: %s`, handlerConfig.Domain, handlerConfig.Source.Type)
 		}
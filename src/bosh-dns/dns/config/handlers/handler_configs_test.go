@@ -1,9 +1,13 @@
 package handlers_test
 
 import (
+	"errors"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/config/handlers"
 	. "bosh-dns/dns/config/handlers/handlersfakes"
 )
@@ -15,6 +19,8 @@ var _ = Describe("Handlers Configuration", func() {
 			fakeHandlerFactory *FakeHandlerFactory
 			fakeJsonHandler    *FakeDnsHandler
 			fakeDnsHandler     *FakeDnsHandler
+			fakeZoneHandler    *FakeDnsHandler
+			fakeHostsHandler   *FakeDnsHandler
 		)
 
 		BeforeEach(func() {
@@ -22,9 +28,13 @@ var _ = Describe("Handlers Configuration", func() {
 
 			fakeDnsHandler = &FakeDnsHandler{}
 			fakeJsonHandler = &FakeDnsHandler{}
+			fakeZoneHandler = &FakeDnsHandler{}
+			fakeHostsHandler = &FakeDnsHandler{}
 
-			fakeHandlerFactory.CreateHTTPJSONHandlerReturns(fakeJsonHandler)
-			fakeHandlerFactory.CreateForwardHandlerReturns(fakeDnsHandler)
+			fakeHandlerFactory.CreateHTTPJSONHandlerReturns(fakeJsonHandler, nil)
+			fakeHandlerFactory.CreateForwardHandlerReturns(fakeDnsHandler, nil)
+			fakeHandlerFactory.CreateZoneHandlerReturns(fakeZoneHandler, nil)
+			fakeHandlerFactory.CreateHostsHandlerReturns(fakeHostsHandler, nil)
 		})
 
 		Context("with no handlers configured", func() {
@@ -57,24 +67,77 @@ var _ = Describe("Handlers Configuration", func() {
 					Expect(len(handlers)).To(Equal(1))
 					Expect(handlers["my-tld."]).To(Equal(fakeJsonHandler))
 
-					url, enableCache := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
-					Expect(url).To(Equal("some-url"))
-					Expect(enableCache).To(Equal(false))
+					urls, httpConfig, cache := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
+					Expect(urls).To(Equal([]string{"some-url"}))
+					Expect(httpConfig).To(Equal(config.HTTPSourceConfig{}))
+					Expect(cache).To(Equal(config.Cache{}))
 				})
 
 				Context("with cache enabled", func() {
 					BeforeEach(func() {
-						handlersConfig[0].Cache.Enabled = true
+						handlersConfig[0].Cache = config.Cache{
+							Enabled:         true,
+							SuccessCapacity: 100,
+							MaxTTL:          config.DurationJSON(time.Minute),
+						}
 					})
 
-					It("passes that option to the factory", func() {
+					It("passes the cache settings to the factory", func() {
 						handlers, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
 						Expect(err).NotTo(HaveOccurred())
 						Expect(len(handlers)).To(Equal(1))
 
-						url, enableCache := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
-						Expect(url).To(Equal("some-url"))
-						Expect(enableCache).To(Equal(true))
+						urls, _, cache := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
+						Expect(urls).To(Equal([]string{"some-url"}))
+						Expect(cache).To(Equal(handlersConfig[0].Cache))
+					})
+				})
+
+				Context("with several urls and http settings", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Source.URLs = []string{"other-url", "last-url"}
+						handlersConfig[0].Source.HTTP = config.HTTPSourceConfig{
+							CAFile:          "/some/ca.crt",
+							CertificateFile: "/some/client.crt",
+							PrivateKeyFile:  "/some/client.key",
+							BearerTokenFile: "/some/token",
+							Timeout:         config.DurationJSON(3 * time.Second),
+							MaxRetries:      2,
+						}
+					})
+
+					It("passes the urls in order and the settings to the factory", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+
+						urls, httpConfig, _ := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
+						Expect(urls).To(Equal([]string{"some-url", "other-url", "last-url"}))
+						Expect(httpConfig).To(Equal(handlersConfig[0].Source.HTTP))
+					})
+
+					Context("but no single url", func() {
+						BeforeEach(func() {
+							handlersConfig[0].Source.URL = ""
+						})
+
+						It("passes the urls to the factory", func() {
+							_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+							Expect(err).NotTo(HaveOccurred())
+
+							urls, _, _ := fakeHandlerFactory.CreateHTTPJSONHandlerArgsForCall(0)
+							Expect(urls).To(Equal([]string{"other-url", "last-url"}))
+						})
+					})
+				})
+
+				Context("when the handler cannot be created", func() {
+					BeforeEach(func() {
+						fakeHandlerFactory.CreateHTTPJSONHandlerReturns(nil, errors.New("bad cache"))
+					})
+
+					It("produces an error", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": bad cache`))
 					})
 				})
 
@@ -91,6 +154,89 @@ var _ = Describe("Handlers Configuration", func() {
 				})
 			})
 
+			Context("of zone type", func() {
+				BeforeEach(func() {
+					handlersConfig = HandlerConfigs{
+						{
+							Domain: "corp.example.com.",
+							Source: Source{
+								Type: "zone",
+								File: "/some/zone.db",
+							},
+						},
+					}
+				})
+
+				It("loads the zone from the file", func() {
+					handlers, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(len(handlers)).To(Equal(1))
+					Expect(handlers["corp.example.com."]).To(Equal(fakeZoneHandler))
+
+					domain, file, cache := fakeHandlerFactory.CreateZoneHandlerArgsForCall(0)
+					Expect(domain).To(Equal("corp.example.com."))
+					Expect(file).To(Equal("/some/zone.db"))
+					Expect(cache).To(Equal(config.Cache{}))
+				})
+
+				Context("when the handler cannot be created", func() {
+					BeforeEach(func() {
+						fakeHandlerFactory.CreateZoneHandlerReturns(nil, errors.New("zone corp.example.com. has no SOA record"))
+					})
+
+					It("produces an error", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "corp.example.com.": zone corp.example.com. has no SOA record`))
+					})
+				})
+
+				Context("but with no file", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Source.File = ""
+					})
+
+					It("produces an error", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "corp.example.com.": Zone handler must receive a file`))
+					})
+				})
+			})
+
+			Context("of hosts type", func() {
+				BeforeEach(func() {
+					handlersConfig = HandlerConfigs{
+						{
+							Domain: "pinned.example.com.",
+							Source: Source{
+								Type: "hosts",
+								File: "/some/hosts",
+							},
+						},
+					}
+				})
+
+				It("loads the entries from the file", func() {
+					handlers, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(handlers["pinned.example.com."]).To(Equal(fakeHostsHandler))
+
+					file, cache := fakeHandlerFactory.CreateHostsHandlerArgsForCall(0)
+					Expect(file).To(Equal("/some/hosts"))
+					Expect(cache).To(Equal(config.Cache{}))
+				})
+
+				Context("but with no file", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Source.File = ""
+					})
+
+					It("produces an error", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "pinned.example.com.": Hosts handler must receive a file`))
+					})
+				})
+			})
+
 			Context("of dns type", func() {
 				BeforeEach(func() {
 					handlersConfig = HandlerConfigs{
@@ -110,9 +256,90 @@ var _ = Describe("Handlers Configuration", func() {
 					Expect(len(handlers)).To(Equal(1))
 					Expect(handlers["my-tld."]).To(Equal(fakeDnsHandler))
 
-					recursors, enableCache := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+					recursors, recursorTLS, options, cache := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
 					Expect(recursors).To(Equal([]string{"some-recursor", "another-recursor"}))
-					Expect(enableCache).To(Equal(false))
+					Expect(recursorTLS).To(Equal(config.RecursorTLSConfig{}))
+					Expect(options).To(Equal(config.RecursorOptions{}))
+					Expect(cache).To(Equal(config.Cache{}))
+				})
+
+				Context("with cache settings", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Cache = config.Cache{
+							Enabled: true,
+							ServeStale: config.ServeStaleConfig{
+								Enabled:  true,
+								MaxStale: config.DurationJSON(time.Hour),
+								TTL:      config.DurationJSON(10 * time.Second),
+							},
+						}
+					})
+
+					It("passes them on to the forward handler", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+
+						_, _, _, cache := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+						Expect(cache).To(Equal(handlersConfig[0].Cache))
+					})
+				})
+
+				Context("with recursor tls settings", func() {
+					BeforeEach(func() {
+						handlersConfig[0].Source.TLS = config.RecursorTLSConfig{
+							ServerName: "dns.example.com",
+							CAFile:     "/some/ca.crt",
+						}
+					})
+
+					It("passes them on to the forward handler", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+
+						_, recursorTLS, _, _ := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+						Expect(recursorTLS).To(Equal(config.RecursorTLSConfig{
+							ServerName: "dns.example.com",
+							CAFile:     "/some/ca.crt",
+						}))
+					})
+				})
+
+				Context("with recursor options", func() {
+					var maxRetries int
+
+					BeforeEach(func() {
+						maxRetries = 3
+						handlersConfig[0].Source.RecursorOptions = config.RecursorOptions{
+							RecursorSelection:  "serial",
+							RecursorTimeout:    config.DurationJSON(10 * time.Second),
+							RecursorMaxRetries: &maxRetries,
+							ExcludedRecursors:  []string{"another-recursor"},
+						}
+					})
+
+					It("passes them on to the forward handler", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).NotTo(HaveOccurred())
+
+						_, _, options, _ := fakeHandlerFactory.CreateForwardHandlerArgsForCall(0)
+						Expect(options).To(Equal(config.RecursorOptions{
+							RecursorSelection:  "serial",
+							RecursorTimeout:    config.DurationJSON(10 * time.Second),
+							RecursorMaxRetries: &maxRetries,
+							ExcludedRecursors:  []string{"another-recursor"},
+						}))
+					})
+				})
+
+				Context("when the forward handler cannot be created", func() {
+					BeforeEach(func() {
+						fakeHandlerFactory.CreateForwardHandlerReturns(nil, errors.New("bad ca"))
+					})
+
+					It("produces an error", func() {
+						_, err := handlersConfig.GenerateHandlers(fakeHandlerFactory)
+						Expect(err).To(MatchError(`Configuring handler for "my-tld.": bad ca`))
+					})
 				})
 
 				Context("but with no recursors declared", func() {
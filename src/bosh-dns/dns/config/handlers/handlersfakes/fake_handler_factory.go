@@ -2,6 +2,7 @@
 package handlersfakes
 
 import (
+	"bosh-dns/dns/config"
 	"bosh-dns/dns/config/handlers"
 	"sync"
 
@@ -9,35 +10,71 @@ import (
 )
 
 type FakeHandlerFactory struct {
-	CreateForwardHandlerStub        func([]string, bool) dns.Handler
+	CreateForwardHandlerStub        func([]string, config.RecursorTLSConfig, config.RecursorOptions, config.Cache) (dns.Handler, error)
 	createForwardHandlerMutex       sync.RWMutex
 	createForwardHandlerArgsForCall []struct {
 		arg1 []string
-		arg2 bool
+		arg2 config.RecursorTLSConfig
+		arg3 config.RecursorOptions
+		arg4 config.Cache
 	}
 	createForwardHandlerReturns struct {
 		result1 dns.Handler
+		result2 error
 	}
 	createForwardHandlerReturnsOnCall map[int]struct {
 		result1 dns.Handler
+		result2 error
 	}
-	CreateHTTPJSONHandlerStub        func(string, bool) dns.Handler
+	CreateHTTPJSONHandlerStub        func([]string, config.HTTPSourceConfig, config.Cache) (dns.Handler, error)
 	createHTTPJSONHandlerMutex       sync.RWMutex
 	createHTTPJSONHandlerArgsForCall []struct {
-		arg1 string
-		arg2 bool
+		arg1 []string
+		arg2 config.HTTPSourceConfig
+		arg3 config.Cache
 	}
 	createHTTPJSONHandlerReturns struct {
 		result1 dns.Handler
+		result2 error
 	}
 	createHTTPJSONHandlerReturnsOnCall map[int]struct {
 		result1 dns.Handler
+		result2 error
+	}
+	CreateHostsHandlerStub        func(string, config.Cache) (dns.Handler, error)
+	createHostsHandlerMutex       sync.RWMutex
+	createHostsHandlerArgsForCall []struct {
+		arg1 string
+		arg2 config.Cache
+	}
+	createHostsHandlerReturns struct {
+		result1 dns.Handler
+		result2 error
+	}
+	createHostsHandlerReturnsOnCall map[int]struct {
+		result1 dns.Handler
+		result2 error
+	}
+	CreateZoneHandlerStub        func(string, string, config.Cache) (dns.Handler, error)
+	createZoneHandlerMutex       sync.RWMutex
+	createZoneHandlerArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 config.Cache
+	}
+	createZoneHandlerReturns struct {
+		result1 dns.Handler
+		result2 error
+	}
+	createZoneHandlerReturnsOnCall map[int]struct {
+		result1 dns.Handler
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 bool) dns.Handler {
+func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 config.RecursorTLSConfig, arg3 config.RecursorOptions, arg4 config.Cache) (dns.Handler, error) {
 	var arg1Copy []string
 	if arg1 != nil {
 		arg1Copy = make([]string, len(arg1))
@@ -47,19 +84,21 @@ func (fake *FakeHandlerFactory) CreateForwardHandler(arg1 []string, arg2 bool) d
 	ret, specificReturn := fake.createForwardHandlerReturnsOnCall[len(fake.createForwardHandlerArgsForCall)]
 	fake.createForwardHandlerArgsForCall = append(fake.createForwardHandlerArgsForCall, struct {
 		arg1 []string
-		arg2 bool
-	}{arg1Copy, arg2})
+		arg2 config.RecursorTLSConfig
+		arg3 config.RecursorOptions
+		arg4 config.Cache
+	}{arg1Copy, arg2, arg3, arg4})
 	stub := fake.CreateForwardHandlerStub
 	fakeReturns := fake.createForwardHandlerReturns
-	fake.recordInvocation("CreateForwardHandler", []interface{}{arg1Copy, arg2})
+	fake.recordInvocation("CreateForwardHandler", []interface{}{arg1Copy, arg2, arg3, arg4})
 	fake.createForwardHandlerMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeHandlerFactory) CreateForwardHandlerCallCount() int {
@@ -68,60 +107,69 @@ func (fake *FakeHandlerFactory) CreateForwardHandlerCallCount() int {
 	return len(fake.createForwardHandlerArgsForCall)
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandlerCalls(stub func([]string, bool) dns.Handler) {
+func (fake *FakeHandlerFactory) CreateForwardHandlerCalls(stub func([]string, config.RecursorTLSConfig, config.RecursorOptions, config.Cache) (dns.Handler, error)) {
 	fake.createForwardHandlerMutex.Lock()
 	defer fake.createForwardHandlerMutex.Unlock()
 	fake.CreateForwardHandlerStub = stub
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandlerArgsForCall(i int) ([]string, bool) {
+func (fake *FakeHandlerFactory) CreateForwardHandlerArgsForCall(i int) ([]string, config.RecursorTLSConfig, config.RecursorOptions, config.Cache) {
 	fake.createForwardHandlerMutex.RLock()
 	defer fake.createForwardHandlerMutex.RUnlock()
 	argsForCall := fake.createForwardHandlerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandlerReturns(result1 dns.Handler) {
+func (fake *FakeHandlerFactory) CreateForwardHandlerReturns(result1 dns.Handler, result2 error) {
 	fake.createForwardHandlerMutex.Lock()
 	defer fake.createForwardHandlerMutex.Unlock()
 	fake.CreateForwardHandlerStub = nil
 	fake.createForwardHandlerReturns = struct {
 		result1 dns.Handler
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeHandlerFactory) CreateForwardHandlerReturnsOnCall(i int, result1 dns.Handler) {
+func (fake *FakeHandlerFactory) CreateForwardHandlerReturnsOnCall(i int, result1 dns.Handler, result2 error) {
 	fake.createForwardHandlerMutex.Lock()
 	defer fake.createForwardHandlerMutex.Unlock()
 	fake.CreateForwardHandlerStub = nil
 	if fake.createForwardHandlerReturnsOnCall == nil {
 		fake.createForwardHandlerReturnsOnCall = make(map[int]struct {
 			result1 dns.Handler
+			result2 error
 		})
 	}
 	fake.createForwardHandlerReturnsOnCall[i] = struct {
 		result1 dns.Handler
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandler(arg1 string, arg2 bool) dns.Handler {
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandler(arg1 []string, arg2 config.HTTPSourceConfig, arg3 config.Cache) (dns.Handler, error) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
 	fake.createHTTPJSONHandlerMutex.Lock()
 	ret, specificReturn := fake.createHTTPJSONHandlerReturnsOnCall[len(fake.createHTTPJSONHandlerArgsForCall)]
 	fake.createHTTPJSONHandlerArgsForCall = append(fake.createHTTPJSONHandlerArgsForCall, struct {
-		arg1 string
-		arg2 bool
-	}{arg1, arg2})
+		arg1 []string
+		arg2 config.HTTPSourceConfig
+		arg3 config.Cache
+	}{arg1Copy, arg2, arg3})
 	stub := fake.CreateHTTPJSONHandlerStub
 	fakeReturns := fake.createHTTPJSONHandlerReturns
-	fake.recordInvocation("CreateHTTPJSONHandler", []interface{}{arg1, arg2})
+	fake.recordInvocation("CreateHTTPJSONHandler", []interface{}{arg1Copy, arg2, arg3})
 	fake.createHTTPJSONHandlerMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCallCount() int {
@@ -130,40 +178,174 @@ func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCallCount() int {
 	return len(fake.createHTTPJSONHandlerArgsForCall)
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCalls(stub func(string, bool) dns.Handler) {
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerCalls(stub func([]string, config.HTTPSourceConfig, config.Cache) (dns.Handler, error)) {
 	fake.createHTTPJSONHandlerMutex.Lock()
 	defer fake.createHTTPJSONHandlerMutex.Unlock()
 	fake.CreateHTTPJSONHandlerStub = stub
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerArgsForCall(i int) (string, bool) {
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerArgsForCall(i int) ([]string, config.HTTPSourceConfig, config.Cache) {
 	fake.createHTTPJSONHandlerMutex.RLock()
 	defer fake.createHTTPJSONHandlerMutex.RUnlock()
 	argsForCall := fake.createHTTPJSONHandlerArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturns(result1 dns.Handler) {
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturns(result1 dns.Handler, result2 error) {
 	fake.createHTTPJSONHandlerMutex.Lock()
 	defer fake.createHTTPJSONHandlerMutex.Unlock()
 	fake.CreateHTTPJSONHandlerStub = nil
 	fake.createHTTPJSONHandlerReturns = struct {
 		result1 dns.Handler
-	}{result1}
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturnsOnCall(i int, result1 dns.Handler) {
+func (fake *FakeHandlerFactory) CreateHTTPJSONHandlerReturnsOnCall(i int, result1 dns.Handler, result2 error) {
 	fake.createHTTPJSONHandlerMutex.Lock()
 	defer fake.createHTTPJSONHandlerMutex.Unlock()
 	fake.CreateHTTPJSONHandlerStub = nil
 	if fake.createHTTPJSONHandlerReturnsOnCall == nil {
 		fake.createHTTPJSONHandlerReturnsOnCall = make(map[int]struct {
 			result1 dns.Handler
+			result2 error
 		})
 	}
 	fake.createHTTPJSONHandlerReturnsOnCall[i] = struct {
 		result1 dns.Handler
-	}{result1}
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandler(arg1 string, arg2 config.Cache) (dns.Handler, error) {
+	fake.createHostsHandlerMutex.Lock()
+	ret, specificReturn := fake.createHostsHandlerReturnsOnCall[len(fake.createHostsHandlerArgsForCall)]
+	fake.createHostsHandlerArgsForCall = append(fake.createHostsHandlerArgsForCall, struct {
+		arg1 string
+		arg2 config.Cache
+	}{arg1, arg2})
+	stub := fake.CreateHostsHandlerStub
+	fakeReturns := fake.createHostsHandlerReturns
+	fake.recordInvocation("CreateHostsHandler", []interface{}{arg1, arg2})
+	fake.createHostsHandlerMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandlerCallCount() int {
+	fake.createHostsHandlerMutex.RLock()
+	defer fake.createHostsHandlerMutex.RUnlock()
+	return len(fake.createHostsHandlerArgsForCall)
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandlerCalls(stub func(string, config.Cache) (dns.Handler, error)) {
+	fake.createHostsHandlerMutex.Lock()
+	defer fake.createHostsHandlerMutex.Unlock()
+	fake.CreateHostsHandlerStub = stub
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandlerArgsForCall(i int) (string, config.Cache) {
+	fake.createHostsHandlerMutex.RLock()
+	defer fake.createHostsHandlerMutex.RUnlock()
+	argsForCall := fake.createHostsHandlerArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandlerReturns(result1 dns.Handler, result2 error) {
+	fake.createHostsHandlerMutex.Lock()
+	defer fake.createHostsHandlerMutex.Unlock()
+	fake.CreateHostsHandlerStub = nil
+	fake.createHostsHandlerReturns = struct {
+		result1 dns.Handler
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHandlerFactory) CreateHostsHandlerReturnsOnCall(i int, result1 dns.Handler, result2 error) {
+	fake.createHostsHandlerMutex.Lock()
+	defer fake.createHostsHandlerMutex.Unlock()
+	fake.CreateHostsHandlerStub = nil
+	if fake.createHostsHandlerReturnsOnCall == nil {
+		fake.createHostsHandlerReturnsOnCall = make(map[int]struct {
+			result1 dns.Handler
+			result2 error
+		})
+	}
+	fake.createHostsHandlerReturnsOnCall[i] = struct {
+		result1 dns.Handler
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandler(arg1 string, arg2 string, arg3 config.Cache) (dns.Handler, error) {
+	fake.createZoneHandlerMutex.Lock()
+	ret, specificReturn := fake.createZoneHandlerReturnsOnCall[len(fake.createZoneHandlerArgsForCall)]
+	fake.createZoneHandlerArgsForCall = append(fake.createZoneHandlerArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 config.Cache
+	}{arg1, arg2, arg3})
+	stub := fake.CreateZoneHandlerStub
+	fakeReturns := fake.createZoneHandlerReturns
+	fake.recordInvocation("CreateZoneHandler", []interface{}{arg1, arg2, arg3})
+	fake.createZoneHandlerMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandlerCallCount() int {
+	fake.createZoneHandlerMutex.RLock()
+	defer fake.createZoneHandlerMutex.RUnlock()
+	return len(fake.createZoneHandlerArgsForCall)
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandlerCalls(stub func(string, string, config.Cache) (dns.Handler, error)) {
+	fake.createZoneHandlerMutex.Lock()
+	defer fake.createZoneHandlerMutex.Unlock()
+	fake.CreateZoneHandlerStub = stub
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandlerArgsForCall(i int) (string, string, config.Cache) {
+	fake.createZoneHandlerMutex.RLock()
+	defer fake.createZoneHandlerMutex.RUnlock()
+	argsForCall := fake.createZoneHandlerArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandlerReturns(result1 dns.Handler, result2 error) {
+	fake.createZoneHandlerMutex.Lock()
+	defer fake.createZoneHandlerMutex.Unlock()
+	fake.CreateZoneHandlerStub = nil
+	fake.createZoneHandlerReturns = struct {
+		result1 dns.Handler
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHandlerFactory) CreateZoneHandlerReturnsOnCall(i int, result1 dns.Handler, result2 error) {
+	fake.createZoneHandlerMutex.Lock()
+	defer fake.createZoneHandlerMutex.Unlock()
+	fake.CreateZoneHandlerStub = nil
+	if fake.createZoneHandlerReturnsOnCall == nil {
+		fake.createZoneHandlerReturnsOnCall = make(map[int]struct {
+			result1 dns.Handler
+			result2 error
+		})
+	}
+	fake.createZoneHandlerReturnsOnCall[i] = struct {
+		result1 dns.Handler
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeHandlerFactory) Invocations() map[string][][]interface{} {
@@ -173,6 +355,10 @@ func (fake *FakeHandlerFactory) Invocations() map[string][][]interface{} {
 	defer fake.createForwardHandlerMutex.RUnlock()
 	fake.createHTTPJSONHandlerMutex.RLock()
 	defer fake.createHTTPJSONHandlerMutex.RUnlock()
+	fake.createHostsHandlerMutex.RLock()
+	defer fake.createHostsHandlerMutex.RUnlock()
+	fake.createZoneHandlerMutex.RLock()
+	defer fake.createZoneHandlerMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -50,7 +50,7 @@ func ConfigureRecursors(reader RecursorReader, dnsConfig *Config) error {
 			recursors[i], recursors[j] = recursors[j], recursors[i]
 		})
 		dnsConfig.Recursors = recursors
-	case SerialRecursorSelection:
+	case SerialRecursorSelection, RaceRecursorSelection, LatencyRecursorSelection:
 		dnsConfig.Recursors = recursors
 	default:
 		return fmt.Errorf("invalid value for recursor selection: '%s'", dnsConfig.RecursorSelection)
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -32,8 +33,12 @@ func GetFreePort() (int, error) {
 }
 
 func WaitForListeningTCP(port int) error {
+	return WaitForListeningTCPOn("127.0.0.1", port)
+}
+
+func WaitForListeningTCPOn(address string, port int) error {
 	for i := 0; i < 20; i++ {
-		c, err := net.Dial("tcp", fmt.Sprintf("%s:%d", "127.0.0.1", port))
+		c, err := net.Dial("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
 		if err != nil {
 			time.Sleep(100 * time.Millisecond)
 			continue
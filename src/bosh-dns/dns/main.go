@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,6 +21,7 @@ import (
 	"github.com/cloudfoundry/bosh-utils/system"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"bosh-dns/dns/api"
 	dnsconfig "bosh-dns/dns/config"
@@ -105,6 +108,12 @@ func mainExitCode() int {
 		return 1
 	}
 
+	links, err := records.LinksFromGlob(fs, config.LinkFilesGlob)
+	if err != nil {
+		logger.Error(logTag, fmt.Sprintf("loading links: %s", err.Error()))
+		return 1
+	}
+
 	handlersConfiguration, err := handlersconfig.ConfigFromGlob(
 		fs,
 		handlersconfig.NewFSLoader(fs),
@@ -149,29 +158,113 @@ func mainExitCode() int {
 
 	shutdown := make(chan struct{})
 
+	interfaceIPs, err := records.InterfaceIPs()
+	if err != nil {
+		logger.Error(logTag, fmt.Sprintf("Unable to list the interface addresses: %s", err.Error()))
+		return 1
+	}
+
 	fileReader := records.NewFileReader(config.RecordsFile, system.NewOsFileSystem(logger), clock, logger, repoUpdate)
 	filtererFactory := records.NewHealthFiltererFactory(healthWatcher, time.Duration(config.Health.SynchronousCheckTimeout))
 	recordSet, err := //nolint:staticcheck
-		records.NewRecordSet(fileReader, aliasConfiguration, healthWatcher, uint(config.Health.MaxTrackedQueries), shutdown, logger, filtererFactory, records.NewAliasEncoder())
+		records.NewRecordSet(fileReader, aliasConfiguration, links, healthWatcher, uint(config.Health.MaxTrackedQueries), shutdown, logger, filtererFactory, records.NewAliasEncoder(), interfaceIPs)
 
 	truncater := dnsresolver.NewResponseTruncater()
-	localDomain := dnsresolver.NewLocalDomain(logger, recordSet, truncater)
+	localDomain := dnsresolver.NewLocalDomain(logger, recordSet, healthWatcher, config.InternalTTL, truncater)
+
+	recursorPool, err := handlers.NewRecursorPool(config.Recursors, config.RecursorSelection, time.Duration(config.RecursorHedgeDelay), config.RecursorMaxRetries, clock, logger)
+	if err != nil {
+		logger.Error(logTag, fmt.Sprintf("Unable to configure recursors: %s", err.Error()))
+		return 1
+	}
+	rankedRecursorPool, _ := recursorPool.(handlers.RankedRecursorPool)
+
+	recursorTLSConfig, err := handlers.NewRecursorTLSConfig(config.RecursorTLS)
+	if err != nil {
+		logger.Error(logTag, fmt.Sprintf("Unable to configure recursor TLS: %s", err.Error()))
+		return 1
+	}
+
+	exchangerFactory := handlers.NewRecursorExchangerFactory(time.Duration(config.RecursorTimeout), recursorTLSConfig)
+
+	// Only the global recursors are probed; the pools of forward handlers
+	// query their recursors regardless.
+	var recursorProber *handlers.RecursorProber
+	if config.RecursorProbe.Enabled {
+		queryType := dns.StringToType[strings.ToUpper(config.RecursorProbe.QueryType)]
+		recursorProber = handlers.NewRecursorProber(config.Recursors, exchangerFactory("udp"), config.RecursorProbe.Domain, queryType, time.Duration(config.RecursorProbe.Interval), clock, logger)
+		recursorPool = handlers.NewProbedRecursorPool(recursorPool, recursorProber)
+	}
 
-	recursorPool := handlers.NewFailoverRecursorPool(config.Recursors, config.RecursorSelection, config.RecursorMaxRetries, logger)
-	exchangerFactory := handlers.NewExchangerFactory(time.Duration(config.RecursorTimeout))
 	forwardHandler := handlers.NewForwardHandler(recursorPool, exchangerFactory, clock, logger, truncater)
 
-	mux.Handle("arpa.", handlers.NewRequestLoggerHandler(handlers.NewArpaHandler(logger, recordSet, forwardHandler), clock, logger))
+	hostsFiles := []string{}
+	if config.HostsFilesGlob != "" {
+		hostsFiles, err = fs.Glob(config.HostsFilesGlob)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("loading hosts files: %s", err.Error()))
+			return 1
+		}
+	}
+
+	// names listed in hosts files take priority over forwarding
+	withHosts := func(next dns.Handler) dns.Handler { return next }
+	if len(hostsFiles) > 0 {
+		hostsFileReaders := []records.FileReader{}
+		for _, hostsFile := range hostsFiles {
+			hostsFileReaders = append(hostsFileReaders, records.NewFileReader(hostsFile, fs, clock, logger, shutdown))
+		}
+
+		hosts := handlers.NewHosts(hostsFileReaders, shutdown, logger)
+		withHosts = func(next dns.Handler) dns.Handler {
+			return handlers.NewHostsHandler(hosts, next, logger, truncater)
+		}
+	}
+
+	policyFiles := []string{}
+	if config.Policy.FilesGlob != "" {
+		policyFiles, err = fs.Glob(config.Policy.FilesGlob)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("loading policy files: %s", err.Error()))
+			return 1
+		}
+	}
+
+	// block lists apply before forwarding, after any hosts file override
+	var policy *handlers.Policy
+	withPolicy := func(next dns.Handler) dns.Handler { return next }
+	if len(policyFiles) > 0 {
+		policyLists := []handlers.PolicyList{}
+		for _, policyFile := range policyFiles {
+			policyLists = append(policyLists, handlers.PolicyList{
+				Name:       filepath.Base(policyFile),
+				RPZ:        filepath.Ext(policyFile) == ".rpz",
+				FileReader: records.NewFileReader(policyFile, fs, clock, logger, shutdown),
+			})
+		}
+
+		policy = handlers.NewPolicy(policyLists, config.Policy, shutdown, logger)
+		withPolicy = func(next dns.Handler) dns.Handler {
+			return handlers.NewPolicyHandler(policy, next, logger, truncater)
+		}
+	}
+
+	mux.Handle("arpa.", handlers.NewRequestLoggerHandler(handlers.NewArpaHandler(logger, recordSet, withHosts(withPolicy(forwardHandler)), config.InternalTTL), clock, logger))
 
-	handlerFactory := handlers.NewFactory(exchangerFactory, clock, config.RecursorMaxRetries, logger, truncater)
+	handlerFactory := handlers.NewFactory(exchangerFactory, recursorTLSConfig, config.RecursorSelection, time.Duration(config.RecursorTimeout), time.Duration(config.RecursorHedgeDelay), clock, config.RecursorMaxRetries, shutdown, logger, truncater)
 
 	delegatingHandlers, err := handlersConfiguration.GenerateHandlers(handlerFactory)
 	if err != nil {
 		logger.Error(logTag, err.Error())
 		return 1
 	}
+	cacheInspectors := api.CacheInspectors{}
 	for domain, handler := range delegatingHandlers {
-		mux.Handle(domain, handlers.NewRequestLoggerHandler(handler, clock, logger))
+		mux.Handle(domain, handlers.NewRequestLoggerHandler(withHosts(withPolicy(handler)), clock, logger))
+
+		if cachingHandler, ok := handler.(handlers.CachingDNSHandler); ok {
+			cacheInspectors = append(cacheInspectors, cachingHandler)
+		}
 	}
 
 	listenAddrs := []string{fmt.Sprintf("%s:%d", config.Address, config.Port)}
@@ -181,7 +274,7 @@ func mainExitCode() int {
 
 	upchecks := []server.Upcheck{}
 	for _, upcheckDomain := range config.UpcheckDomains {
-		mux.Handle(upcheckDomain, handlers.NewRequestLoggerHandler(handlers.NewUpcheckHandler(logger), clock, logger))
+		mux.Handle(upcheckDomain, handlers.NewRequestLoggerHandler(handlers.NewUpcheckHandler(logger, config.InternalTTL.ForDomain(upcheckDomain)), clock, logger))
 		for _, addr := range listenAddrs {
 			upchecks = append(upchecks, server.NewDNSAnswerValidatingUpcheck(addr, upcheckDomain, "udp", logger))
 			upchecks = append(upchecks, server.NewDNSAnswerValidatingUpcheck(addr, upcheckDomain, "tcp", logger))
@@ -198,15 +291,31 @@ func mainExitCode() int {
 		metricsServerWrapper *monitoring.MetricsServerWrapper
 	)
 	if config.Cache.Enabled {
-		nextExternalHandler = handlers.NewCachingDNSHandler(nextExternalHandler, truncater, clock, logger)
+		cachingHandler, err := handlers.NewCachingDNSHandler(nextExternalHandler, config.Cache, truncater, clock, logger)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to configure cache: %s", err.Error()))
+			return 1
+		}
+		nextExternalHandler = cachingHandler
+		cacheInspectors = append(cacheInspectors, cachingHandler)
 	}
 	if config.Metrics.Enabled {
 		metricsAddr := fmt.Sprintf("%s:%d", config.Metrics.Address, config.Metrics.Port)
 		metricsServerWrapper = monitoring.NewMetricsServerWrapper(logger, monitoring.MetricsServer(metricsAddr, nextInternalHandler, nextExternalHandler))
 		nextExternalHandler = handlers.NewMetricsDNSHandler(metricsServerWrapper.MetricsReporter(), monitoring.DNSRequestTypeExternal)
 		nextInternalHandler = handlers.NewMetricsDNSHandler(metricsServerWrapper.MetricsReporter(), monitoring.DNSRequestTypeInternal)
+
+		if rankedRecursorPool != nil {
+			prometheus.MustRegister(handlers.NewRecursorRankingCollector(rankedRecursorPool))
+		}
+		if recursorProber != nil {
+			prometheus.MustRegister(handlers.NewRecursorStatusCollector(recursorProber))
+		}
+		if policy != nil {
+			prometheus.MustRegister(handlers.NewPolicyHitsCollector(policy))
+		}
 	}
-	mux.Handle(".", nextExternalHandler)
+	mux.Handle(".", withHosts(withPolicy(nextExternalHandler)))
 
 	servers := []server.DNSServer{}
 	numListeners := runtime.NumCPU()
@@ -223,6 +332,48 @@ func mainExitCode() int {
 		}
 	}
 
+	if config.DNSOverTLS.Enabled {
+		tlsConfig, err := tlsconfig.Build(
+			tlsconfig.WithIdentityFromFile(config.DNSOverTLS.CertificateFile, config.DNSOverTLS.PrivateKeyFile),
+			tlsconfig.WithInternalServiceDefaults(),
+		).Server()
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to configure DNS over TLS listener: %s", err.Error()))
+			return 1
+		}
+
+		servers = append(servers,
+			&dns.Server{Addr: fmt.Sprintf("%s:%d", config.DNSOverTLS.Address, config.DNSOverTLS.Port), Net: "tcp-tls", TLSConfig: tlsConfig, Handler: mux, ReadTimeout: time.Duration(config.RequestTimeout), WriteTimeout: time.Duration(config.RequestTimeout)},
+		)
+	}
+
+	if config.DNSOverHTTPS.Enabled {
+		serverOptions := []tlsconfig.ServerOption{}
+		if config.DNSOverHTTPS.CAFile != "" {
+			serverOptions = append(serverOptions, tlsconfig.WithClientAuthenticationFromFile(config.DNSOverHTTPS.CAFile))
+		}
+
+		tlsConfig, err := tlsconfig.Build(
+			tlsconfig.WithIdentityFromFile(config.DNSOverHTTPS.CertificateFile, config.DNSOverHTTPS.PrivateKeyFile),
+			tlsconfig.WithInternalServiceDefaults(),
+		).Server(serverOptions...)
+		if err != nil {
+			logger.Error(logTag, fmt.Sprintf("Unable to configure DNS over HTTPS listener: %s", err.Error()))
+			return 1
+		}
+
+		dohMux := http.NewServeMux()
+		dohMux.Handle("/dns-query", api.NewDNSOverHTTPSHandler(mux, logger))
+
+		servers = append(servers, server.NewHTTPSServer(&http.Server{
+			Addr:         fmt.Sprintf("%s:%d", config.DNSOverHTTPS.Address, config.DNSOverHTTPS.Port),
+			Handler:      dohMux,
+			TLSConfig:    tlsConfig,
+			ReadTimeout:  time.Duration(config.RequestTimeout),
+			WriteTimeout: time.Duration(config.RequestTimeout),
+		}))
+	}
+
 	dnsServer := server.New(
 		servers,
 		upchecks,
@@ -250,6 +401,9 @@ func mainExitCode() int {
 	}
 
 	go healthWatcher.Run(shutdown)
+	if recursorProber != nil {
+		go recursorProber.Run(shutdown)
+	}
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGTERM)
@@ -268,6 +422,15 @@ func mainExitCode() int {
 
 	http.Handle("/instances", api.NewInstancesHandler(recordSet, healthWatcher))
 	http.Handle("/local-groups", api.NewLocalGroupsHandler(jobs, healthChecker))
+	if len(cacheInspectors) > 0 {
+		http.Handle("/cache", api.NewCacheHandler(cacheInspectors))
+	}
+	if rankedRecursorPool != nil {
+		http.Handle("/recursors", api.NewRecursorsHandler(rankedRecursorPool))
+	}
+	if recursorProber != nil {
+		http.Handle("/recursor-status", api.NewRecursorStatusHandler(recursorProber))
+	}
 
 	go func(config dnsconfig.APIConfig) {
 		tlsConfig, err := tlsconfig.Build(
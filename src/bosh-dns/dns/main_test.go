@@ -1,6 +1,9 @@
 package main_test
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -111,6 +114,7 @@ var _ = Describe("main", func() {
 			cmd                 *exec.Cmd
 			handlersDir         string
 			healthEnabled       bool
+			linksDir            string
 			metricsEnabled      bool
 			httpJSONServer      *ghttp.Server
 			recordsFilePath     string
@@ -118,10 +122,14 @@ var _ = Describe("main", func() {
 			recordsJSONContent  string
 			aliases1JSONContent string
 			aliases2JSONContent string
+			dnsOverTLS          config.DNSOverTLSConfig
+			dnsOverHTTPS        config.DNSOverHTTPSConfig
 		)
 
 		BeforeEach(func() {
 			checkInterval = 100 * time.Millisecond
+			dnsOverTLS = config.DNSOverTLSConfig{}
+			dnsOverHTTPS = config.DNSOverHTTPSConfig{}
 			recordsJSONContent = `{
 				"record_keys": ["id", "num_id", "instance_group", "group_ids", "az", "az_id","network", "deployment", "ip", "domain"],
 				"record_infos": [
@@ -216,6 +224,11 @@ var _ = Describe("main", func() {
 			_, err = aliasesFile2.Write([]byte(aliases2JSONContent))
 			Expect(err).NotTo(HaveOccurred())
 
+			linksDir, err = os.MkdirTemp("", "links")
+			Expect(err).NotTo(HaveOccurred())
+			err = os.WriteFile(path.Join(linksDir, "links.json"), []byte(`[{"group_id": "7", "name": "http", "port": 8080}]`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+
 			var handlersFilesGlob string
 			if handlersDir != "" {
 				handlersFilesGlob = path.Join(handlersDir, "*")
@@ -239,6 +252,7 @@ var _ = Describe("main", func() {
 			cfg.RecordsFile = recordsFilePath
 			cfg.AddressesFilesGlob = path.Join(addressesDir, "*")
 			cfg.AliasFilesGlob = path.Join(aliasesDir, "*")
+			cfg.LinkFilesGlob = path.Join(linksDir, "*")
 			cfg.JobsDir = jobsDir
 			cfg.HandlersFilesGlob = handlersFilesGlob
 			cfg.UpcheckDomains = []string{"health.check.bosh.", "health.check.ca."}
@@ -250,6 +264,9 @@ var _ = Describe("main", func() {
 				PrivateKeyFile:  "api/assets/test_certs/test_server.key",
 			}
 
+			cfg.DNSOverTLS = dnsOverTLS
+			cfg.DNSOverHTTPS = dnsOverHTTPS
+
 			cfg.Health = config.HealthConfig{
 				Enabled:         healthEnabled,
 				Port:            2345 + suiteConfig.ParallelProcess,
@@ -270,7 +287,7 @@ var _ = Describe("main", func() {
 			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(testhelpers.WaitForListeningTCP(listenPort)).To(Succeed())
+			Expect(testhelpers.WaitForListeningTCPOn(listenAddress, listenPort)).To(Succeed())
 			Expect(testhelpers.WaitForListeningTCP(listenAPIPort)).To(Succeed())
 
 			Eventually(func() int {
@@ -294,6 +311,7 @@ var _ = Describe("main", func() {
 
 			Expect(os.RemoveAll(aliasesDir)).To(Succeed())
 			Expect(os.RemoveAll(addressesDir)).To(Succeed())
+			Expect(os.RemoveAll(linksDir)).To(Succeed())
 
 			if httpJSONServer != nil {
 				httpJSONServer.Close()
@@ -325,6 +343,127 @@ var _ = Describe("main", func() {
 				itResponds("udp", fmt.Sprintf("%s:%d", listenAddress2, listenPort2))
 				itResponds("tcp", fmt.Sprintf("%s:%d", listenAddress2, listenPort2))
 			})
+
+			Context("when the listen address is not the address of an instance", func() {
+				BeforeEach(func() {
+					// sudo ifconfig lo0 alias 127.0.0.5 up # on osx
+					listenAddress = "127.0.0.5"
+				})
+
+				It("orders the instances in the AZ of the VM first", func() {
+					for i := 0; i < 10; i++ {
+						c := &dns.Client{}
+						m := &dns.Msg{}
+						SetQuestion(m, nil, "q-o2s4.my-group.my-network.my-deployment.bosh.", dns.TypeA)
+						r, _, err := c.Exchange(m, fmt.Sprintf("%s:%d", listenAddress, listenPort))
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(r.Answer).To(HaveLen(2))
+						Expect(r.Answer[0].(*dns.A).A.String()).To(Equal("127.0.0.1"))
+					}
+				})
+			})
+
+			It("answers service queries with the ports of the links listed by the link files", func() {
+				c := &dns.Client{}
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "_http._tcp.q-s4.my-group.my-network.my-deployment.bosh.", dns.TypeSRV)
+				r, _, err := c.Exchange(m, fmt.Sprintf("%s:%d", listenAddress, listenPort))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(r.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(r.Answer).To(HaveLen(2))
+				for _, answer := range r.Answer {
+					Expect(answer.(*dns.SRV).Port).To(Equal(uint16(8080)))
+				}
+			})
+
+			Context("when DNS over TLS is enabled", func() {
+				BeforeEach(func() {
+					port, err := testhelpers.GetFreePort()
+					Expect(err).NotTo(HaveOccurred())
+
+					dnsOverTLS = config.DNSOverTLSConfig{
+						Enabled:         true,
+						Address:         listenAddress,
+						Port:            port,
+						CertificateFile: "api/assets/test_certs/test_server.pem",
+						PrivateKeyFile:  "api/assets/test_certs/test_server.key",
+					}
+				})
+
+				It("responds over TLS", func() {
+					Expect(testhelpers.WaitForListeningTCP(dnsOverTLS.Port)).To(Succeed())
+
+					caCert, err := os.ReadFile("api/assets/test_certs/test_ca.pem")
+					Expect(err).NotTo(HaveOccurred())
+					caPool := x509.NewCertPool()
+					Expect(caPool.AppendCertsFromPEM(caCert)).To(BeTrue())
+
+					c := &dns.Client{
+						Net:       "tcp-tls",
+						TLSConfig: &tls.Config{RootCAs: caPool, ServerName: "api.bosh-dns"},
+					}
+
+					m := &dns.Msg{}
+					SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
+					r, _, err := c.Exchange(m, fmt.Sprintf("%s:%d", listenAddress, dnsOverTLS.Port))
+
+					Expect(err).NotTo(HaveOccurred())
+					Expect(r.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(r.Answer).To(HaveLen(1))
+					Expect(r.Answer[0].(*dns.A).A.String()).To(Equal("127.0.0.1"))
+				})
+			})
+
+			Context("when DNS over HTTPS is enabled", func() {
+				BeforeEach(func() {
+					port, err := testhelpers.GetFreePort()
+					Expect(err).NotTo(HaveOccurred())
+
+					dnsOverHTTPS = config.DNSOverHTTPSConfig{
+						Enabled:         true,
+						Address:         listenAddress,
+						Port:            port,
+						CertificateFile: "api/assets/test_certs/test_server.pem",
+						PrivateKeyFile:  "api/assets/test_certs/test_server.key",
+					}
+				})
+
+				It("responds to RFC 8484 requests", func() {
+					Expect(testhelpers.WaitForListeningTCP(dnsOverHTTPS.Port)).To(Succeed())
+
+					caCert, err := os.ReadFile("api/assets/test_certs/test_ca.pem")
+					Expect(err).NotTo(HaveOccurred())
+					caPool := x509.NewCertPool()
+					Expect(caPool.AppendCertsFromPEM(caCert)).To(BeTrue())
+
+					client := &http.Client{
+						Transport: &http.Transport{
+							TLSClientConfig: &tls.Config{RootCAs: caPool, ServerName: "api.bosh-dns"},
+						},
+					}
+
+					m := &dns.Msg{}
+					SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
+					packed, err := m.Pack()
+					Expect(err).NotTo(HaveOccurred())
+
+					resp, err := client.Post(fmt.Sprintf("https://%s:%d/dns-query", listenAddress, dnsOverHTTPS.Port), "application/dns-message", bytes.NewReader(packed))
+					Expect(err).NotTo(HaveOccurred())
+					defer resp.Body.Close()
+					Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+					body, err := io.ReadAll(resp.Body)
+					Expect(err).NotTo(HaveOccurred())
+
+					r := &dns.Msg{}
+					Expect(r.Unpack(body)).To(Succeed())
+					Expect(r.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(r.Answer).To(HaveLen(1))
+					Expect(r.Answer[0].(*dns.A).A.String()).To(Equal("127.0.0.1"))
+				})
+			})
 		})
 
 		Describe("HTTP API", func() {
@@ -754,6 +893,15 @@ var _ = Describe("main", func() {
 
 						Eventually(session.Out).Should(gbytes.Say(`\[RequestLoggerHandler\].*DEBUG \- handlers\.DiscoveryHandler Request id=\d+ qtype=\[A\] qname=\[` + casedQname + `\] rcode=NOERROR ancount=1 time=\d+ns`))
 					})
+
+					It("answers TXT questions without records", func() {
+						m.Question[0].Qtype = dns.TypeTXT
+						response, _, err := c.Exchange(m, fmt.Sprintf("%s:%d", listenAddress, listenPort))
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(response.Rcode).To(Equal(dns.RcodeSuccess))
+						Expect(response.Answer).To(BeEmpty())
+					})
 				})
 
 				Context("with multiple resolving addresses", func() {
@@ -1716,6 +1864,39 @@ var _ = Describe("main", func() {
 			Eventually(session).Should(gexec.Exit(1))
 		})
 
+		It("exits 1 when fails to bind to the DNS over HTTPS port", func() {
+			listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(listenAddress)})
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			cfg := config.NewDefaultConfig()
+			cfg.Address = listenAddress
+			cfg.Port = listenPort
+			cfg.UpcheckDomains = []string{"upcheck.bosh-dns."}
+			cfg.JobsDir = jobsDir
+			cfg.DNSOverHTTPS = config.DNSOverHTTPSConfig{
+				Enabled:         true,
+				Address:         listenAddress,
+				Port:            listener.Addr().(*net.TCPAddr).Port,
+				CertificateFile: "api/assets/test_certs/test_server.pem",
+				PrivateKeyFile:  "api/assets/test_certs/test_server.key",
+			}
+
+			cfg.API = config.APIConfig{
+				Port:            listenAPIPort,
+				CAFile:          "api/assets/test_certs/test_ca.pem",
+				CertificateFile: "api/assets/test_certs/test_server.pem",
+				PrivateKeyFile:  "api/assets/test_certs/test_server.key",
+			}
+			cmd = newCommandWithConfig(cfg)
+
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session, "5s").Should(gexec.Exit(1))
+			Eventually(session.Out).Should(gbytes.Say("[main].*ERROR - bosh-dns failed: .*address already in use"))
+		})
+
 		It("exits 1 and logs a helpful error message when the server times out binding to ports", func() {
 			cfg := config.NewDefaultConfig()
 			cfg.Address = listenAddress
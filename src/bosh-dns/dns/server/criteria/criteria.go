@@ -10,7 +10,7 @@ import (
 	"bosh-dns/dns/server/record"
 )
 
-var keyValueRegex = regexp.MustCompile("(a|i|s|m|n|y)([0-9]+)")
+var keyValueRegex = regexp.MustCompile("(a|c|i|s|m|n|o|p|t|y)([0-9]+)")
 var groupRegex = regexp.MustCompile("^q-g([0-9]+)$")
 
 type Criteria map[string][]string
@@ -32,7 +32,7 @@ func NewCriteria(fqdn string, domains []string) (Criteria, error) {
 func (c Criteria) Matcher() Matcher {
 	matcher := new(AndMatcher)
 	for field, values := range c {
-		if field == "y" || field == "s" || field == "fqdn" {
+		if field == "y" || field == "s" || field == "o" || field == "c" || field == "p" || field == "t" || field == "fqdn" {
 			continue
 		}
 		matcher.Append(Field(field, values))
@@ -18,6 +18,34 @@ var _ = Describe("Criteria", func() {
 			Expect(c).To(BeAssignableToTypeOf(criteria.Criteria{}))
 		})
 
+		It("parses the port segment without matching on it", func() {
+			c, err := criteria.NewCriteria("q-p8080s0.q-g7.bosh.", []string{"bosh."})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c["p"]).To(Equal([]string{"8080"}))
+			Expect(c.Matcher().Match(&record.Record{GroupIDs: []string{"7"}, Domain: "bosh."})).To(BeTrue())
+		})
+
+		It("parses the ttl segment without matching on it", func() {
+			c, err := criteria.NewCriteria("q-s0t30.q-g7.bosh.", []string{"bosh."})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c["t"]).To(Equal([]string{"30"}))
+			Expect(c.Matcher().Match(&record.Record{GroupIDs: []string{"7"}, Domain: "bosh."})).To(BeTrue())
+		})
+
+		It("parses the order segment without matching on it", func() {
+			c, err := criteria.NewCriteria("q-o2s0.q-g7.bosh.", []string{"bosh."})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c["o"]).To(Equal([]string{"2"}))
+			Expect(c.Matcher().Match(&record.Record{GroupIDs: []string{"7"}, Domain: "bosh."})).To(BeTrue())
+		})
+
+		It("parses the count segment without matching on it", func() {
+			c, err := criteria.NewCriteria("q-c3s0.q-g7.bosh.", []string{"bosh."})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c["c"]).To(Equal([]string{"3"}))
+			Expect(c.Matcher().Match(&record.Record{GroupIDs: []string{"7"}, Domain: "bosh."})).To(BeTrue())
+		})
+
 		It("returns an error when failing to parse segments", func() {
 			_, err := criteria.NewCriteria("garbage", []string{})
 			Expect(err).To(MatchError("domain is malformed"))
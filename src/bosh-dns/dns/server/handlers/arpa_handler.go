@@ -6,6 +6,8 @@ import (
 
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/miekg/dns"
+
+	"bosh-dns/dns/config"
 )
 
 //counterfeiter:generate . IPProvider
@@ -24,14 +26,16 @@ type ArpaHandler struct {
 	logger         logger.Logger
 	ipProvider     IPProvider
 	forwardHandler DNSHandler
+	ttls           config.InternalTTLConfig
 	logTag         string
 }
 
-func NewArpaHandler(logger logger.Logger, i IPProvider, h DNSHandler) ArpaHandler {
+func NewArpaHandler(logger logger.Logger, i IPProvider, h DNSHandler, ttls config.InternalTTLConfig) ArpaHandler {
 	return ArpaHandler{
 		logger:         logger,
 		forwardHandler: h,
 		ipProvider:     i,
+		ttls:           ttls,
 		logTag:         "ArpaHandler",
 	}
 }
@@ -92,13 +96,14 @@ func (a ArpaHandler) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	fqdns := a.ipProvider.GetFQDNs(ip)
 	if len(fqdns) > 0 {
 		m.SetRcode(req, dns.RcodeSuccess)
+		ttl := a.ttl(fqdns)
 		for _, fqdn := range fqdns {
 			m.Answer = append(m.Answer, &dns.PTR{
 				Hdr: dns.RR_Header{
 					Name:   req.Question[0].Name,
 					Rrtype: dns.TypePTR,
 					Class:  dns.ClassINET,
-					Ttl:    0,
+					Ttl:    ttl,
 				},
 				Ptr: fqdn,
 			})
@@ -111,6 +116,20 @@ func (a ArpaHandler) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
 	a.forwardHandler.ServeDNS(w, req)
 }
 
+// ttl returns the TTL configured for the domains of the names an address
+// points to. The records of an answer share the lowest one, since they belong
+// to the same RRset.
+func (a ArpaHandler) ttl(fqdns []string) uint32 {
+	ttl := a.ttls.ForDomain(fqdns[0])
+	for _, fqdn := range fqdns[1:] {
+		if fqdnTTL := a.ttls.ForDomain(fqdn); fqdnTTL < ttl {
+			ttl = fqdnTTL
+		}
+	}
+
+	return ttl
+}
+
 func (a ArpaHandler) logErrors(w dns.ResponseWriter, err error) {
 	if err != nil {
 		a.logger.Error(a.logTag, err.Error())
@@ -6,6 +6,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/internal/testhelpers/question_case_helpers"
 	"bosh-dns/dns/server/handlers"
 	"bosh-dns/dns/server/handlers/handlersfakes"
@@ -28,7 +29,7 @@ var _ = Describe("ArpaHandler", func() {
 			fakeIPProvider = &handlersfakes.FakeIPProvider{}
 			fakeForwarder = &handlersfakes.FakeDNSHandler{}
 
-			arpaHandler = handlers.NewArpaHandler(fakeLogger, fakeIPProvider, fakeForwarder)
+			arpaHandler = handlers.NewArpaHandler(fakeLogger, fakeIPProvider, fakeForwarder, config.InternalTTLConfig{})
 		})
 
 		Context("when there are no questions", func() {
@@ -79,6 +80,47 @@ var _ = Describe("ArpaHandler", func() {
 						Expect(len(message.Answer)).To(Equal(2))
 						Expect(message.Answer[0].(*dns.PTR).Ptr).To(Equal("instance.fqdn"))
 						Expect(message.Answer[1].(*dns.PTR).Ptr).To(Equal("index.fqdn"))
+						Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(0)))
+					})
+
+					Context("when TTLs are configured for the domains of the records", func() {
+						BeforeEach(func() {
+							fakeIPProvider.GetFQDNsReturns([]string{
+								"instance.group.network.deployment.bosh.",
+								"0.group.network.other-deployment.bosh.",
+							})
+							arpaHandler = handlers.NewArpaHandler(fakeLogger, fakeIPProvider, fakeForwarder, config.InternalTTLConfig{
+								Default: 5,
+								Domains: map[string]uint32{
+									"in-addr.arpa.":          300,
+									"deployment.bosh.":       60,
+									"other-deployment.bosh.": 30,
+								},
+							})
+						})
+
+						It("responds with PTR records using the lowest TTL of their domains", func() {
+							m := &dns.Msg{}
+							SetQuestion(m, nil, "4.3.2.1.in-addr.arpa.", dns.TypePTR)
+
+							arpaHandler.ServeDNS(fakeWriter, m)
+							message := fakeWriter.WriteMsgArgsForCall(0)
+							Expect(len(message.Answer)).To(Equal(2))
+							Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+							Expect(message.Answer[1].Header().Ttl).To(Equal(uint32(30)))
+						})
+
+						It("uses the TTL of the domain of a single record", func() {
+							fakeIPProvider.GetFQDNsReturns([]string{"instance.group.network.deployment.bosh."})
+
+							m := &dns.Msg{}
+							SetQuestion(m, nil, "4.3.2.1.in-addr.arpa.", dns.TypePTR)
+
+							arpaHandler.ServeDNS(fakeWriter, m)
+							message := fakeWriter.WriteMsgArgsForCall(0)
+							Expect(len(message.Answer)).To(Equal(1))
+							Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(60)))
+						})
 					})
 				})
 			})
@@ -1,47 +1,224 @@
 package handlers
 
 import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	"code.cloudfoundry.org/clock"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin/cache"
 	"github.com/miekg/dns"
 	"golang.org/x/net/context"
 
+	"bosh-dns/dns/config"
 	"bosh-dns/dns/server/handlers/internal"
 	"bosh-dns/dns/server/records/dnsresolver"
 )
 
+// Defaults of the CoreDNS cache plugin, which apply to settings left unset.
+const (
+	cacheDefaultCapacity           = 10000
+	cacheDefaultSuccessMaxTTL      = time.Hour
+	cacheDefaultDenialMaxTTL       = 30 * time.Minute
+	cacheDefaultMinTTL             = 5 * time.Second
+	cacheDefaultPrefetchDuration   = time.Minute
+	cacheDefaultPrefetchPercentage = 10
+	cacheMaxServFailTTL            = 5 * time.Minute
+)
+
 type CachingDNSHandler struct {
 	next      dns.Handler
-	ca        *cache.Cache
+	store     *cacheStore
 	logger    boshlog.Logger
 	logTag    string
 	truncater dnsresolver.ResponseTruncater
 	clock     clock.Clock
+
+	// staleTTL is the TTL of the records of expired answers
+	staleTTL uint32
 }
 
+// requestContext is shared with the refreshes the cache plugin makes in the
+// background, which may still run after the request has been answered.
 type requestContext struct {
-	fromCache bool
+	fromCache atomic.Bool
+	stale     atomic.Bool
+
+	// lookupOnly keeps the request from being forwarded on a cache miss
+	lookupOnly bool
 }
 
-func NewCachingDNSHandler(next dns.Handler, truncater dnsresolver.ResponseTruncater, clock clock.Clock, logger boshlog.Logger) CachingDNSHandler {
-	ca := cache.New()
-	ca.Next = corednsHandlerWrapper{Next: next}
-	ca.Zones = []string{"."}
+func NewCachingDNSHandler(next dns.Handler, cacheConfig config.Cache, truncater dnsresolver.ResponseTruncater, clock clock.Clock, logger boshlog.Logger) (CachingDNSHandler, error) {
+	if _, err := newCorednsCache(cacheConfig); err != nil {
+		return CachingDNSHandler{}, err
+	}
+
+	successMinTTL, successMaxTTL := cacheTTLBounds(cacheConfig, cacheDefaultSuccessMaxTTL)
+	denialMinTTL, denialMaxTTL := cacheTTLBounds(cacheConfig, cacheDefaultDenialMaxTTL)
+
+	store := &cacheStore{
+		capacity:      cacheCapacity(cacheConfig.SuccessCapacity) + cacheCapacity(cacheConfig.DenialCapacity),
+		successMinTTL: successMinTTL,
+		successMaxTTL: successMaxTTL,
+		denialMinTTL:  denialMinTTL,
+		denialMaxTTL:  denialMaxTTL,
+		nxdomain:      cacheConfig.NXDomain,
+		nodata:        cacheConfig.NoData,
+		servfail:      cacheConfig.ServFail,
+		clock:         clock,
+		entries:       map[cacheEntryKey]*list.Element{},
+		recorded:      list.New(),
+		flushed:       map[string]time.Time{},
+	}
+	store.newCache = func() *cache.Cache {
+		// the configuration has been validated above
+		ca, _ := newCorednsCache(cacheConfig)
+		ca.Next = corednsHandlerWrapper{Next: next, store: store}
+		ca.Zones = []string{"."}
+		return ca
+	}
+	store.ca = store.newCache()
+
+	if cacheConfig.ServeStale.Enabled {
+		store.maxStale = time.Duration(cacheConfig.ServeStale.MaxStale)
+	}
+
 	return CachingDNSHandler{
-		ca:        ca,
+		store:     store,
 		logTag:    "CachingDNSHandler",
 		next:      next,
 		truncater: truncater,
 		clock:     clock,
 		logger:    logger,
+		staleTTL:  uint32(time.Duration(cacheConfig.ServeStale.TTL).Seconds()),
+	}, nil
+}
+
+// newCorednsCache configures the CoreDNS cache plugin through its own
+// Corefile parser, since its settings cannot be set directly.
+func newCorednsCache(cacheConfig config.Cache) (*cache.Cache, error) {
+	if cacheConfig.MinTTL > 0 && cacheConfig.MaxTTL > 0 && cacheConfig.MinTTL > cacheConfig.MaxTTL {
+		return nil, errors.New("cache min_ttl must not be greater than max_ttl")
+	}
+
+	if cacheConfig.NXDomain.TTL < 0 || cacheConfig.NoData.TTL < 0 || cacheConfig.ServFail.TTL < 0 {
+		return nil, errors.New("cache nxdomain, nodata and servfail ttls must not be negative")
+	}
+	if time.Duration(cacheConfig.ServFail.TTL) > cacheMaxServFailTTL {
+		// RFC 2308 prohibits caching SERVFAIL answers any longer
+		return nil, fmt.Errorf("cache servfail ttl must not be greater than %s", cacheMaxServFailTTL)
+	}
+
+	// negative answers are cached by the cacheStore
+	directives := []string{
+		"success " + cacheCapacityArgs(cacheConfig.SuccessCapacity, cacheConfig, cacheDefaultSuccessMaxTTL),
+		"denial " + cacheCapacityArgs(cacheConfig.DenialCapacity, cacheConfig, cacheDefaultDenialMaxTTL),
+		"disable denial",
+	}
+
+	if prefetch := cacheConfig.Prefetch; prefetch.Amount > 0 {
+		duration, percentage := time.Duration(prefetch.Duration), prefetch.Percentage
+		if duration == 0 {
+			duration = cacheDefaultPrefetchDuration
+		}
+		if percentage == 0 {
+			percentage = cacheDefaultPrefetchPercentage
+		}
+
+		directives = append(directives, fmt.Sprintf("prefetch %d %s %d%%", prefetch.Amount, duration, percentage))
+	}
+
+	if serveStale := cacheConfig.ServeStale; serveStale.Enabled {
+		// expired answers are only served when refreshing them fails
+		directives = append(directives, fmt.Sprintf("serve_stale %s verify", time.Duration(serveStale.MaxStale)))
+	}
+
+	setup, err := caddy.DirectiveAction("dns", "cache")
+	if err != nil {
+		return nil, err
+	}
+
+	controller := caddy.NewTestController("dns", "cache {\n"+strings.Join(directives, "\n")+"\n}")
+	if err := setup(controller); err != nil {
+		return nil, err
+	}
+
+	return dnsserver.GetConfig(controller).Plugin[0](nil).(*cache.Cache), nil
+}
+
+func cacheCapacityArgs(capacity int, cacheConfig config.Cache, defaultMaxTTL time.Duration) string {
+	capacity = cacheCapacity(capacity)
+
+	if cacheConfig.MinTTL == 0 && cacheConfig.MaxTTL == 0 {
+		return strconv.Itoa(capacity)
+	}
+
+	minTTL, maxTTL := cacheTTLBounds(cacheConfig, defaultMaxTTL)
+	return fmt.Sprintf("%d %d %d", capacity, int(maxTTL.Seconds()), int(minTTL.Seconds()))
+}
+
+func cacheCapacity(capacity int) int {
+	if capacity == 0 {
+		return cacheDefaultCapacity
 	}
+	return capacity
+}
+
+func cacheTTLBounds(cacheConfig config.Cache, defaultMaxTTL time.Duration) (time.Duration, time.Duration) {
+	minTTL, maxTTL := time.Duration(cacheConfig.MinTTL), time.Duration(cacheConfig.MaxTTL)
+	if minTTL == 0 {
+		minTTL = cacheDefaultMinTTL
+	}
+	if maxTTL == 0 {
+		maxTTL = defaultMaxTTL
+	}
+	return minTTL, maxTTL
+}
+
+// Entries returns the answers currently cached with their remaining TTL.
+func (c CachingDNSHandler) Entries() []CacheEntry {
+	return c.store.list()
+}
+
+// Flush drops every cached answer and returns how many were dropped.
+func (c CachingDNSHandler) Flush() int {
+	return c.store.flushAll()
+}
+
+// FlushName drops the cached answers for a name.
+func (c CachingDNSHandler) FlushName(name string) int {
+	name = strings.ToLower(dns.Fqdn(name))
+	return c.store.flushMatching(func(cached string) bool {
+		return cached == name
+	})
+}
+
+// FlushDomain drops the cached answers for a domain and all its subdomains.
+func (c CachingDNSHandler) FlushDomain(domain string) int {
+	domain = strings.ToLower(dns.Fqdn(domain))
+	return c.store.flushMatching(func(cached string) bool {
+		return dns.IsSubDomain(domain, cached)
+	})
 }
 
 func (c CachingDNSHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 	internal.LogReceivedRequest(c.logger, c, c.logTag, r)
+
+	indicator := &requestContext{}
+	indicator.fromCache.Store(true)
+
 	var dnsMsg *dns.Msg
 	truncatingWriter := internal.WrapWriterWithIntercept(w, func(resp *dns.Msg) {
+		if indicator.stale.Load() {
+			setTTL(resp, c.staleTTL)
+		}
 		dnsMsg = resp
 		c.truncater.TruncateIfNeeded(w, r, resp)
 	})
@@ -51,35 +228,102 @@ func (c CachingDNSHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
 		return
 	}
 
-	indicator := &requestContext{
-		fromCache: true,
+	before := c.clock.Now()
+
+	if answer := c.store.cached(r); answer != nil {
+		if err := truncatingWriter.WriteMsg(answer); err != nil {
+			c.logger.Error(c.logTag, "error writing response: %s", err.Error())
+		}
+		internal.LogRequest(c.logger, c, c.logTag, c.clock.Now().Sub(before).Nanoseconds(), r, dnsMsg, "")
+		return
+	}
+
+	if c.store.isFlushed(r) {
+		// the cache plugin still holds outdated answers for the name, so the
+		// store caches its answers until they have expired
+		c.next.ServeDNS(internal.WrapWriterWithIntercept(truncatingWriter, func(resp *dns.Msg) {
+			c.store.record(r, resp)
+		}), r)
+		return
 	}
+
 	requestContext := context.WithValue(context.Background(), "indicator", indicator)
 
-	before := c.clock.Now()
-	_, err := c.ca.ServeDNS(requestContext, truncatingWriter, r)
+	_, err := c.store.current().ServeDNS(requestContext, truncatingWriter, r)
 	duration := c.clock.Now().Sub(before).Nanoseconds()
 
 	if err != nil {
 		c.logger.Error(c.logTag, "Error getting dns cache:", err.Error())
 	}
-	if indicator.fromCache {
+	if indicator.stale.Load() {
+		internal.LogRequest(c.logger, c, c.logTag, duration, r, dnsMsg, "stale=true")
+	} else if indicator.fromCache.Load() {
 		internal.LogRequest(c.logger, c, c.logTag, duration, r, dnsMsg, "")
 	}
 }
 
+// setTTL sets the TTL of every record of an answer.
+func setTTL(answer *dns.Msg, ttl uint32) {
+	for _, section := range [][]dns.RR{answer.Answer, answer.Ns, answer.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				rr.Header().Ttl = ttl
+			}
+		}
+	}
+}
+
 type corednsHandlerWrapper struct {
-	Next dns.Handler
+	Next  dns.Handler
+	store *cacheStore
 }
 
 func (w corednsHandlerWrapper) ServeDNS(ctx context.Context, writer dns.ResponseWriter, m *dns.Msg) (int, error) {
 	requestContext := ctx.Value("indicator").(*requestContext)
-	requestContext.fromCache = false
+	if requestContext.lookupOnly {
+		return dns.RcodeSuccess, nil
+	}
+	requestContext.fromCache.Store(false)
 
-	w.Next.ServeDNS(writer, m)
+	w.Next.ServeDNS(&cachingResponseWriter{
+		ResponseWriter: writer,
+		request:        m,
+		store:          w.store,
+		indicator:      requestContext,
+	}, m)
 	return 0, nil
 }
 
+// cachingResponseWriter records the answers of the handler being cached. The
+// cache plugin refreshes expired answers which it may still serve through a
+// writer of its own, and any other answer through its ResponseWriter; when
+// refreshing fails, it answers with the expired answer instead.
+type cachingResponseWriter struct {
+	dns.ResponseWriter
+	request   *dns.Msg
+	store     *cacheStore
+	indicator *requestContext
+}
+
+func (w *cachingResponseWriter) WriteMsg(m *dns.Msg) error {
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError && w.ServeStale() {
+		// the plugin discards the failure, which must not be cached either
+		return w.ResponseWriter.WriteMsg(m)
+	}
+
+	w.store.record(w.request, m)
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+func (w *cachingResponseWriter) ServeStale() bool {
+	if _, refreshing := w.ResponseWriter.(*cache.ResponseWriter); refreshing {
+		return false
+	}
+
+	w.indicator.stale.Store(true)
+	return true
+}
+
 func (w corednsHandlerWrapper) Name() string {
 	return "CorednsHandlerWrapper"
 }
@@ -2,6 +2,7 @@ package handlers_test
 
 import (
 	"net"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
@@ -10,6 +11,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/internal/testhelpers/question_case_helpers"
 	"bosh-dns/dns/server/handlers"
 	"bosh-dns/dns/server/handlers/handlersfakes"
@@ -26,6 +28,7 @@ var _ bool = Describe("CacheHandler", func() {
 		fakeClock      *fakeclock.FakeClock
 		fakeLogger     *loggerfakes.FakeLogger
 		response       *dns.Msg
+		cacheConfig    config.Cache
 	)
 
 	BeforeEach(func() {
@@ -34,7 +37,12 @@ var _ bool = Describe("CacheHandler", func() {
 		fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
 		fakeClock = fakeclock.NewFakeClock(time.Now())
 		fakeLogger = &loggerfakes.FakeLogger{}
-		cacheHandler = handlers.NewCachingDNSHandler(fakeDnsHandler, fakeTruncater, fakeClock, fakeLogger)
+		cacheConfig = config.Cache{
+			Enabled:  true,
+			NXDomain: config.CacheRcodePolicy{Enabled: true},
+			NoData:   config.CacheRcodePolicy{Enabled: true},
+			ServFail: config.CacheRcodePolicy{Enabled: true, TTL: config.DurationJSON(config.DefaultServFailCacheTTL)},
+		}
 
 		response = &dns.Msg{
 			Answer: []dns.RR{&dns.A{A: net.ParseIP("99.99.99.99")}},
@@ -46,6 +54,571 @@ var _ bool = Describe("CacheHandler", func() {
 		}
 	})
 
+	JustBeforeEach(func() {
+		var err error
+		cacheHandler, err = handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("NewCachingDNSHandler", func() {
+		It("rejects a min_ttl above the max_ttl", func() {
+			cacheConfig.MinTTL = config.DurationJSON(time.Hour)
+			cacheConfig.MaxTTL = config.DurationJSON(time.Minute)
+
+			_, err := handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+			Expect(err).To(MatchError("cache min_ttl must not be greater than max_ttl"))
+		})
+
+		It("rejects invalid prefetch settings", func() {
+			cacheConfig.Prefetch = config.CachePrefetchConfig{Amount: 3, Percentage: 95}
+
+			_, err := handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+			Expect(err).To(MatchError(ContainSubstring("percentage should fall in range [10, 90]: 95")))
+		})
+
+		It("rejects caching SERVFAIL answers for more than 5 minutes", func() {
+			cacheConfig.ServFail.TTL = config.DurationJSON(6 * time.Minute)
+
+			_, err := handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+			Expect(err).To(MatchError("cache servfail ttl must not be greater than 5m0s"))
+		})
+
+		It("rejects negative ttls for negative answers", func() {
+			cacheConfig.NoData.TTL = config.DurationJSON(-time.Second)
+
+			_, err := handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+			Expect(err).To(MatchError("cache nxdomain, nodata and servfail ttls must not be negative"))
+		})
+
+		It("accepts capacities and prefetch settings", func() {
+			cacheConfig.SuccessCapacity = 100
+			cacheConfig.DenialCapacity = 50
+			cacheConfig.Prefetch = config.CachePrefetchConfig{Amount: 3, Duration: config.DurationJSON(30 * time.Second), Percentage: 20}
+
+			_, err := handlers.NewCachingDNSHandler(fakeDnsHandler, cacheConfig, fakeTruncater, fakeClock, fakeLogger)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("TTL bounds", func() {
+		cachedTTL := func() uint32 {
+			for i := 0; i < 2; i++ {
+				m := &dns.Msg{}
+				m.SetQuestion("example.com.", dns.TypeA)
+				cacheHandler.ServeDNS(fakeWriter, m)
+			}
+
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			return fakeWriter.WriteMsgArgsForCall(1).Answer[0].Header().Ttl
+		}
+
+		withTTL := func(ttl uint32) {
+			response.Answer = []dns.RR{&dns.A{
+				Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   net.ParseIP("99.99.99.99"),
+			}}
+		}
+
+		Context("with a max_ttl", func() {
+			BeforeEach(func() {
+				cacheConfig.MaxTTL = config.DurationJSON(time.Minute)
+				withTTL(3600)
+			})
+
+			It("caches answers no longer than the max_ttl", func() {
+				Expect(cachedTTL()).To(BeNumerically("<=", 60))
+			})
+		})
+
+		Context("with a min_ttl", func() {
+			BeforeEach(func() {
+				cacheConfig.MinTTL = config.DurationJSON(time.Minute)
+				withTTL(1)
+			})
+
+			It("caches answers at least for the min_ttl", func() {
+				Expect(cachedTTL()).To(BeNumerically(">", 50))
+			})
+		})
+	})
+
+	Describe("negative answers", func() {
+		var rcode int
+
+		query := func(name string, qtype uint16) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, qtype)
+			cacheHandler.ServeDNS(fakeWriter, m)
+			return fakeWriter.WriteMsgArgsForCall(fakeWriter.WriteMsgCallCount() - 1)
+		}
+
+		BeforeEach(func() {
+			rcode = dns.RcodeNameError
+			fakeDnsHandler.ServeDNSStub = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+				m := &dns.Msg{}
+				m.SetRcode(r, rcode)
+				if r.Question[0].Qtype == dns.TypeAAAA {
+					m.SetRcode(r, dns.RcodeSuccess)
+				}
+				if r.Question[0].Qtype != dns.TypeTXT {
+					m.Ns = []dns.RR{&dns.SOA{
+						Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 60},
+						Ns:     "ns.example.com.",
+						Mbox:   "hostmaster.example.com.",
+						Minttl: 60,
+					}}
+				}
+				Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+			}
+		})
+
+		It("caches NXDOMAIN answers for the TTL of their SOA record", func() {
+			query("missing.example.com.", dns.TypeA)
+			answer := query("missing.example.com.", dns.TypeA)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			Expect(answer.Rcode).To(Equal(dns.RcodeNameError))
+			Expect(answer.Ns).To(HaveLen(1))
+
+			fakeClock.Increment(20 * time.Second)
+			answer = query("missing.example.com.", dns.TypeA)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			Expect(answer.Ns[0].Header().Ttl).To(Equal(uint32(40)))
+
+			fakeClock.Increment(40 * time.Second)
+			query("missing.example.com.", dns.TypeA)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+		})
+
+		It("caches NXDOMAIN answers without a SOA record for the min TTL", func() {
+			query("missing.example.com.", dns.TypeTXT)
+			query("missing.example.com.", dns.TypeTXT)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+
+			fakeClock.Increment(5 * time.Second)
+			query("missing.example.com.", dns.TypeTXT)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+		})
+
+		It("caches NODATA answers", func() {
+			query("example.com.", dns.TypeAAAA)
+			answer := query("example.com.", dns.TypeAAAA)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			Expect(answer.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(answer.Answer).To(BeEmpty())
+		})
+
+		It("caches SERVFAIL answers for 5 seconds", func() {
+			rcode = dns.RcodeServerFailure
+
+			query("broken.example.com.", dns.TypeTXT)
+			answer := query("broken.example.com.", dns.TypeTXT)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			Expect(answer.Rcode).To(Equal(dns.RcodeServerFailure))
+
+			fakeClock.Increment(5 * time.Second)
+			query("broken.example.com.", dns.TypeTXT)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+		})
+
+		It("caches negative answers again right after they were flushed", func() {
+			query("missing.example.com.", dns.TypeA)
+			Expect(cacheHandler.FlushName("missing.example.com.")).To(Equal(1))
+
+			query("missing.example.com.", dns.TypeA)
+			query("missing.example.com.", dns.TypeA)
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+		})
+
+		It("answers while expired answers are being replaced", func() {
+			rcode = dns.RcodeServerFailure
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					for j := 0; j < 500; j++ {
+						m := &dns.Msg{}
+						m.SetQuestion("broken.example.com.", dns.TypeTXT)
+						cacheHandler.ServeDNS(&internalfakes.FakeResponseWriter{}, m)
+					}
+				}()
+			}
+
+			for i := 0; i < 100; i++ {
+				fakeClock.Increment(5 * time.Second)
+				time.Sleep(time.Millisecond)
+			}
+			wg.Wait()
+
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(BeNumerically(">", 1))
+		})
+
+		It("truncates cached negative answers if needed", func() {
+			query("missing.example.com.", dns.TypeA)
+			query("missing.example.com.", dns.TypeA)
+
+			Expect(fakeTruncater.TruncateIfNeededCallCount()).To(Equal(2))
+		})
+
+		Context("when a ttl is configured", func() {
+			BeforeEach(func() {
+				cacheConfig.NXDomain.TTL = config.DurationJSON(10 * time.Minute)
+				cacheConfig.ServFail.TTL = config.DurationJSON(time.Second)
+			})
+
+			It("overrides the TTL of NXDOMAIN answers", func() {
+				query("missing.example.com.", dns.TypeA)
+
+				fakeClock.Increment(5 * time.Minute)
+				answer := query("missing.example.com.", dns.TypeA)
+				Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+				Expect(answer.Ns[0].Header().Ttl).To(Equal(uint32(300)))
+			})
+
+			It("caches SERVFAIL answers for the ttl", func() {
+				rcode = dns.RcodeServerFailure
+
+				query("broken.example.com.", dns.TypeTXT)
+				fakeClock.Increment(time.Second)
+				query("broken.example.com.", dns.TypeTXT)
+				Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when caching is disabled for an outcome", func() {
+			BeforeEach(func() {
+				cacheConfig.NXDomain.Enabled = false
+				cacheConfig.ServFail.Enabled = false
+			})
+
+			It("does not cache NXDOMAIN answers", func() {
+				query("missing.example.com.", dns.TypeA)
+				query("missing.example.com.", dns.TypeA)
+				Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+			})
+
+			It("does not cache SERVFAIL answers", func() {
+				rcode = dns.RcodeServerFailure
+
+				query("broken.example.com.", dns.TypeTXT)
+				query("broken.example.com.", dns.TypeTXT)
+				Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(2))
+			})
+
+			It("still caches the other outcomes", func() {
+				query("example.com.", dns.TypeAAAA)
+				query("example.com.", dns.TypeAAAA)
+				Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("inspecting and flushing", func() {
+		query := func(name string) {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeA)
+			cacheHandler.ServeDNS(fakeWriter, m)
+		}
+
+		names := func(entries []handlers.CacheEntry) []string {
+			result := []string{}
+			for _, entry := range entries {
+				result = append(result, entry.Name)
+			}
+			return result
+		}
+
+		BeforeEach(func() {
+			response.Answer = []dns.RR{&dns.A{
+				Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("99.99.99.99"),
+			}}
+		})
+
+		JustBeforeEach(func() {
+			query("example.com.")
+			query("www.example.com.")
+			query("example.org.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(3))
+		})
+
+		It("lists the cached answers with their remaining TTL", func() {
+			fakeClock.Increment(20 * time.Second)
+
+			entries := cacheHandler.Entries()
+			Expect(names(entries)).To(ConsistOf("example.com.", "www.example.com.", "example.org."))
+
+			for _, entry := range entries {
+				Expect(entry.Type).To(Equal(dns.TypeA))
+				Expect(entry.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(entry.TTL).To(Equal(40 * time.Second))
+				Expect(entry.Records).To(HaveLen(1))
+				Expect(entry.Records[0].(*dns.A).A.String()).To(Equal("99.99.99.99"))
+			}
+
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(3))
+		})
+
+		It("does not list expired answers", func() {
+			fakeClock.Increment(60 * time.Second)
+
+			Expect(cacheHandler.Entries()).To(BeEmpty())
+		})
+
+		Context("when the cache is full", func() {
+			BeforeEach(func() {
+				cacheConfig.SuccessCapacity = 1
+				cacheConfig.DenialCapacity = 1
+			})
+
+			It("drops the answers recorded longest ago", func() {
+				Expect(names(cacheHandler.Entries())).To(ConsistOf("www.example.com.", "example.org."))
+
+				query("example.net.")
+				Expect(names(cacheHandler.Entries())).To(ConsistOf("example.org.", "example.net."))
+			})
+		})
+
+		It("lists negative answers", func() {
+			fakeDnsHandler.ServeDNSStub = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+				m := &dns.Msg{}
+				m.SetRcode(r, dns.RcodeNameError)
+				m.Ns = []dns.RR{&dns.SOA{
+					Hdr:    dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 300},
+					Ns:     "ns.example.com.",
+					Mbox:   "hostmaster.example.com.",
+					Minttl: 60,
+				}}
+				Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+			}
+			query("missing.example.com.")
+
+			Expect(cacheHandler.Entries()).To(ContainElement(And(
+				HaveField("Name", "missing.example.com."),
+				HaveField("Rcode", dns.RcodeNameError),
+			)))
+		})
+
+		It("flushes everything", func() {
+			Expect(cacheHandler.Flush()).To(Equal(3))
+			Expect(cacheHandler.Entries()).To(BeEmpty())
+
+			query("example.com.")
+			query("example.org.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(5))
+
+			query("example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(5))
+		})
+
+		It("flushes a single name", func() {
+			Expect(cacheHandler.FlushName("EXAMPLE.com")).To(Equal(1))
+			Expect(names(cacheHandler.Entries())).To(ConsistOf("www.example.com.", "example.org."))
+
+			query("example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(4))
+
+			query("www.example.com.")
+			query("example.org.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(4))
+		})
+
+		It("caches the answers for a flushed name again right away", func() {
+			Expect(cacheHandler.FlushName("example.com.")).To(Equal(1))
+
+			query("example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(4))
+
+			fakeClock.Increment(10 * time.Second)
+			query("example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(4))
+
+			message := fakeWriter.WriteMsgArgsForCall(fakeWriter.WriteMsgCallCount() - 1)
+			Expect(message.Answer).To(HaveLen(1))
+			Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(50)))
+			Expect(names(cacheHandler.Entries())).To(ConsistOf("example.com.", "www.example.com.", "example.org."))
+
+			fakeClock.Increment(50 * time.Second)
+			query("example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(5))
+		})
+
+		It("flushes a domain with its subdomains", func() {
+			Expect(cacheHandler.FlushDomain("example.com.")).To(Equal(2))
+			Expect(names(cacheHandler.Entries())).To(ConsistOf("example.org."))
+
+			query("example.com.")
+			query("www.example.com.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(5))
+
+			query("example.org.")
+			Expect(fakeDnsHandler.ServeDNSCallCount()).To(Equal(5))
+		})
+
+		It("does not flush anything for unknown names", func() {
+			Expect(cacheHandler.FlushName("unknown.com.")).To(Equal(0))
+			Expect(cacheHandler.FlushDomain("com.example.")).To(Equal(0))
+			Expect(cacheHandler.Entries()).To(HaveLen(3))
+		})
+	})
+
+	Describe("serving stale answers", func() {
+		var refresh func(dns.ResponseWriter, *dns.Msg)
+
+		query := func() *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion("example.com.", dns.TypeA)
+			cacheHandler.ServeDNS(fakeWriter, m)
+			return fakeWriter.WriteMsgArgsForCall(fakeWriter.WriteMsgCallCount() - 1)
+		}
+
+		BeforeEach(func() {
+			cacheConfig.MinTTL = config.DurationJSON(time.Second)
+			cacheConfig.MaxTTL = config.DurationJSON(time.Second)
+			cacheConfig.ServeStale = config.ServeStaleConfig{
+				Enabled:  true,
+				MaxStale: config.DurationJSON(time.Hour),
+				TTL:      config.DurationJSON(30 * time.Second),
+			}
+
+			// like the ForwardHandler once every recursor failed
+			refresh = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+				if staleWriter, ok := cacheWriter.(handlers.StaleAnswerWriter); ok && staleWriter.ServeStale() {
+					return
+				}
+
+				m := &dns.Msg{}
+				m.SetRcode(r, dns.RcodeServerFailure)
+				Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+			}
+
+			fakeDnsHandler.ServeDNSStub = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+				if fakeDnsHandler.ServeDNSCallCount() > 1 {
+					refresh(cacheWriter, r)
+					return
+				}
+
+				m := &dns.Msg{}
+				m.SetReply(r)
+				m.Answer = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 1},
+					A:   net.ParseIP("99.99.99.99"),
+				}}
+				Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+			}
+		})
+
+		JustBeforeEach(func() {
+			Expect(query().Answer).To(HaveLen(1))
+		})
+
+		It("answers with the expired answer and the stale ttl when it cannot be refreshed", func() {
+			Eventually(func() int {
+				query()
+				return fakeDnsHandler.ServeDNSCallCount()
+			}, 5*time.Second, 100*time.Millisecond).Should(Equal(2))
+
+			message := fakeWriter.WriteMsgArgsForCall(fakeWriter.WriteMsgCallCount() - 1)
+			Expect(message.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(message.Answer).To(HaveLen(1))
+			Expect(message.Answer[0].(*dns.A).A.String()).To(Equal("99.99.99.99"))
+			Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+		})
+
+		It("lists expired answers as stale", func() {
+			fakeClock.Increment(2 * time.Second)
+
+			entries := cacheHandler.Entries()
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name).To(Equal("example.com."))
+			Expect(entries[0].Stale).To(BeTrue())
+			Expect(entries[0].TTL).To(BeZero())
+			Expect(entries[0].Records).To(HaveLen(1))
+		})
+
+		Context("when refreshing the answer fails with SERVFAIL", func() {
+			BeforeEach(func() {
+				refresh = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+					m := &dns.Msg{}
+					m.SetRcode(r, dns.RcodeServerFailure)
+					Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+				}
+			})
+
+			It("keeps answering with the expired answer", func() {
+				Eventually(func() int {
+					query()
+					return fakeDnsHandler.ServeDNSCallCount()
+				}, 5*time.Second, 100*time.Millisecond).Should(Equal(2))
+
+				for i := 0; i < 2; i++ {
+					message := query()
+					Expect(message.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(message.Answer).To(HaveLen(1))
+					Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+				}
+			})
+		})
+
+		Context("when the answer expired longer than max_stale ago", func() {
+			BeforeEach(func() {
+				cacheConfig.ServeStale.MaxStale = config.DurationJSON(time.Second)
+			})
+
+			It("answers with the failure", func() {
+				Eventually(func() int {
+					return query().Rcode
+				}, 5*time.Second, 100*time.Millisecond).Should(Equal(dns.RcodeServerFailure))
+			})
+		})
+
+		It("does not answer with expired answers of flushed names", func() {
+			Expect(cacheHandler.FlushName("example.com.")).To(Equal(1))
+
+			message := query()
+			Expect(message.Rcode).To(Equal(dns.RcodeServerFailure))
+		})
+	})
+
+	Describe("prefetching", func() {
+		var refreshed chan struct{}
+
+		BeforeEach(func() {
+			cacheConfig.MinTTL = config.DurationJSON(time.Second)
+			cacheConfig.MaxTTL = config.DurationJSON(time.Second)
+			cacheConfig.Prefetch = config.CachePrefetchConfig{Amount: 1, Percentage: 10}
+
+			refreshed = make(chan struct{})
+			fakeDnsHandler.ServeDNSStub = func(cacheWriter dns.ResponseWriter, r *dns.Msg) {
+				m := &dns.Msg{}
+				m.SetReply(r)
+				m.Answer = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 1},
+					A:   net.ParseIP("99.99.99.99"),
+				}}
+				Expect(cacheWriter.WriteMsg(m)).To(Succeed())
+
+				if fakeDnsHandler.ServeDNSCallCount() == 2 {
+					close(refreshed)
+				}
+			}
+		})
+
+		It("refreshes answers in the background while answering from the cache", func() {
+			for i := 0; i < 2; i++ {
+				m := &dns.Msg{}
+				m.SetQuestion("example.com.", dns.TypeA)
+				cacheHandler.ServeDNS(fakeWriter, m)
+			}
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(2))
+			Eventually(refreshed).Should(BeClosed())
+		})
+	})
+
 	Describe("ServeDNS", func() {
 		Context("when the request doesn't have recursion desired bit set", func() {
 			It("forwards the question up to a recursor", func() {
@@ -96,7 +669,7 @@ var _ bool = Describe("CacheHandler", func() {
 			})
 
 			Context("when an answer is cached", func() {
-				BeforeEach(func() {
+				JustBeforeEach(func() {
 					m := &dns.Msg{}
 					SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeANY)
 					cacheHandler.ServeDNS(fakeWriter, m) // should cache response
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/response"
+	"github.com/miekg/dns"
+
+	"bosh-dns/dns/config"
+)
+
+// CacheEntry is an answer held by a CachingDNSHandler. Stale answers have
+// expired, and are only served when they cannot be refreshed.
+type CacheEntry struct {
+	Name    string
+	Type    uint16
+	Rcode   int
+	TTL     time.Duration
+	Stale   bool
+	Records []dns.RR
+}
+
+type cacheEntryKey struct {
+	name  string
+	qtype uint16
+	do    bool
+}
+
+type cacheStoreEntry struct {
+	key     cacheEntryKey
+	rcode   int
+	expires time.Time
+
+	// answer is kept for answers which are cached by the store itself
+	// rather than by the cache plugin
+	answer *dns.Msg
+}
+
+// cacheStore owns the CoreDNS cache plugin and keeps track of which answers it
+// holds and until when, since the plugin can neither list them nor remove
+// single answers. Their records are looked up in the plugin when they are
+// listed.
+//
+// NXDOMAIN, NODATA and SERVFAIL answers are cached by the store according to
+// their policy, since the plugin does not tell NXDOMAIN and NODATA apart and
+// only caches NXDOMAIN answers which carry a SOA record. Names flushed from
+// the store are answered without the plugin until the answers it still holds
+// for them have expired, and their answers are cached by the store as well.
+type cacheStore struct {
+	newCache func() *cache.Cache
+	capacity int
+
+	successMinTTL, successMaxTTL time.Duration
+	denialMinTTL, denialMaxTTL   time.Duration
+
+	nxdomain, nodata, servfail config.CacheRcodePolicy
+
+	// maxStale is how long after expiring the plugin may still serve an
+	// answer when refreshing it fails
+	maxStale time.Duration
+
+	clock clock.Clock
+
+	mutex   sync.RWMutex
+	ca      *cache.Cache
+	entries map[cacheEntryKey]*list.Element
+	flushed map[string]time.Time
+
+	// recorded orders the entries from the most to the least recently
+	// recorded one, which is evicted first when the store is full
+	recorded *list.List
+}
+
+func (s *cacheStore) current() *cache.Cache {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.ca
+}
+
+// record caches negative answers according to their policy, and mirrors the
+// decision of the cache plugin whether and for how long to cache any other
+// answer.
+func (s *cacheStore) record(req, answer *dns.Msg) {
+	if len(req.Question) == 0 || answer.Truncated {
+		return
+	}
+
+	now := s.clock.Now()
+	mt, _ := response.Typify(answer, now.UTC())
+
+	var (
+		ttl      time.Duration
+		negative bool
+	)
+	switch {
+	case answer.Rcode == dns.RcodeNameError:
+		ttl = boundTTL(dnsutil.MinimalTTL(answer, response.NameError), s.denialMinTTL, s.denialMaxTTL)
+		ttl, negative = negativeTTL(s.nxdomain, ttl), true
+	case answer.Rcode == dns.RcodeServerFailure:
+		ttl, negative = negativeTTL(s.servfail, config.DefaultServFailCacheTTL), true
+	case mt == response.NoData:
+		ttl = boundTTL(dnsutil.MinimalTTL(answer, mt), s.denialMinTTL, s.denialMaxTTL)
+		ttl, negative = negativeTTL(s.nodata, ttl), true
+	case mt == response.NoError, mt == response.Delegation:
+		ttl = boundTTL(dnsutil.MinimalTTL(answer, mt), s.successMinTTL, s.successMaxTTL)
+	default:
+		return
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	entry := &cacheStoreEntry{
+		key:     newCacheEntryKey(req),
+		rcode:   answer.Rcode,
+		expires: now.Add(ttl),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if negative || s.flushedUntil(entry.key.name, now) {
+		entry.answer = answer.Copy()
+	}
+
+	if element, found := s.entries[entry.key]; found {
+		element.Value = entry
+		s.recorded.MoveToFront(element)
+		return
+	}
+
+	s.entries[entry.key] = s.recorded.PushFront(entry)
+
+	if s.recorded.Len() > s.capacity {
+		oldest := s.recorded.Back()
+		s.recorded.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheStoreEntry).key)
+	}
+}
+
+// cached returns an answer to the request cached by the store itself, with
+// the TTL of its records set to the time it remains cached.
+func (s *cacheStore) cached(req *dns.Msg) *dns.Msg {
+	if len(req.Question) == 0 {
+		return nil
+	}
+
+	now := s.clock.Now()
+
+	var (
+		answer  *dns.Msg
+		expires time.Time
+	)
+
+	s.mutex.RLock()
+	if element, found := s.entries[newCacheEntryKey(req)]; found {
+		entry := element.Value.(*cacheStoreEntry)
+		answer, expires = entry.answer, entry.expires
+	}
+	s.mutex.RUnlock()
+
+	if answer == nil || !now.Before(expires) {
+		return nil
+	}
+
+	reply := answer.Copy()
+	reply.Id = req.Id
+	reply.Question = req.Question
+
+	ttl := uint32(expires.Sub(now).Seconds())
+	for _, section := range [][]dns.RR{reply.Answer, reply.Ns, reply.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				rr.Header().Ttl = ttl
+			}
+		}
+	}
+
+	return reply
+}
+
+// isFlushed tells whether the cache plugin may still hold answers for a name
+// which has been flushed.
+func (s *cacheStore) isFlushed(r *dns.Msg) bool {
+	if len(r.Question) == 0 {
+		return false
+	}
+	name := strings.ToLower(r.Question[0].Name)
+
+	s.mutex.RLock()
+	until, found := s.flushed[name]
+	s.mutex.RUnlock()
+
+	if !found {
+		return false
+	}
+
+	if s.clock.Now().Before(until) {
+		return true
+	}
+
+	s.mutex.Lock()
+	s.flushedUntil(name, s.clock.Now())
+	s.mutex.Unlock()
+
+	return false
+}
+
+// flushedUntil tells whether a name is flushed at a given time, and forgets
+// it once the answers of the cache plugin have expired. It must be called
+// holding the write lock.
+func (s *cacheStore) flushedUntil(name string, now time.Time) bool {
+	until, found := s.flushed[name]
+	if !found {
+		return false
+	}
+
+	if !now.Before(until) {
+		delete(s.flushed, name)
+		return false
+	}
+
+	return true
+}
+
+// list returns the entries which may still be served, including stale
+// ones. The records of positive answers are looked up in the cache plugin,
+// which may have evicted them already.
+func (s *cacheStore) list() []CacheEntry {
+	now := s.clock.Now()
+
+	s.mutex.RLock()
+	ca := s.ca
+	live := []*cacheStoreEntry{}
+	for element := s.recorded.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*cacheStoreEntry)
+		if now.Before(entry.expires) || (entry.answer == nil && now.Before(entry.expires.Add(s.maxStale))) {
+			live = append(live, entry)
+		}
+	}
+	s.mutex.RUnlock()
+
+	entries := []CacheEntry{}
+	for _, entry := range live {
+		answer := entry.answer
+		if answer == nil {
+			answer = lookup(ca, entry.key)
+			if answer == nil {
+				continue
+			}
+		}
+
+		records := []dns.RR{}
+		for _, rr := range answer.Answer {
+			records = append(records, dns.Copy(rr))
+		}
+
+		cacheEntry := CacheEntry{
+			Name:    entry.key.name,
+			Type:    entry.key.qtype,
+			Rcode:   entry.rcode,
+			TTL:     entry.expires.Sub(now),
+			Records: records,
+		}
+		if !now.Before(entry.expires) {
+			cacheEntry.TTL, cacheEntry.Stale = 0, true
+		}
+
+		entries = append(entries, cacheEntry)
+	}
+
+	return entries
+}
+
+// lookup returns the answer the cache plugin holds for a key, without
+// forwarding the request on a miss.
+func lookup(ca *cache.Cache, key cacheEntryKey) *dns.Msg {
+	req := &dns.Msg{}
+	req.SetQuestion(key.name, key.qtype)
+	if key.do {
+		req.SetEdns0(dns.DefaultMsgSize, true)
+	}
+
+	writer := &lookupResponseWriter{}
+	ctx := context.WithValue(context.Background(), "indicator", &requestContext{lookupOnly: true})
+	if _, err := ca.ServeDNS(ctx, writer, req); err != nil {
+		return nil
+	}
+
+	return writer.answer
+}
+
+func (s *cacheStore) flushAll() int {
+	ca := s.newCache()
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	flushed := 0
+	for element := s.recorded.Front(); element != nil; element = element.Next() {
+		if now.Before(element.Value.(*cacheStoreEntry).expires.Add(s.maxStale)) {
+			flushed++
+		}
+	}
+
+	s.ca = ca
+	s.entries = map[cacheEntryKey]*list.Element{}
+	s.recorded = list.New()
+	s.flushed = map[string]time.Time{}
+
+	return flushed
+}
+
+func (s *cacheStore) flushMatching(matches func(name string) bool) int {
+	now := s.clock.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	flushed := 0
+	for key, element := range s.entries {
+		if !matches(key.name) {
+			continue
+		}
+		delete(s.entries, key)
+		s.recorded.Remove(element)
+
+		entry := element.Value.(*cacheStoreEntry)
+		if !now.Before(entry.expires.Add(s.maxStale)) {
+			continue
+		}
+		flushed++
+
+		if entry.answer != nil {
+			continue
+		}
+
+		// the cache plugin keeps serving answers during the second their
+		// TTL drops to zero, and serves them stale for a while afterwards
+		until := entry.expires.Add(s.maxStale + time.Second)
+		if until.After(s.flushed[key.name]) {
+			s.flushed[key.name] = until
+		}
+	}
+
+	return flushed
+}
+
+func newCacheEntryKey(req *dns.Msg) cacheEntryKey {
+	key := cacheEntryKey{
+		name:  strings.ToLower(req.Question[0].Name),
+		qtype: req.Question[0].Qtype,
+	}
+	if opt := req.IsEdns0(); opt != nil {
+		key.do = opt.Do()
+	}
+	return key
+}
+
+// negativeTTL returns how long to cache a negative answer according to its
+// policy, or zero when it must not be cached.
+func negativeTTL(policy config.CacheRcodePolicy, ttl time.Duration) time.Duration {
+	if !policy.Enabled {
+		return 0
+	}
+	if policy.TTL > 0 {
+		return time.Duration(policy.TTL)
+	}
+	return ttl
+}
+
+func boundTTL(ttl, minTTL, maxTTL time.Duration) time.Duration {
+	if ttl < minTTL {
+		ttl = minTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	return ttl
+}
+
+// lookupResponseWriter captures the answer of the cache plugin to a lookup.
+type lookupResponseWriter struct {
+	answer *dns.Msg
+}
+
+func (w *lookupResponseWriter) WriteMsg(m *dns.Msg) error {
+	w.answer = m
+	return nil
+}
+
+func (w *lookupResponseWriter) Write(b []byte) (int, error) {
+	return -1, errors.New("not implemented, use WriteMsg")
+}
+
+func (w *lookupResponseWriter) LocalAddr() net.Addr   { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (w *lookupResponseWriter) RemoteAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (w *lookupResponseWriter) Close() error          { return nil }
+func (w *lookupResponseWriter) TsigStatus() error     { return nil }
+func (w *lookupResponseWriter) TsigTimersOnly(b bool) {}
+func (w *lookupResponseWriter) Hijack()               {}
@@ -27,7 +27,7 @@ func (d DiscoveryHandler) ServeDNS(responseWriter dns.ResponseWriter, requestMsg
 	if len(requestMsg.Question) > 0 {
 		hostResponse := d.localDomain.Resolve(responseWriter, requestMsg)
 		switch requestMsg.Question[0].Qtype {
-		case dns.TypeA, dns.TypeANY, dns.TypeAAAA:
+		case dns.TypeA, dns.TypeANY, dns.TypeAAAA, dns.TypeSRV, dns.TypeTXT, dns.TypeSOA, dns.TypeNS:
 			responseMsg = hostResponse
 		default:
 			if hostResponse.Rcode == dns.RcodeNameError {
@@ -35,6 +35,7 @@ func (d DiscoveryHandler) ServeDNS(responseWriter dns.ResponseWriter, requestMsg
 			} else {
 				responseMsg.SetRcode(requestMsg, dns.RcodeSuccess)
 			}
+			responseMsg.Ns = hostResponse.Ns
 		}
 	}
 
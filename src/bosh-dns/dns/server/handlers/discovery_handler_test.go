@@ -10,9 +10,11 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/internal/testhelpers/question_case_helpers"
 	"bosh-dns/dns/server/handlers"
 	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/record"
 	"bosh-dns/dns/server/records"
 	"bosh-dns/dns/server/records/dnsresolver"
 	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
@@ -25,6 +27,7 @@ var _ = Describe("DiscoveryHandler", func() {
 			fakeWriter       *internalfakes.FakeResponseWriter
 			fakeLogger       *loggerfakes.FakeLogger
 			fakeRecordSet    *dnsresolverfakes.FakeRecordSet
+			fakeHealth       *dnsresolverfakes.FakeHealthStateGetter
 			fakeTruncater    *dnsresolverfakes.FakeResponseTruncater
 		)
 
@@ -32,10 +35,11 @@ var _ = Describe("DiscoveryHandler", func() {
 			fakeWriter = &internalfakes.FakeResponseWriter{}
 			fakeLogger = &loggerfakes.FakeLogger{}
 			fakeRecordSet = &dnsresolverfakes.FakeRecordSet{}
+			fakeHealth = &dnsresolverfakes.FakeHealthStateGetter{}
 
 			fakeWriter.RemoteAddrReturns(&net.UDPAddr{})
 			fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
-			discoveryHandler = handlers.NewDiscoveryHandler(fakeLogger, dnsresolver.NewLocalDomain(fakeLogger, fakeRecordSet, fakeTruncater))
+			discoveryHandler = handlers.NewDiscoveryHandler(fakeLogger, dnsresolver.NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{}, fakeTruncater))
 		})
 
 		Context("when there are no questions", func() {
@@ -73,7 +77,7 @@ var _ = Describe("DiscoveryHandler", func() {
 			})
 
 			It("returns success with no data for all other types if host lookup succeeds", func() {
-				fakeRecordSet.ResolveReturns([]string{"2601:0646:0102:0095:0000:0000:0000:0025", "123.123.123.123"}, nil)
+				fakeRecordSet.ResolveReturns(records.Resolution{AliasIPs: []string{"2601:0646:0102:0095:0000:0000:0000:0025", "123.123.123.123"}}, nil)
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-network.my-deployment.bosh.", dns.TypePTR)
 
@@ -86,7 +90,7 @@ var _ = Describe("DiscoveryHandler", func() {
 			})
 
 			It("returns name error for all other types if host lookup returns name error", func() {
-				fakeRecordSet.ResolveReturns(nil, records.DomainError)
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.DomainError)
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-network.my-deployment.bosh.", dns.TypePTR)
 
@@ -99,7 +103,7 @@ var _ = Describe("DiscoveryHandler", func() {
 			})
 
 			It("returns success with no data for all other types if host lookup returns criteria error", func() {
-				fakeRecordSet.ResolveReturns(nil, records.CriteriaError)
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.CriteriaError)
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-network.my-deployment.bosh.", dns.TypePTR)
 
@@ -111,12 +115,82 @@ var _ = Describe("DiscoveryHandler", func() {
 				Expect(message.Answer).To(BeEmpty())
 			})
 
+			It("returns the SOA of the zone with negative responses for all other types", func() {
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.DomainError)
+				fakeRecordSet.DomainsReturns([]string{"bosh."})
+				fakeRecordSet.VersionReturns(7)
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "my-instance.my-network.my-deployment.bosh.", dns.TypeMX)
+
+				discoveryHandler.ServeDNS(fakeWriter, m)
+				message := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(message.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(message.Ns).To(HaveLen(1))
+				Expect(message.Ns[0].(*dns.SOA).Hdr.Name).To(Equal("bosh."))
+				Expect(message.Ns[0].(*dns.SOA).Serial).To(Equal(uint32(7)))
+			})
+
+			It("returns the SOA when queried for the SOA of a zone", func() {
+				fakeRecordSet.DomainsReturns([]string{"bosh."})
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "bosh.", dns.TypeSOA)
+
+				discoveryHandler.ServeDNS(fakeWriter, m)
+				message := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(message.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(message.Answer).To(HaveLen(1))
+				Expect(message.Answer[0]).To(BeAssignableToTypeOf(&dns.SOA{}))
+			})
+
+			It("returns SRV records with glue when queried for SRV records", func() {
+				fakeRecordSet.ResolveServicesReturns([]records.ServiceRecord{
+					{
+						Record: record.Record{
+							ID:         "my-instance",
+							Group:      "my-group",
+							Network:    "my-network",
+							Deployment: "my-deployment",
+							Domain:     "bosh.",
+							IP:         "123.123.123.123",
+						},
+						Port: 443,
+					},
+				}, nil)
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "_https._tcp.q-p443s0.my-group.my-network.my-deployment.bosh.", dns.TypeSRV)
+
+				discoveryHandler.ServeDNS(fakeWriter, m)
+				message := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(message.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(message.Authoritative).To(BeTrue())
+				Expect(message.Answer).To(HaveLen(1))
+				Expect(message.Answer[0].(*dns.SRV).Target).To(Equal("my-instance.my-group.my-network.my-deployment.bosh."))
+				Expect(message.Answer[0].(*dns.SRV).Port).To(Equal(uint16(443)))
+				Expect(message.Extra).To(HaveLen(1))
+				Expect(message.Extra[0].(*dns.A).A.String()).To(Equal("123.123.123.123"))
+			})
+
+			It("returns TXT records when queried for TXT records", func() {
+				fakeRecordSet.ExpandAliasesReturns([]string{"my-instance.my-group.my-network.my-deployment.bosh."})
+				fakeRecordSet.ResolveRecordsReturns([]record.Record{{ID: "my-instance", AZ: "z1", IP: "123.123.123.123"}}, nil)
+				fakeHealth.HealthStateStringReturns("failing")
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeTXT)
+
+				discoveryHandler.ServeDNS(fakeWriter, m)
+				message := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(message.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(message.Answer).To(HaveLen(1))
+				Expect(message.Answer[0].(*dns.TXT).Txt).To(ContainElement("az=z1"))
+				Expect(message.Answer[0].(*dns.TXT).Txt).To(ContainElement("health_state=failing"))
+			})
+
 			// q: A -> only A even if AAAA
 			// q: AAAA -> only AAAA even if A
 			// q: ANY -> both A and AAAA
 
 			It("returns only A records (no AAAA records) when the queried for A records", func() {
-				fakeRecordSet.ResolveReturns([]string{"2601:0646:0102:0095:0000:0000:0000:0025", "123.123.123.123"}, nil)
+				fakeRecordSet.ResolveReturns(records.Resolution{AliasIPs: []string{"2601:0646:0102:0095:0000:0000:0000:0025", "123.123.123.123"}}, nil)
 
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
@@ -145,7 +219,7 @@ var _ = Describe("DiscoveryHandler", func() {
 			})
 
 			It("returns only AAAA records (no A records) when the queried for AAAA records", func() {
-				fakeRecordSet.ResolveReturns([]string{"2601:0646:0102:0095:0000:0000:0000:0025", "4.2.2.2"}, nil)
+				fakeRecordSet.ResolveReturns(records.Resolution{AliasIPs: []string{"2601:0646:0102:0095:0000:0000:0000:0025", "4.2.2.2"}}, nil)
 
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeAAAA)
@@ -177,7 +251,7 @@ var _ = Describe("DiscoveryHandler", func() {
 				ipv6ResolutionList := []string{"2601:0646:0102:0095:0000:0000:0000:0025"}
 				ipv4ResolutionList := []string{"4.2.2.2"}
 
-				fakeRecordSet.ResolveReturns(append(ipv6ResolutionList, ipv4ResolutionList...), nil)
+				fakeRecordSet.ResolveReturns(records.Resolution{AliasIPs: append(ipv6ResolutionList, ipv4ResolutionList...)}, nil)
 
 				m := &dns.Msg{}
 				SetQuestion(m, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeANY)
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const dnsMessageContentType = "application/dns-message"
+
+// DNSOverHTTPSExchanger sends queries to RFC 8484 recursors, addressed by
+// their full URL (e.g. "https://1.1.1.1/dns-query").
+type DNSOverHTTPSExchanger struct {
+	client *http.Client
+}
+
+func NewDNSOverHTTPSExchanger(timeout time.Duration, tlsConfig *tls.Config) *DNSOverHTTPSExchanger {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &DNSOverHTTPSExchanger{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+	}
+}
+
+func (e *DNSOverHTTPSExchanger) Exchange(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+	// RFC 8484 asks for a message ID of 0 so that responses are cacheable by
+	// HTTP intermediaries; the original ID is restored on the answer.
+	query := m.Copy()
+	query.Id = 0
+
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, recursor, bytes.NewReader(packed))
+	if err != nil {
+		return nil, 0, err
+	}
+	request.Header.Set("Content-Type", dnsMessageContentType)
+	request.Header.Set("Accept", dnsMessageContentType)
+
+	before := time.Now()
+	response, err := e.client.Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, time.Since(before), httpStatusError{recursor: recursor, status: response.Status}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, time.Since(before), err
+	}
+	rtt := time.Since(before)
+
+	answer := &dns.Msg{}
+	if err := answer.Unpack(body); err != nil {
+		return nil, rtt, err
+	}
+	answer.Id = m.Id
+
+	return answer, rtt, nil
+}
+
+// httpStatusError is a net.Error so that the recursor pool retries and fails
+// over on it the same way it does for an unreachable recursor.
+type httpStatusError struct {
+	recursor string
+	status   string
+}
+
+func (e httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status %q from %s", e.status, e.recursor)
+}
+
+func (httpStatusError) Timeout() bool   { return false }
+func (httpStatusError) Temporary() bool { return true }
@@ -1,9 +1,17 @@
 package handlers
 
 import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"code.cloudfoundry.org/tlsconfig"
 	"github.com/miekg/dns"
+
+	"bosh-dns/dns/config"
 )
 
 type ExchangerFactory func(string) Exchanger
@@ -13,3 +21,143 @@ func NewExchangerFactory(timeout time.Duration) ExchangerFactory {
 		return &dns.Client{Net: net, Timeout: timeout, UDPSize: 65535}
 	}
 }
+
+// NewRecursorExchangerFactory builds exchangers which pick the transport from
+// the recursor address: "tls://host:port" recursors are queried over DNS over
+// TLS, "https://host/path" recursors over DNS over HTTPS and any other
+// recursor over plain DNS using the network of the client request. Encrypted
+// recursors addressed by name are resolved when connecting to them and
+// verified against that name. They may name the server to verify them against
+// after a '#' instead, which takes precedence over the server name of
+// tlsConfig. TCP and TLS connections are kept open and shared by all
+// exchangers of the factory.
+func NewRecursorExchangerFactory(timeout time.Duration, tlsConfig *tls.Config) ExchangerFactory {
+	udpExchanger := &dns.Client{Net: "udp", Timeout: timeout, UDPSize: 65535}
+	tcpExchanger := NewPipeliningExchanger(&dns.Client{Net: "tcp", Timeout: timeout}, pipelineIdleTimeout)
+	encrypted := &encryptedExchangers{
+		timeout:   timeout,
+		tlsConfig: tlsConfig,
+		tls:       map[string]Exchanger{},
+		https:     map[string]Exchanger{},
+	}
+
+	exchangers := map[string]Exchanger{
+		"udp": &recursorExchanger{plain: udpExchanger, encrypted: encrypted},
+		"tcp": &recursorExchanger{plain: tcpExchanger, encrypted: encrypted},
+	}
+
+	return func(net string) Exchanger {
+		if exchanger, ok := exchangers[net]; ok {
+			return exchanger
+		}
+
+		return &recursorExchanger{
+			plain:     &dns.Client{Net: net, Timeout: timeout, UDPSize: 65535},
+			encrypted: encrypted,
+		}
+	}
+}
+
+// NewRecursorTLSConfig builds the client TLS configuration used for DNS over
+// TLS and DNS over HTTPS recursors. Without a CA file the system roots are
+// trusted.
+func NewRecursorTLSConfig(recursorTLS config.RecursorTLSConfig) (*tls.Config, error) {
+	clientOptions := []tlsconfig.ClientOption{}
+	if recursorTLS.CAFile != "" {
+		clientOptions = append(clientOptions, tlsconfig.WithAuthorityFromFile(recursorTLS.CAFile))
+	}
+
+	if recursorTLS.ServerName != "" {
+		clientOptions = append(clientOptions, tlsconfig.WithServerName(recursorTLS.ServerName))
+	}
+
+	return tlsconfig.Build(tlsconfig.WithExternalServiceDefaults()).Client(clientOptions...)
+}
+
+type recursorExchanger struct {
+	plain     Exchanger
+	encrypted *encryptedExchangers
+}
+
+func (e *recursorExchanger) Exchange(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+	switch {
+	case strings.HasPrefix(recursor, config.TLSRecursorScheme):
+		address, serverName := config.SplitRecursorServerName(strings.TrimPrefix(recursor, config.TLSRecursorScheme))
+		host, _, _ := net.SplitHostPort(address)
+		return e.encrypted.forTLS(recursorServerName(host, serverName)).Exchange(m, address)
+	case strings.HasPrefix(recursor, config.HTTPSRecursorScheme):
+		recursorURL, serverName := config.SplitRecursorServerName(recursor)
+		var host string
+		if parsed, err := url.Parse(recursorURL); err == nil {
+			host = parsed.Hostname()
+		}
+		return e.encrypted.forHTTPS(recursorServerName(host, serverName)).Exchange(m, recursorURL)
+	default:
+		return e.plain.Exchange(m, recursor)
+	}
+}
+
+// recursorServerName returns the server name to verify an encrypted recursor
+// against: the one it names after a '#', else its host unless it is an IP.
+func recursorServerName(host, serverName string) string {
+	if serverName == "" && host != "" && net.ParseIP(host) == nil {
+		return host
+	}
+
+	return serverName
+}
+
+// encryptedExchangers creates the DNS over TLS and DNS over HTTPS exchangers
+// for each server name recursors are verified against, the empty one standing
+// for the server name of tlsConfig.
+type encryptedExchangers struct {
+	timeout   time.Duration
+	tlsConfig *tls.Config
+
+	mutex sync.Mutex
+	tls   map[string]Exchanger
+	https map[string]Exchanger
+}
+
+func (e *encryptedExchangers) forTLS(serverName string) Exchanger {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	exchanger, found := e.tls[serverName]
+	if !found {
+		client := &dns.Client{Net: "tcp-tls", Timeout: e.timeout, TLSConfig: e.withServerName(serverName)}
+		exchanger = NewPipeliningExchanger(client, pipelineIdleTimeout)
+		e.tls[serverName] = exchanger
+	}
+
+	return exchanger
+}
+
+func (e *encryptedExchangers) forHTTPS(serverName string) Exchanger {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	exchanger, found := e.https[serverName]
+	if !found {
+		exchanger = NewDNSOverHTTPSExchanger(e.timeout, e.withServerName(serverName))
+		e.https[serverName] = exchanger
+	}
+
+	return exchanger
+}
+
+func (e *encryptedExchangers) withServerName(serverName string) *tls.Config {
+	if serverName == "" {
+		return e.tlsConfig
+	}
+
+	var tlsConfig *tls.Config
+	if e.tlsConfig != nil {
+		tlsConfig = e.tlsConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.ServerName = serverName
+
+	return tlsConfig
+}
@@ -1,14 +1,23 @@
 package handlers_test
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	"bosh-dns/dns/server/handlers"
 )
 
@@ -26,4 +35,199 @@ var _ = Describe("ExchangerFactory", func() {
 		Expect(client.Net).To(Equal(net))
 		Expect(client.Timeout).To(Equal(timeout))
 	})
+
+	Describe("NewRecursorExchangerFactory", func() {
+		var (
+			answerWith func(string) dns.HandlerFunc
+			tlsConfig  *tls.Config
+			httpServer *httptest.Server
+			dnsServers []*dns.Server
+		)
+
+		startDNSServer := func(server *dns.Server) string {
+			started := make(chan struct{})
+			server.NotifyStartedFunc = func() { close(started) }
+			go server.ActivateAndServe() //nolint:errcheck
+			Eventually(started).Should(BeClosed())
+			dnsServers = append(dnsServers, server)
+
+			if server.PacketConn != nil {
+				return server.PacketConn.LocalAddr().String()
+			}
+			return server.Listener.Addr().String()
+		}
+
+		BeforeEach(func() {
+			dnsServers = nil
+			answerWith = func(ip string) dns.HandlerFunc {
+				return func(w dns.ResponseWriter, req *dns.Msg) {
+					m := &dns.Msg{}
+					m.SetReply(req)
+					m.Answer = []dns.RR{&dns.A{
+						Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
+						A:   net.ParseIP(ip),
+					}}
+					Expect(w.WriteMsg(m)).To(Succeed())
+				}
+			}
+
+			httpServer = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/dns-message"))
+
+				body, err := io.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+
+				req := &dns.Msg{}
+				Expect(req.Unpack(body)).To(Succeed())
+				Expect(req.Id).To(BeZero())
+
+				if r.URL.Path != "/dns-query" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				m := &dns.Msg{}
+				m.SetReply(req)
+				m.Answer = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
+					A:   net.ParseIP("10.0.0.3"),
+				}}
+				packed, err := m.Pack()
+				Expect(err).NotTo(HaveOccurred())
+
+				w.Header().Set("Content-Type", "application/dns-message")
+				w.Write(packed) //nolint:errcheck
+			}))
+
+			pool := x509.NewCertPool()
+			pool.AddCert(httpServer.Certificate())
+			tlsConfig = &tls.Config{RootCAs: pool, ServerName: "example.com"}
+		})
+
+		AfterEach(func() {
+			httpServer.Close()
+			for _, server := range dnsServers {
+				Expect(server.Shutdown()).To(Succeed())
+			}
+		})
+
+		query := func(recursor string) (*dns.Msg, error) {
+			m := &dns.Msg{}
+			m.SetQuestion("example.com.", dns.TypeA)
+			m.Id = 1234
+
+			exchanger := handlers.NewRecursorExchangerFactory(time.Second, tlsConfig)("udp")
+			answer, _, err := exchanger.Exchange(m, recursor)
+			if answer != nil {
+				Expect(answer.Id).To(Equal(uint16(1234)))
+			}
+			return answer, err
+		}
+
+		It("queries plain recursors over the network of the request", func() {
+			packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			address := startDNSServer(&dns.Server{PacketConn: packetConn, Handler: answerWith("10.0.0.1")})
+
+			answer, err := query(address)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.1"))
+		})
+
+		It("queries tls:// recursors over DNS over TLS", func() {
+			listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: httpServer.TLS.Certificates})
+			Expect(err).NotTo(HaveOccurred())
+			address := startDNSServer(&dns.Server{Listener: listener, Net: "tcp-tls", Handler: answerWith("10.0.0.2")})
+
+			answer, err := query("tls://" + address)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.2"))
+		})
+
+		It("verifies tls:// recursors against the server name of their address", func() {
+			listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: httpServer.TLS.Certificates})
+			Expect(err).NotTo(HaveOccurred())
+			address := startDNSServer(&dns.Server{Listener: listener, Net: "tcp-tls", Handler: answerWith("10.0.0.2")})
+
+			tlsConfig.ServerName = "dns.example.org"
+
+			answer, err := query("tls://" + address + "#example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.2"))
+
+			_, err = query("tls://" + address)
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+
+		It("resolves tls:// recursors addressed by name and verifies them against it", func() {
+			listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: httpServer.TLS.Certificates})
+			Expect(err).NotTo(HaveOccurred())
+			_, port, err := net.SplitHostPort(startDNSServer(&dns.Server{Listener: listener, Net: "tcp-tls", Handler: answerWith("10.0.0.2")}))
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = query("tls://localhost:" + port)
+			Expect(err).To(MatchError(ContainSubstring("not localhost")))
+
+			answer, err := query("tls://localhost:" + port + "#example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.2"))
+		})
+
+		It("queries https:// recursors over DNS over HTTPS", func() {
+			answer, err := query(httpServer.URL + "/dns-query")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.3"))
+		})
+
+		It("verifies https:// recursors against the server name of their address", func() {
+			tlsConfig.ServerName = "dns.example.org"
+
+			answer, err := query(httpServer.URL + "/dns-query#example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.3"))
+
+			_, err = query(httpServer.URL + "/dns-query")
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+
+		It("resolves https:// recursors addressed by name and verifies them against it", func() {
+			namedURL := strings.Replace(httpServer.URL, "127.0.0.1", "localhost", 1)
+
+			_, err := query(namedURL + "/dns-query")
+			Expect(err).To(MatchError(ContainSubstring("not localhost")))
+
+			answer, err := query(namedURL + "/dns-query#example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(answer.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.3"))
+		})
+
+		It("returns a network error when the https recursor does not answer successfully", func() {
+			_, err := query(httpServer.URL + "/not-found")
+			Expect(err).To(MatchError(ContainSubstring("404")))
+
+			var netErr net.Error
+			Expect(errors.As(err, &netErr)).To(BeTrue())
+		})
+
+		It("does not trust recursors signed by an unknown authority", func() {
+			tlsConfig = &tls.Config{ServerName: "example.com"}
+
+			_, err := query(httpServer.URL + "/dns-query")
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+	})
+
+	Describe("NewRecursorTLSConfig", func() {
+		It("sets the server name", func() {
+			tlsConfig, err := handlers.NewRecursorTLSConfig(config.RecursorTLSConfig{ServerName: "dns.example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tlsConfig.ServerName).To(Equal("dns.example.com"))
+			Expect(tlsConfig.RootCAs).To(BeNil())
+		})
+
+		It("returns an error when the CA file cannot be read", func() {
+			_, err := handlers.NewRecursorTLSConfig(config.RecursorTLSConfig{CAFile: "/does/not/exist"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
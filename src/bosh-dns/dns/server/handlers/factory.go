@@ -1,64 +1,290 @@
 package handlers
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/clock"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	"github.com/cloudfoundry/bosh-utils/httpclient"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/cloudfoundry/bosh-utils/system"
 	"github.com/miekg/dns"
 
 	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/records"
 	"bosh-dns/dns/server/records/dnsresolver"
+	"bosh-dns/tlsclient"
 )
 
 type Factory struct {
 	exchangerFactory   ExchangerFactory
+	recursorTLSConfig  *tls.Config
+	recursorSelection  string
+	recursorTimeout    time.Duration
+	recursorHedgeDelay time.Duration
 	clock              clock.Clock
 	recursorRetryCount int
+	shutdown           chan struct{}
 	logger             boshlog.Logger
 	truncater          dnsresolver.ResponseTruncater
 }
 
-func NewFactory(exchangerFactory ExchangerFactory, clock clock.Clock, recursorRetryCount int, logger boshlog.Logger, truncater dnsresolver.ResponseTruncater) *Factory {
+func NewFactory(exchangerFactory ExchangerFactory, recursorTLSConfig *tls.Config, recursorSelection string, recursorTimeout, recursorHedgeDelay time.Duration, clock clock.Clock, recursorRetryCount int, shutdown chan struct{}, logger boshlog.Logger, truncater dnsresolver.ResponseTruncater) *Factory {
 	return &Factory{
 		exchangerFactory:   exchangerFactory,
+		recursorTLSConfig:  recursorTLSConfig,
+		recursorSelection:  recursorSelection,
+		recursorTimeout:    recursorTimeout,
+		recursorHedgeDelay: recursorHedgeDelay,
 		clock:              clock,
 		recursorRetryCount: recursorRetryCount,
+		shutdown:           shutdown,
 		logger:             logger,
 		truncater:          truncater,
 	}
 }
 
-func (f *Factory) CreateHTTPJSONHandler(url string, cache bool) dns.Handler {
+func (f *Factory) CreateHTTPJSONHandler(urls []string, httpConfig config.HTTPSourceConfig, cache config.Cache) (dns.Handler, error) {
 	var handler dns.Handler
 
-	httpClient := httpclient.NewHTTPClient(httpclient.DefaultClient, f.logger)
-	handler = NewHTTPJSONHandler(url, httpClient, f.logger, f.truncater)
+	httpClient, err := NewHTTPSourceClient(httpConfig, f.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization, err := httpSourceAuthorization(httpConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	handler = NewHTTPJSONHandler(urls, authorization, httpConfig.MaxRetries, httpClient, f.logger, f.truncater)
+
+	if cache.Enabled {
+		return NewCachingDNSHandler(handler, cache, f.truncater, f.clock, f.logger)
+	}
+	return handler, nil
+}
+
+func (f *Factory) CreateZoneHandler(domain, file string, cache config.Cache) (dns.Handler, error) {
+	var handler dns.Handler
 
-	if cache {
-		handler = NewCachingDNSHandler(handler, f.truncater, f.clock, f.logger)
+	fileReader := records.NewFileReader(file, system.NewOsFileSystem(f.logger), f.clock, f.logger, f.shutdown)
+
+	zoneHandler, err := NewZoneHandler(domain, fileReader, f.shutdown, f.logger, f.truncater)
+	if err != nil {
+		return nil, err
+	}
+	handler = zoneHandler
+
+	if cache.Enabled {
+		return NewCachingDNSHandler(handler, cache, f.truncater, f.clock, f.logger)
 	}
-	return handler
+	return handler, nil
 }
 
-func (f *Factory) CreateForwardHandler(recursors []string, cache bool) dns.Handler {
+func (f *Factory) CreateHostsHandler(file string, cache config.Cache) (dns.Handler, error) {
 	var handler dns.Handler
 
-	// Forward handlers are not treated the same as recursors in
-	// /etc/resolv.conf.
-	//
-	// The default behavior defined by DNS spec is to use
-	// "smart" recursor selection.
-	rand.Shuffle(len(recursors), func(i, j int) {
-		recursors[i], recursors[j] = recursors[j], recursors[i]
-	})
+	fileReader := records.NewFileReader(file, system.NewOsFileSystem(f.logger), f.clock, f.logger, f.shutdown)
+	if _, err := fileReader.Get(); err != nil {
+		return nil, err
+	}
 
-	pool := NewFailoverRecursorPool(recursors, config.SmartRecursorSelection, f.recursorRetryCount, f.logger)
-	handler = NewForwardHandler(pool, f.exchangerFactory, f.clock, f.logger, f.truncater)
+	handler = NewHostsHandler(NewHosts([]records.FileReader{fileReader}, f.shutdown, f.logger), nil, f.logger, f.truncater)
 
-	if cache {
-		handler = NewCachingDNSHandler(handler, f.truncater, f.clock, f.logger)
+	if cache.Enabled {
+		return NewCachingDNSHandler(handler, cache, f.truncater, f.clock, f.logger)
 	}
-	return handler
+	return handler, nil
+}
+
+func (f *Factory) CreateForwardHandler(recursors []string, recursorTLS config.RecursorTLSConfig, options config.RecursorOptions, cache config.Cache) (dns.Handler, error) {
+	var handler dns.Handler
+
+	exchangerFactory := f.exchangerFactory
+	if recursorTLS != (config.RecursorTLSConfig{}) || options.RecursorTimeout > 0 {
+		tlsConfig := f.recursorTLSConfig
+		if recursorTLS != (config.RecursorTLSConfig{}) {
+			var err error
+			tlsConfig, err = NewRecursorTLSConfig(recursorTLS)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		timeout := f.recursorTimeout
+		if options.RecursorTimeout > 0 {
+			timeout = time.Duration(options.RecursorTimeout)
+		}
+
+		exchangerFactory = NewRecursorExchangerFactory(timeout, tlsConfig)
+	}
+
+	recursorSelection := f.recursorSelection
+	if options.RecursorSelection != "" {
+		recursorSelection = options.RecursorSelection
+	}
+	if recursorSelection == "" {
+		recursorSelection = config.SmartRecursorSelection
+	}
+
+	hedgeDelay := f.recursorHedgeDelay
+	if options.RecursorHedgeDelay > 0 {
+		hedgeDelay = time.Duration(options.RecursorHedgeDelay)
+	}
+
+	retryCount := f.recursorRetryCount
+	if options.RecursorMaxRetries != nil {
+		retryCount = *options.RecursorMaxRetries
+	}
+
+	recursors = excludeRecursors(recursors, options.ExcludedRecursors)
+	if len(recursors) == 0 {
+		return nil, errors.New("all recursors are excluded")
+	}
+
+	if recursorSelection == config.SmartRecursorSelection {
+		rand.Shuffle(len(recursors), func(i, j int) {
+			recursors[i], recursors[j] = recursors[j], recursors[i]
+		})
+	}
+
+	pool, err := NewRecursorPool(recursors, recursorSelection, hedgeDelay, retryCount, f.clock, f.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	handler = NewForwardHandler(pool, exchangerFactory, f.clock, f.logger, f.truncater)
+
+	if cache.Enabled {
+		return NewCachingDNSHandler(handler, cache, f.truncater, f.clock, f.logger)
+	}
+	return handler, nil
+}
+
+// NewHTTPSourceClient creates the client requesting HTTP JSON sources, which
+// authenticates with a client certificate when one is configured.
+func NewHTTPSourceClient(httpConfig config.HTTPSourceConfig, logger boshlog.Logger) (*httpclient.HTTPClient, error) {
+	timeout := time.Duration(httpConfig.Timeout)
+
+	if httpConfig.CertificateFile != "" || httpConfig.PrivateKeyFile != "" {
+		if httpConfig.CAFile == "" {
+			return nil, errors.New("http source ca_file is required with a client certificate")
+		}
+
+		cert, err := tls.LoadX509KeyPair(httpConfig.CertificateFile, httpConfig.PrivateKeyFile)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "loading http source client certificate")
+		}
+
+		caCert, err := os.ReadFile(httpConfig.CAFile)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "reading http source ca_file")
+		}
+
+		return tlsclient.New(httpConfig.ServerName, caCert, cert, timeout, logger)
+	}
+
+	client := httpclient.DefaultClient
+	if httpConfig.CAFile != "" {
+		caCert, err := os.ReadFile(httpConfig.CAFile)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "reading http source ca_file")
+		}
+
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM(caCert) {
+			return nil, errors.New("http source ca_file contains no certificates")
+		}
+
+		client = httpclient.CreateDefaultClient(caCertPool)
+		if httpConfig.ServerName != "" {
+			client.Transport.(*http.Transport).TLSClientConfig.ServerName = httpConfig.ServerName
+		}
+	}
+
+	if timeout > 0 {
+		timedClient := *client
+		timedClient.Timeout = timeout
+		client = &timedClient
+	}
+
+	return httpclient.NewHTTPClient(client, logger), nil
+}
+
+// httpSourceAuthorization returns the Authorization header to send to HTTP
+// JSON sources, if any.
+func httpSourceAuthorization(httpConfig config.HTTPSourceConfig) (string, error) {
+	if httpConfig.BearerTokenFile != "" && (httpConfig.Username != "" || httpConfig.PasswordFile != "") {
+		return "", errors.New("http source bearer_token_file and username must not both be set")
+	}
+
+	if httpConfig.BearerTokenFile != "" {
+		token, err := os.ReadFile(httpConfig.BearerTokenFile)
+		if err != nil {
+			return "", bosherr.WrapError(err, "reading http source bearer_token_file")
+		}
+
+		return "Bearer " + strings.TrimSpace(string(token)), nil
+	}
+
+	if httpConfig.Username != "" || httpConfig.PasswordFile != "" {
+		if httpConfig.Username == "" || httpConfig.PasswordFile == "" {
+			return "", errors.New("http source username and password_file must be set together")
+		}
+
+		password, err := os.ReadFile(httpConfig.PasswordFile)
+		if err != nil {
+			return "", bosherr.WrapError(err, "reading http source password_file")
+		}
+
+		credentials := httpConfig.Username + ":" + strings.TrimSpace(string(password))
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)), nil
+	}
+
+	return "", nil
+}
+
+// NewRecursorPool creates the recursor pool implementing a recursor
+// selection strategy.
+func NewRecursorPool(recursors []string, recursorSelection string, hedgeDelay time.Duration, recursorMaxRetries int, clock clock.Clock, logger boshlog.Logger) (RecursorPool, error) {
+	switch recursorSelection {
+	case config.SmartRecursorSelection, config.SerialRecursorSelection:
+		return NewFailoverRecursorPool(recursors, recursorSelection, recursorMaxRetries, logger), nil
+	case config.RaceRecursorSelection:
+		return NewRaceRecursorPool(recursors, hedgeDelay, recursorMaxRetries, logger), nil
+	case config.LatencyRecursorSelection:
+		return NewLatencyRecursorPool(recursors, recursorMaxRetries, clock, logger), nil
+	default:
+		return nil, fmt.Errorf("invalid value for recursor_selection: '%s'", recursorSelection)
+	}
+}
+
+// excludeRecursors returns a copy of recursors without the excluded ones.
+func excludeRecursors(recursors, excluded []string) []string {
+	remaining := []string{}
+	for _, recursor := range recursors {
+		isExcluded := false
+		for _, excludedRecursor := range excluded {
+			if recursor == excludedRecursor {
+				isExcluded = true
+				break
+			}
+		}
+
+		if !isExcluded {
+			remaining = append(remaining, recursor)
+		}
+	}
+
+	return remaining
 }
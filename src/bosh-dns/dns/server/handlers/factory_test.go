@@ -0,0 +1,352 @@
+package handlers_test
+
+import (
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
+)
+
+var _ = Describe("Factory", func() {
+	Describe("CreateHTTPJSONHandler", func() {
+		var (
+			factory    *handlers.Factory
+			fakeWriter *internalfakes.FakeResponseWriter
+			server     *ghttp.Server
+			tempDir    string
+		)
+
+		BeforeEach(func() {
+			fakeWriter = &internalfakes.FakeResponseWriter{}
+			factory = handlers.NewFactory(nil, nil, "", time.Second, 0, fakeclock.NewFakeClock(time.Now()), 0, nil, &loggerfakes.FakeLogger{}, &dnsresolverfakes.FakeResponseTruncater{})
+			server = ghttp.NewServer()
+			tempDir = GinkgoT().TempDir()
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		writeFile := func(name, contents string) string {
+			path := filepath.Join(tempDir, name)
+			Expect(os.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+			return path
+		}
+
+		serve := func(handler dns.Handler) {
+			m := &dns.Msg{}
+			m.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+			Expect(server.ReceivedRequests()).To(HaveLen(1))
+		}
+
+		It("sends the bearer token read from a file", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyHeaderKV("Authorization", "Bearer some-token"),
+				ghttp.RespondWith(http.StatusOK, `{"Status": 0}`),
+			))
+
+			handler, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				BearerTokenFile: writeFile("token", "some-token\n"),
+			}, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			serve(handler)
+		})
+
+		It("sends basic auth credentials with the password read from a file", func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyBasicAuth("some-user", "some-password"),
+				ghttp.RespondWith(http.StatusOK, `{"Status": 0}`),
+			))
+
+			handler, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				Username:     "some-user",
+				PasswordFile: writeFile("password", "some-password"),
+			}, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			serve(handler)
+		})
+
+		It("errors when both a bearer token and basic auth are configured", func() {
+			_, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				BearerTokenFile: writeFile("token", "some-token"),
+				Username:        "some-user",
+				PasswordFile:    writeFile("password", "some-password"),
+			}, config.Cache{})
+			Expect(err).To(MatchError("http source bearer_token_file and username must not both be set"))
+		})
+
+		It("errors when a username is configured without a password", func() {
+			_, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				Username: "some-user",
+			}, config.Cache{})
+			Expect(err).To(MatchError("http source username and password_file must be set together"))
+		})
+
+		It("errors when the token file cannot be read", func() {
+			_, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				BearerTokenFile: filepath.Join(tempDir, "missing"),
+			}, config.Cache{})
+			Expect(err).To(MatchError(ContainSubstring("reading http source bearer_token_file")))
+		})
+
+		It("verifies the server with the CA read from a file", func() {
+			tlsServer := ghttp.NewTLSServer()
+			defer tlsServer.Close()
+			tlsServer.AppendHandlers(ghttp.RespondWith(http.StatusOK, `{"Status": 0}`))
+
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.HTTPTestServer.Certificate().Raw})
+			handler, err := factory.CreateHTTPJSONHandler([]string{tlsServer.URL()}, config.HTTPSourceConfig{
+				CAFile:     writeFile("ca.crt", string(caCert)),
+				ServerName: "example.com",
+			}, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			m := &dns.Msg{}
+			m.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+
+			Expect(tlsServer.ReceivedRequests()).To(HaveLen(1))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeSuccess))
+		})
+
+		It("errors when a client certificate is configured without a CA", func() {
+			_, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				CertificateFile: "/some/client.crt",
+				PrivateKeyFile:  "/some/client.key",
+			}, config.Cache{})
+			Expect(err).To(MatchError("http source ca_file is required with a client certificate"))
+		})
+
+		It("errors when the CA contains no certificates", func() {
+			_, err := factory.CreateHTTPJSONHandler([]string{server.URL()}, config.HTTPSourceConfig{
+				CAFile: writeFile("ca.crt", "not a certificate"),
+			}, config.Cache{})
+			Expect(err).To(MatchError("http source ca_file contains no certificates"))
+		})
+	})
+
+	Describe("CreateZoneHandler", func() {
+		var factory *handlers.Factory
+
+		BeforeEach(func() {
+			factory = handlers.NewFactory(nil, nil, "", time.Second, 0, fakeclock.NewFakeClock(time.Now()), 0, nil, &loggerfakes.FakeLogger{}, &dnsresolverfakes.FakeResponseTruncater{})
+		})
+
+		It("serves the zone file", func() {
+			zoneFile := filepath.Join(GinkgoT().TempDir(), "zone.db")
+			Expect(os.WriteFile(zoneFile, []byte(`$TTL 300
+@   IN SOA ns.corp.example.com. hostmaster.corp.example.com. 1 3600 600 86400 60
+www IN A   10.0.0.10
+`), 0600)).To(Succeed())
+
+			handler, err := factory.CreateZoneHandler("corp.example.com.", zoneFile, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeWriter := &internalfakes.FakeResponseWriter{}
+			m := &dns.Msg{}
+			m.SetQuestion("www.corp.example.com.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Answer).To(HaveLen(1))
+		})
+
+		It("errors when the zone file does not exist", func() {
+			_, err := factory.CreateZoneHandler("corp.example.com.", filepath.Join(GinkgoT().TempDir(), "missing.db"), config.Cache{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CreateHostsHandler", func() {
+		var factory *handlers.Factory
+
+		BeforeEach(func() {
+			factory = handlers.NewFactory(nil, nil, "", time.Second, 0, fakeclock.NewFakeClock(time.Now()), 0, nil, &loggerfakes.FakeLogger{}, &dnsresolverfakes.FakeResponseTruncater{})
+		})
+
+		It("serves the hosts file", func() {
+			hostsFile := filepath.Join(GinkgoT().TempDir(), "hosts")
+			Expect(os.WriteFile(hostsFile, []byte("203.0.113.10 api.pinned.example.com\n"), 0600)).To(Succeed())
+
+			handler, err := factory.CreateHostsHandler(hostsFile, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeWriter := &internalfakes.FakeResponseWriter{}
+			m := &dns.Msg{}
+			m.SetQuestion("api.pinned.example.com.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Answer).To(HaveLen(1))
+		})
+
+		It("errors when the hosts file does not exist", func() {
+			_, err := factory.CreateHostsHandler(filepath.Join(GinkgoT().TempDir(), "missing"), config.Cache{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CreateForwardHandler", func() {
+		var (
+			factory       *handlers.Factory
+			fakeExchanger *handlersfakes.FakeExchanger
+			fakeWriter    *internalfakes.FakeResponseWriter
+			recursors     []string
+		)
+
+		BeforeEach(func() {
+			fakeExchanger = &handlersfakes.FakeExchanger{}
+			fakeExchanger.ExchangeStub = func(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+				if recursor == "10.0.0.1:53" {
+					return nil, 0, errors.New("recursor down")
+				}
+				return &dns.Msg{}, 0, nil
+			}
+			fakeWriter = &internalfakes.FakeResponseWriter{}
+			fakeWriter.RemoteAddrReturns(&net.UDPAddr{})
+
+			recursors = []string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53"}
+			factory = handlers.NewFactory(
+				func(string) handlers.Exchanger { return fakeExchanger },
+				nil,
+				"",
+				time.Second,
+				0,
+				fakeclock.NewFakeClock(time.Now()),
+				0,
+				nil,
+				&loggerfakes.FakeLogger{},
+				&dnsresolverfakes.FakeResponseTruncater{},
+			)
+		})
+
+		exchangedRecursors := func() []string {
+			exchanged := []string{}
+			for i := 0; i < fakeExchanger.ExchangeCallCount(); i++ {
+				_, recursor := fakeExchanger.ExchangeArgsForCall(i)
+				exchanged = append(exchanged, recursor)
+			}
+			return exchanged
+		}
+
+		It("uses the recursor selection and skips excluded recursors", func() {
+			handler, err := factory.CreateForwardHandler(recursors, config.RecursorTLSConfig{}, config.RecursorOptions{
+				RecursorSelection: config.SerialRecursorSelection,
+				ExcludedRecursors: []string{"10.0.0.2:53"},
+			}, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			m := &dns.Msg{}
+			m.SetQuestion("corp.example.com.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+
+			Expect(exchangedRecursors()).To(Equal([]string{"10.0.0.1:53", "10.0.0.3:53"}))
+			Expect(recursors).To(Equal([]string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53"}))
+		})
+
+		It("inherits the global recursor selection when unset", func() {
+			fakeExchanger.ExchangeReturns(nil, 0, errors.New("recursor down"))
+			recursors = []string{"10.0.0.1:53", "10.0.0.2:53", "10.0.0.3:53", "10.0.0.4:53", "10.0.0.5:53", "10.0.0.6:53"}
+
+			factory = handlers.NewFactory(
+				func(string) handlers.Exchanger { return fakeExchanger },
+				nil,
+				config.SerialRecursorSelection,
+				time.Second,
+				0,
+				fakeclock.NewFakeClock(time.Now()),
+				0,
+				nil,
+				&loggerfakes.FakeLogger{},
+				&dnsresolverfakes.FakeResponseTruncater{},
+			)
+
+			handler, err := factory.CreateForwardHandler(recursors, config.RecursorTLSConfig{}, config.RecursorOptions{}, config.Cache{})
+			Expect(err).NotTo(HaveOccurred())
+
+			m := &dns.Msg{}
+			m.SetQuestion("corp.example.com.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, m)
+
+			Expect(exchangedRecursors()).To(Equal(recursors))
+		})
+
+		Describe("recursor_max_retries", func() {
+			var retryCount func(*int) int
+
+			BeforeEach(func() {
+				fakeExchanger.ExchangeStub = func(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+					return nil, 0, &net.OpError{Op: "read", Err: errors.New("i/o timeout")}
+				}
+
+				factory = handlers.NewFactory(
+					func(string) handlers.Exchanger { return fakeExchanger },
+					nil,
+					"",
+					time.Second,
+					0,
+					fakeclock.NewFakeClock(time.Now()),
+					2,
+					nil,
+					&loggerfakes.FakeLogger{},
+					&dnsresolverfakes.FakeResponseTruncater{},
+				)
+
+				retryCount = func(maxRetries *int) int {
+					handler, err := factory.CreateForwardHandler([]string{"10.0.0.1:53"}, config.RecursorTLSConfig{}, config.RecursorOptions{
+						RecursorMaxRetries: maxRetries,
+					}, config.Cache{})
+					Expect(err).NotTo(HaveOccurred())
+
+					m := &dns.Msg{}
+					m.SetQuestion("corp.example.com.", dns.TypeA)
+					handler.ServeDNS(fakeWriter, m)
+
+					return fakeExchanger.ExchangeCallCount() - 1
+				}
+			})
+
+			It("inherits the global retries when unset", func() {
+				Expect(retryCount(nil)).To(Equal(2))
+			})
+
+			It("does not retry when set to zero", func() {
+				zero := 0
+				Expect(retryCount(&zero)).To(Equal(0))
+			})
+		})
+
+		It("errors when all recursors are excluded", func() {
+			_, err := factory.CreateForwardHandler(recursors, config.RecursorTLSConfig{}, config.RecursorOptions{
+				ExcludedRecursors: recursors,
+			}, config.Cache{})
+			Expect(err).To(MatchError("all recursors are excluded"))
+		})
+
+		It("errors when the recursor selection is invalid", func() {
+			_, err := factory.CreateForwardHandler(recursors, config.RecursorTLSConfig{}, config.RecursorOptions{
+				RecursorSelection: "random",
+			}, config.Cache{})
+			Expect(err).To(MatchError("invalid value for recursor_selection: 'random'"))
+		})
+	})
+})
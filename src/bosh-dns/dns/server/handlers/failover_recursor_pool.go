@@ -84,7 +84,7 @@ func newSerialFailoverRecursorPool(recursors []string, recursorSettings recursor
 
 }
 
-func newSmartFailoverRecursorPool(recursors []string, recursorSettings recursorRetrySettings, logger logger.Logger) RecursorPool {
+func newSmartFailoverRecursorPool(recursors []string, recursorSettings recursorRetrySettings, logger logger.Logger) *smartFailoverRecursorPool {
 	recursorsWithHistory := []recursorWithHistory{}
 
 	if recursors == nil {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/clock"
@@ -34,6 +35,15 @@ type Cache interface {
 	GetExpired(*dns.Msg) *dns.Msg
 }
 
+// StaleAnswerWriter is implemented by the response writers of caches which
+// hold expired answers (RFC 8767). ServeStale tells whether the cache answers
+// with the expired answer to the request, in which case the handler must not
+// answer itself.
+type StaleAnswerWriter interface {
+	dns.ResponseWriter
+	ServeStale() bool
+}
+
 func NewForwardHandler(
 	recursors RecursorPool,
 	exchangerFactory ExchangerFactory,
@@ -64,6 +74,8 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 
 	client := r.exchangerFactory(network)
 
+	var answered, nameError atomic.Bool
+
 	err := r.recursors.PerformStrategically(func(recursor string) error {
 		exchangeAnswer, _, err := client.Exchange(request, recursor)
 
@@ -75,6 +87,7 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 			question := request.Question[0].Name
 			err = server.NewDnsError(exchangeAnswer.MsgHdr.Rcode, question, recursor)
 			if exchangeAnswer.MsgHdr.Rcode == dns.RcodeNameError {
+				nameError.Store(true)
 				r.logger.Debug(r.logTag, "error recursing to %q: %s", recursor, err.Error())
 			} else {
 				r.logger.Error(r.logTag, "error recursing to %q: %s", recursor, err.Error())
@@ -85,6 +98,12 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 			return err
 		}
 
+		// with the "race" recursor selection several recursors may answer;
+		// only the first answer is written
+		if !answered.CompareAndSwap(false, true) {
+			return nil
+		}
+
 		r.truncater.TruncateIfNeeded(responseWriter, request, exchangeAnswer)
 
 		r.logRecursor(before, request, exchangeAnswer, "recursor="+recursor)
@@ -95,6 +114,15 @@ func (r ForwardHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns
 		return nil
 	})
 
+	if err != nil && !nameError.Load() {
+		// a recursor denying that the name exists is an answer; anything
+		// else means that no recursor could answer
+		if staleWriter, ok := responseWriter.(StaleAnswerWriter); ok && staleWriter.ServeStale() {
+			r.logger.Debug(r.logTag, "serving stale answer for %s: %s", request.Question[0].Name, err.Error())
+			return
+		}
+	}
+
 	if err != nil {
 		responseMessage := r.createResponseFromError(request, err)
 		r.logRecursor(before, request, responseMessage, "error=["+err.Error()+"]")
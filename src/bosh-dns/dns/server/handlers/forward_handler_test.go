@@ -184,6 +184,31 @@ var _ = Describe("ForwardHandler", func() {
 				Expect(message.RecursionAvailable).To(Equal(false))
 			})
 
+			Context("when several recursors answer", func() {
+				BeforeEach(func() {
+					fakeExchanger.ExchangeReturns(&dns.Msg{
+						Answer: []dns.RR{&dns.A{A: net.ParseIP("99.99.99.99")}},
+					}, 0, nil)
+
+					fakeRecursorPool.PerformStrategicallyStub = func(f func(string) error) error {
+						Expect(f("127.0.0.1")).To(Succeed())
+						Expect(f("10.244.5.4")).To(Succeed())
+						return nil
+					}
+				})
+
+				It("only writes the first answer", func() {
+					m := &dns.Msg{}
+					SetQuestion(m, nil, "example.com.", dns.TypeANY)
+
+					recursionHandler.ServeDNS(fakeWriter, m)
+
+					Expect(fakeExchanger.ExchangeCallCount()).To(Equal(2))
+					Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+					Expect(fakeTruncater.TruncateIfNeededCallCount()).To(Equal(1))
+				})
+			})
+
 			Context("when the message fails to write", func() {
 				It("logs the error", func() {
 					fakeWriter.WriteMsgReturns(errors.New("failed to write message"))
@@ -198,6 +223,64 @@ var _ = Describe("ForwardHandler", func() {
 			})
 		})
 
+		Context("when the cache holds an expired answer", func() {
+			var (
+				staleWriter *staleAnswerWriter
+				msg         *dns.Msg
+			)
+
+			BeforeEach(func() {
+				staleWriter = &staleAnswerWriter{FakeResponseWriter: fakeWriter, serveStale: true}
+
+				msg = &dns.Msg{}
+				msg.SetQuestion("example.com.", dns.TypeA)
+			})
+
+			Context("when all recursors fail", func() {
+				BeforeEach(func() {
+					fakeExchanger.ExchangeReturns(nil, 0, errors.New("i/o timeout"))
+				})
+
+				It("leaves the answer to the cache", func() {
+					recursionHandler.ServeDNS(staleWriter, msg)
+
+					Expect(staleWriter.asked).To(Equal(1))
+					Expect(fakeWriter.WriteMsgCallCount()).To(Equal(0))
+				})
+
+				Context("when the cache does not serve it", func() {
+					BeforeEach(func() {
+						staleWriter.serveStale = false
+					})
+
+					It("answers with the error", func() {
+						recursionHandler.ServeDNS(staleWriter, msg)
+
+						Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+						message := fakeWriter.WriteMsgArgsForCall(0)
+						Expect(message.Rcode).To(Equal(dns.RcodeNameError))
+					})
+				})
+			})
+
+			Context("when a recursor denies that the name exists", func() {
+				BeforeEach(func() {
+					nxdomain := &dns.Msg{}
+					nxdomain.SetRcode(msg, dns.RcodeNameError)
+					fakeExchanger.ExchangeReturns(nxdomain, 0, nil)
+				})
+
+				It("answers with the denial", func() {
+					recursionHandler.ServeDNS(staleWriter, msg)
+
+					Expect(staleWriter.asked).To(Equal(0))
+					Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+					message := fakeWriter.WriteMsgArgsForCall(0)
+					Expect(message.Rcode).To(Equal(dns.RcodeNameError))
+				})
+			})
+		})
+
 		Context("when request contains no questions", func() {
 			It("set a success rcode and authorative", func() {
 				recursionHandler.ServeDNS(fakeWriter, &dns.Msg{})
@@ -549,3 +632,14 @@ var _ = Describe("ForwardHandler", func() {
 		})
 	})
 })
+
+type staleAnswerWriter struct {
+	*internalfakes.FakeResponseWriter
+	serveStale bool
+	asked      int
+}
+
+func (w *staleAnswerWriter) ServeStale() bool {
+	w.asked++
+	return w.serveStale
+}
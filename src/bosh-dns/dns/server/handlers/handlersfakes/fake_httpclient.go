@@ -8,16 +8,17 @@ import (
 )
 
 type FakeHTTPClient struct {
-	GetStub        func(string) (*http.Response, error)
-	getMutex       sync.RWMutex
-	getArgsForCall []struct {
+	GetCustomizedStub        func(string, func(*http.Request)) (*http.Response, error)
+	getCustomizedMutex       sync.RWMutex
+	getCustomizedArgsForCall []struct {
 		arg1 string
+		arg2 func(*http.Request)
 	}
-	getReturns struct {
+	getCustomizedReturns struct {
 		result1 *http.Response
 		result2 error
 	}
-	getReturnsOnCall map[int]struct {
+	getCustomizedReturnsOnCall map[int]struct {
 		result1 *http.Response
 		result2 error
 	}
@@ -25,18 +26,19 @@ type FakeHTTPClient struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeHTTPClient) Get(arg1 string) (*http.Response, error) {
-	fake.getMutex.Lock()
-	ret, specificReturn := fake.getReturnsOnCall[len(fake.getArgsForCall)]
-	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+func (fake *FakeHTTPClient) GetCustomized(arg1 string, arg2 func(*http.Request)) (*http.Response, error) {
+	fake.getCustomizedMutex.Lock()
+	ret, specificReturn := fake.getCustomizedReturnsOnCall[len(fake.getCustomizedArgsForCall)]
+	fake.getCustomizedArgsForCall = append(fake.getCustomizedArgsForCall, struct {
 		arg1 string
-	}{arg1})
-	stub := fake.GetStub
-	fakeReturns := fake.getReturns
-	fake.recordInvocation("Get", []interface{}{arg1})
-	fake.getMutex.Unlock()
+		arg2 func(*http.Request)
+	}{arg1, arg2})
+	stub := fake.GetCustomizedStub
+	fakeReturns := fake.getCustomizedReturns
+	fake.recordInvocation("GetCustomized", []interface{}{arg1, arg2})
+	fake.getCustomizedMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -44,46 +46,46 @@ func (fake *FakeHTTPClient) Get(arg1 string) (*http.Response, error) {
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeHTTPClient) GetCallCount() int {
-	fake.getMutex.RLock()
-	defer fake.getMutex.RUnlock()
-	return len(fake.getArgsForCall)
+func (fake *FakeHTTPClient) GetCustomizedCallCount() int {
+	fake.getCustomizedMutex.RLock()
+	defer fake.getCustomizedMutex.RUnlock()
+	return len(fake.getCustomizedArgsForCall)
 }
 
-func (fake *FakeHTTPClient) GetCalls(stub func(string) (*http.Response, error)) {
-	fake.getMutex.Lock()
-	defer fake.getMutex.Unlock()
-	fake.GetStub = stub
+func (fake *FakeHTTPClient) GetCustomizedCalls(stub func(string, func(*http.Request)) (*http.Response, error)) {
+	fake.getCustomizedMutex.Lock()
+	defer fake.getCustomizedMutex.Unlock()
+	fake.GetCustomizedStub = stub
 }
 
-func (fake *FakeHTTPClient) GetArgsForCall(i int) string {
-	fake.getMutex.RLock()
-	defer fake.getMutex.RUnlock()
-	argsForCall := fake.getArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeHTTPClient) GetCustomizedArgsForCall(i int) (string, func(*http.Request)) {
+	fake.getCustomizedMutex.RLock()
+	defer fake.getCustomizedMutex.RUnlock()
+	argsForCall := fake.getCustomizedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeHTTPClient) GetReturns(result1 *http.Response, result2 error) {
-	fake.getMutex.Lock()
-	defer fake.getMutex.Unlock()
-	fake.GetStub = nil
-	fake.getReturns = struct {
+func (fake *FakeHTTPClient) GetCustomizedReturns(result1 *http.Response, result2 error) {
+	fake.getCustomizedMutex.Lock()
+	defer fake.getCustomizedMutex.Unlock()
+	fake.GetCustomizedStub = nil
+	fake.getCustomizedReturns = struct {
 		result1 *http.Response
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeHTTPClient) GetReturnsOnCall(i int, result1 *http.Response, result2 error) {
-	fake.getMutex.Lock()
-	defer fake.getMutex.Unlock()
-	fake.GetStub = nil
-	if fake.getReturnsOnCall == nil {
-		fake.getReturnsOnCall = make(map[int]struct {
+func (fake *FakeHTTPClient) GetCustomizedReturnsOnCall(i int, result1 *http.Response, result2 error) {
+	fake.getCustomizedMutex.Lock()
+	defer fake.getCustomizedMutex.Unlock()
+	fake.GetCustomizedStub = nil
+	if fake.getCustomizedReturnsOnCall == nil {
+		fake.getCustomizedReturnsOnCall = make(map[int]struct {
 			result1 *http.Response
 			result2 error
 		})
 	}
-	fake.getReturnsOnCall[i] = struct {
+	fake.getCustomizedReturnsOnCall[i] = struct {
 		result1 *http.Response
 		result2 error
 	}{result1, result2}
@@ -92,8 +94,8 @@ func (fake *FakeHTTPClient) GetReturnsOnCall(i int, result1 *http.Response, resu
 func (fake *FakeHTTPClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.getMutex.RLock()
-	defer fake.getMutex.RUnlock()
+	fake.getCustomizedMutex.RLock()
+	defer fake.getCustomizedMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
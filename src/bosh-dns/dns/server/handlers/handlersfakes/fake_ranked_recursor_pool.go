@@ -0,0 +1,175 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package handlersfakes
+
+import (
+	"bosh-dns/dns/server/handlers"
+	"sync"
+)
+
+type FakeRankedRecursorPool struct {
+	PerformStrategicallyStub        func(func(string) error) error
+	performStrategicallyMutex       sync.RWMutex
+	performStrategicallyArgsForCall []struct {
+		arg1 func(string) error
+	}
+	performStrategicallyReturns struct {
+		result1 error
+	}
+	performStrategicallyReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RankingsStub        func() []handlers.RecursorRanking
+	rankingsMutex       sync.RWMutex
+	rankingsArgsForCall []struct {
+	}
+	rankingsReturns struct {
+		result1 []handlers.RecursorRanking
+	}
+	rankingsReturnsOnCall map[int]struct {
+		result1 []handlers.RecursorRanking
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategically(arg1 func(string) error) error {
+	fake.performStrategicallyMutex.Lock()
+	ret, specificReturn := fake.performStrategicallyReturnsOnCall[len(fake.performStrategicallyArgsForCall)]
+	fake.performStrategicallyArgsForCall = append(fake.performStrategicallyArgsForCall, struct {
+		arg1 func(string) error
+	}{arg1})
+	stub := fake.PerformStrategicallyStub
+	fakeReturns := fake.performStrategicallyReturns
+	fake.recordInvocation("PerformStrategically", []interface{}{arg1})
+	fake.performStrategicallyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategicallyCallCount() int {
+	fake.performStrategicallyMutex.RLock()
+	defer fake.performStrategicallyMutex.RUnlock()
+	return len(fake.performStrategicallyArgsForCall)
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategicallyCalls(stub func(func(string) error) error) {
+	fake.performStrategicallyMutex.Lock()
+	defer fake.performStrategicallyMutex.Unlock()
+	fake.PerformStrategicallyStub = stub
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategicallyArgsForCall(i int) func(string) error {
+	fake.performStrategicallyMutex.RLock()
+	defer fake.performStrategicallyMutex.RUnlock()
+	argsForCall := fake.performStrategicallyArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategicallyReturns(result1 error) {
+	fake.performStrategicallyMutex.Lock()
+	defer fake.performStrategicallyMutex.Unlock()
+	fake.PerformStrategicallyStub = nil
+	fake.performStrategicallyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRankedRecursorPool) PerformStrategicallyReturnsOnCall(i int, result1 error) {
+	fake.performStrategicallyMutex.Lock()
+	defer fake.performStrategicallyMutex.Unlock()
+	fake.PerformStrategicallyStub = nil
+	if fake.performStrategicallyReturnsOnCall == nil {
+		fake.performStrategicallyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.performStrategicallyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeRankedRecursorPool) Rankings() []handlers.RecursorRanking {
+	fake.rankingsMutex.Lock()
+	ret, specificReturn := fake.rankingsReturnsOnCall[len(fake.rankingsArgsForCall)]
+	fake.rankingsArgsForCall = append(fake.rankingsArgsForCall, struct {
+	}{})
+	stub := fake.RankingsStub
+	fakeReturns := fake.rankingsReturns
+	fake.recordInvocation("Rankings", []interface{}{})
+	fake.rankingsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRankedRecursorPool) RankingsCallCount() int {
+	fake.rankingsMutex.RLock()
+	defer fake.rankingsMutex.RUnlock()
+	return len(fake.rankingsArgsForCall)
+}
+
+func (fake *FakeRankedRecursorPool) RankingsCalls(stub func() []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = stub
+}
+
+func (fake *FakeRankedRecursorPool) RankingsReturns(result1 []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = nil
+	fake.rankingsReturns = struct {
+		result1 []handlers.RecursorRanking
+	}{result1}
+}
+
+func (fake *FakeRankedRecursorPool) RankingsReturnsOnCall(i int, result1 []handlers.RecursorRanking) {
+	fake.rankingsMutex.Lock()
+	defer fake.rankingsMutex.Unlock()
+	fake.RankingsStub = nil
+	if fake.rankingsReturnsOnCall == nil {
+		fake.rankingsReturnsOnCall = make(map[int]struct {
+			result1 []handlers.RecursorRanking
+		})
+	}
+	fake.rankingsReturnsOnCall[i] = struct {
+		result1 []handlers.RecursorRanking
+	}{result1}
+}
+
+func (fake *FakeRankedRecursorPool) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.performStrategicallyMutex.RLock()
+	defer fake.performStrategicallyMutex.RUnlock()
+	fake.rankingsMutex.RLock()
+	defer fake.rankingsMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeRankedRecursorPool) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ handlers.RankedRecursorPool = new(FakeRankedRecursorPool)
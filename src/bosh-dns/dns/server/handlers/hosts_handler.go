@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/dnsresolver"
+)
+
+// hostsTTL is kept short so that clients pick up changes to hosts files
+// quickly, as entries tend to be pinned only for a while.
+const hostsTTL = 5
+
+// Hosts holds the entries of files in the /etc/hosts format. Each file is
+// parsed again whenever it changes.
+type Hosts struct {
+	fileReaders []records.FileReader
+	logger      logger.Logger
+	logTag      string
+
+	mutex   sync.RWMutex
+	entries hostsEntries
+}
+
+type hostsEntries struct {
+	// addresses holds the addresses of each name in the order of the files
+	addresses map[string][]net.IP
+
+	// names holds the names of each address by its reverse lookup name
+	names map[string][]string
+}
+
+func NewHosts(fileReaders []records.FileReader, shutdown chan struct{}, logger logger.Logger) *Hosts {
+	h := &Hosts{
+		fileReaders: fileReaders,
+		logger:      logger,
+		logTag:      "Hosts",
+	}
+
+	h.load()
+
+	for _, fileReader := range fileReaders {
+		go func(fileReader records.FileReader) {
+			subscriptionChan := fileReader.Subscribe()
+
+			for {
+				select {
+				case <-shutdown:
+					return
+				case ok := <-subscriptionChan:
+					if !ok {
+						return
+					}
+
+					h.load()
+				}
+			}
+		}(fileReader)
+	}
+
+	return h
+}
+
+func (h *Hosts) load() {
+	entries := hostsEntries{
+		addresses: map[string][]net.IP{},
+		names:     map[string][]string{},
+	}
+
+	for _, fileReader := range h.fileReaders {
+		contents, err := fileReader.Get()
+		if err != nil {
+			h.logger.Error(h.logTag, "Skipping hosts file: %s", err.Error())
+			continue
+		}
+
+		h.parse(contents, entries)
+	}
+
+	h.mutex.Lock()
+	h.entries = entries
+	h.mutex.Unlock()
+}
+
+func (h *Hosts) parse(contents []byte, entries hostsEntries) {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		ip := net.ParseIP(fields[0])
+		if ip == nil || len(fields) < 2 {
+			h.logger.Error(h.logTag, "Skipping invalid hosts entry '%s'", scanner.Text())
+			continue
+		}
+
+		reverseName, _ := dns.ReverseAddr(ip.String())
+		for _, name := range fields[1:] {
+			name = strings.ToLower(dns.Fqdn(name))
+			if _, ok := dns.IsDomainName(name); !ok {
+				h.logger.Error(h.logTag, "Skipping invalid hosts name '%s'", name)
+				continue
+			}
+
+			entries.addresses[name] = append(entries.addresses[name], ip)
+			entries.names[reverseName] = append(entries.names[reverseName], name)
+		}
+	}
+}
+
+// lookup returns the records answering the question, and whether the name
+// of the question is listed at all.
+func (h *Hosts) lookup(question dns.Question) ([]dns.RR, bool) {
+	name := strings.ToLower(question.Name)
+
+	h.mutex.RLock()
+	addresses, isHost := h.entries.addresses[name]
+	names, isAddress := h.entries.names[name]
+	h.mutex.RUnlock()
+
+	header := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: question.Name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: hostsTTL}
+	}
+
+	var rrs []dns.RR
+	for _, ip := range addresses {
+		if ip4 := ip.To4(); ip4 != nil && (question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY) {
+			rrs = append(rrs, &dns.A{Hdr: header(dns.TypeA), A: ip4})
+		} else if ip4 == nil && (question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY) {
+			rrs = append(rrs, &dns.AAAA{Hdr: header(dns.TypeAAAA), AAAA: ip})
+		}
+	}
+
+	for _, ptr := range names {
+		if question.Qtype == dns.TypePTR || question.Qtype == dns.TypeANY {
+			rrs = append(rrs, &dns.PTR{Hdr: header(dns.TypePTR), Ptr: ptr})
+		}
+	}
+
+	return rrs, isHost || isAddress
+}
+
+// HostsHandler answers A, AAAA and PTR queries for the names listed in hosts
+// files. Other names are passed on to the next handler, or do not exist when
+// there is none.
+type HostsHandler struct {
+	hosts     *Hosts
+	next      dns.Handler
+	logger    logger.Logger
+	logTag    string
+	truncater dnsresolver.ResponseTruncater
+}
+
+func NewHostsHandler(hosts *Hosts, next dns.Handler, logger logger.Logger, truncater dnsresolver.ResponseTruncater) HostsHandler {
+	return HostsHandler{
+		hosts:     hosts,
+		next:      next,
+		logger:    logger,
+		logTag:    "HostsHandler",
+		truncater: truncater,
+	}
+}
+
+func (h HostsHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns.Msg) {
+	responseMsg := &dns.Msg{}
+	responseMsg.SetReply(request)
+	responseMsg.Authoritative = true
+	responseMsg.RecursionAvailable = true
+
+	if len(request.Question) > 0 {
+		question := request.Question[0]
+		rrs, listed := h.hosts.lookup(question)
+
+		switch {
+		case listed && isHostsQueryType(question.Qtype):
+			responseMsg.Answer = rrs
+		case h.next != nil:
+			h.next.ServeDNS(responseWriter, request)
+			return
+		case !listed:
+			responseMsg.Rcode = dns.RcodeNameError
+		}
+	}
+
+	h.truncater.TruncateIfNeeded(responseWriter, request, responseMsg)
+
+	if err := responseWriter.WriteMsg(responseMsg); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+// isHostsQueryType tells whether hosts files hold the answers to queries of
+// a type, so that listed names are not resolved any further.
+func isHostsQueryType(qtype uint16) bool {
+	switch qtype {
+	case dns.TypeA, dns.TypeAAAA, dns.TypePTR, dns.TypeANY:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,248 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
+	"bosh-dns/dns/server/records/recordsfakes"
+)
+
+var _ = Describe("HostsHandler", func() {
+	var (
+		hostsHandler    handlers.HostsHandler
+		hosts           *handlers.Hosts
+		fakeFileReader  *recordsfakes.FakeFileReader
+		otherFileReader *recordsfakes.FakeFileReader
+		fakeNext        *handlersfakes.FakeDNSHandler
+		next            dns.Handler
+		fakeWriter      *internalfakes.FakeResponseWriter
+		fakeLogger      *loggerfakes.FakeLogger
+		fakeTruncater   *dnsresolverfakes.FakeResponseTruncater
+		subscription    chan bool
+		shutdown        chan struct{}
+	)
+
+	BeforeEach(func() {
+		fakeFileReader = &recordsfakes.FakeFileReader{}
+		fakeFileReader.GetReturns([]byte(`# pinned during the incident
+203.0.113.10   api.example.com   API-Alias.example.com
+2001:db8::10   api.example.com
+203.0.113.20   ipv4-only.example.com # trailing comment
+not-an-ip      broken.example.com
+`), nil)
+		subscription = make(chan bool)
+		fakeFileReader.SubscribeReturns(subscription)
+
+		otherFileReader = &recordsfakes.FakeFileReader{}
+		otherFileReader.GetReturns([]byte("203.0.113.30 other.example.com\n"), nil)
+
+		fakeNext = &handlersfakes.FakeDNSHandler{}
+		next = fakeNext
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
+		shutdown = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		hosts = handlers.NewHosts([]records.FileReader{fakeFileReader, otherFileReader}, shutdown, fakeLogger)
+		hostsHandler = handlers.NewHostsHandler(hosts, next, fakeLogger, fakeTruncater)
+	})
+
+	AfterEach(func() {
+		close(shutdown)
+	})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := &dns.Msg{}
+		req.SetQuestion(name, qtype)
+
+		callCount := fakeWriter.WriteMsgCallCount()
+		hostsHandler.ServeDNS(fakeWriter, req)
+		if fakeWriter.WriteMsgCallCount() == callCount {
+			return nil
+		}
+
+		resp := fakeWriter.WriteMsgArgsForCall(callCount)
+		Expect(resp.Id).To(Equal(req.Id))
+		Expect(resp.Question).To(Equal(req.Question))
+		return resp
+	}
+
+	records := func(rrs []dns.RR) []string {
+		strs := []string{}
+		for _, rr := range rrs {
+			strs = append(strs, rr.String())
+		}
+		return strs
+	}
+
+	It("answers A queries for listed names", func() {
+		resp := query("api.example.com.", dns.TypeA)
+
+		Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(resp.Authoritative).To(BeTrue())
+		Expect(resp.RecursionAvailable).To(BeTrue())
+		Expect(records(resp.Answer)).To(ConsistOf("api.example.com.\t5\tIN\tA\t203.0.113.10"))
+		Expect(fakeNext.ServeDNSCallCount()).To(Equal(0))
+	})
+
+	It("answers AAAA queries for listed names", func() {
+		resp := query("api.example.com.", dns.TypeAAAA)
+
+		Expect(records(resp.Answer)).To(ConsistOf("api.example.com.\t5\tIN\tAAAA\t2001:db8::10"))
+	})
+
+	It("answers every address for ANY queries", func() {
+		resp := query("api.example.com.", dns.TypeANY)
+
+		Expect(resp.Answer).To(HaveLen(2))
+	})
+
+	It("matches names case insensitively and keeps the case of the question", func() {
+		resp := query("api-alias.EXAMPLE.com.", dns.TypeA)
+
+		Expect(records(resp.Answer)).To(ConsistOf("api-alias.EXAMPLE.com.\t5\tIN\tA\t203.0.113.10"))
+	})
+
+	It("answers without records for an address family which is not listed", func() {
+		resp := query("ipv4-only.example.com.", dns.TypeAAAA)
+
+		Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(resp.Answer).To(BeEmpty())
+		Expect(fakeNext.ServeDNSCallCount()).To(Equal(0))
+	})
+
+	It("answers PTR queries for listed addresses", func() {
+		resp := query("10.113.0.203.in-addr.arpa.", dns.TypePTR)
+
+		Expect(records(resp.Answer)).To(ConsistOf(
+			"10.113.0.203.in-addr.arpa.\t5\tIN\tPTR\tapi.example.com.",
+			"10.113.0.203.in-addr.arpa.\t5\tIN\tPTR\tapi-alias.example.com.",
+		))
+	})
+
+	It("merges the entries of every file", func() {
+		resp := query("other.example.com.", dns.TypeA)
+
+		Expect(records(resp.Answer)).To(ConsistOf("other.example.com.\t5\tIN\tA\t203.0.113.30"))
+	})
+
+	It("truncates the response if needed", func() {
+		resp := query("api.example.com.", dns.TypeA)
+
+		Expect(fakeTruncater.TruncateIfNeededCallCount()).To(Equal(1))
+		_, _, truncated := fakeTruncater.TruncateIfNeededArgsForCall(0)
+		Expect(truncated).To(Equal(resp))
+	})
+
+	It("logs invalid entries", func() {
+		Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+		tag, template, args := fakeLogger.ErrorArgsForCall(0)
+		Expect(tag).To(Equal("Hosts"))
+		Expect(fmt.Sprintf(template, args...)).To(Equal("Skipping invalid hosts entry 'not-an-ip      broken.example.com'"))
+	})
+
+	Context("when the name is not listed", func() {
+		It("passes the request on to the next handler", func() {
+			Expect(query("www.example.com.", dns.TypeA)).To(BeNil())
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+			writer, req := fakeNext.ServeDNSArgsForCall(0)
+			Expect(writer).To(Equal(fakeWriter))
+			Expect(req.Question[0].Name).To(Equal("www.example.com."))
+		})
+
+		It("passes subdomains of listed names on as well", func() {
+			Expect(query("www.api.example.com.", dns.TypeA)).To(BeNil())
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+		})
+
+		Context("and there is no next handler", func() {
+			BeforeEach(func() {
+				next = nil
+			})
+
+			It("answers NXDOMAIN", func() {
+				resp := query("www.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(resp.Answer).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the query is of another type", func() {
+		It("passes the request on to the next handler", func() {
+			Expect(query("api.example.com.", dns.TypeMX)).To(BeNil())
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+		})
+
+		Context("and there is no next handler", func() {
+			BeforeEach(func() {
+				next = nil
+			})
+
+			It("answers without records", func() {
+				resp := query("api.example.com.", dns.TypeMX)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when a hosts file changes", func() {
+		It("serves the new entries", func() {
+			fakeFileReader.GetReturns([]byte("198.51.100.10 api.example.com\n"), nil)
+			subscription <- true
+
+			Eventually(func() []string {
+				return records(query("api.example.com.", dns.TypeA).Answer)
+			}).Should(ConsistOf("api.example.com.\t5\tIN\tA\t198.51.100.10"))
+
+			Expect(records(query("other.example.com.", dns.TypeA).Answer)).To(ConsistOf("other.example.com.\t5\tIN\tA\t203.0.113.30"))
+		})
+	})
+
+	Context("when a hosts file cannot be read", func() {
+		BeforeEach(func() {
+			otherFileReader.GetReturns(nil, errors.New("no such file"))
+		})
+
+		It("serves the entries of the other files and logs the error", func() {
+			Expect(records(query("api.example.com.", dns.TypeA).Answer)).To(HaveLen(1))
+
+			Expect(fakeLogger.ErrorCallCount()).To(Equal(2))
+			tag, template, args := fakeLogger.ErrorArgsForCall(1)
+			Expect(tag).To(Equal("Hosts"))
+			Expect(fmt.Sprintf(template, args...)).To(Equal("Skipping hosts file: no such file"))
+		})
+	})
+
+	Context("when it cannot write the response message", func() {
+		BeforeEach(func() {
+			fakeWriter.WriteMsgReturns(errors.New("failed to write message"))
+		})
+
+		It("logs the error", func() {
+			query("api.example.com.", dns.TypeA)
+
+			tag, msg, _ := fakeLogger.ErrorArgsForCall(fakeLogger.ErrorCallCount() - 1)
+			Expect(tag).To(Equal("HostsHandler"))
+			Expect(msg).To(Equal("failed to write message"))
+		})
+	})
+})
@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/miekg/dns"
@@ -18,15 +18,19 @@ import (
 //counterfeiter:generate . HTTPClient
 
 type HTTPClient interface {
-	Get(endpoint string) (*http.Response, error)
+	GetCustomized(endpoint string, f func(*http.Request)) (*http.Response, error)
 }
 
+// HTTPJSONHandler queries its addresses in order until one of them answers,
+// starting over up to maxRetries times when none of them do.
 type HTTPJSONHandler struct {
-	address   string
-	client    HTTPClient
-	logger    logger.Logger
-	logTag    string
-	truncater dnsresolver.ResponseTruncater
+	addresses     []string
+	authorization string
+	maxRetries    int
+	client        HTTPClient
+	logger        logger.Logger
+	logTag        string
+	truncater     dnsresolver.ResponseTruncater
 }
 
 type Answer struct {
@@ -36,18 +40,25 @@ type Answer struct {
 	Data   string `json:"data"`
 }
 
+// httpDNSMessage is a message of the JSON DNS schema served by Google and
+// Cloudflare.
 type httpDNSMessage struct {
-	Truncated bool     `json:"TC"`
-	Answer    []Answer `json:"Answer"`
+	Status     int      `json:"Status"`
+	Truncated  bool     `json:"TC"`
+	Answer     []Answer `json:"Answer"`
+	Authority  []Answer `json:"Authority"`
+	Additional []Answer `json:"Additional"`
 }
 
-func NewHTTPJSONHandler(address string, httpClient HTTPClient, logger logger.Logger, truncater dnsresolver.ResponseTruncater) HTTPJSONHandler {
+func NewHTTPJSONHandler(addresses []string, authorization string, maxRetries int, httpClient HTTPClient, logger logger.Logger, truncater dnsresolver.ResponseTruncater) HTTPJSONHandler {
 	return HTTPJSONHandler{
-		address:   address,
-		client:    httpClient,
-		logger:    logger,
-		logTag:    "HTTPJSONHandler",
-		truncater: truncater,
+		addresses:     addresses,
+		authorization: authorization,
+		maxRetries:    maxRetries,
+		client:        httpClient,
+		logger:        logger,
+		logTag:        "HTTPJSONHandler",
+		truncater:     truncater,
 	}
 }
 
@@ -78,54 +89,111 @@ func (h HTTPJSONHandler) buildResponse(request *dns.Msg) *dns.Msg {
 		"name": []string{question.Name},
 	}.Encode()
 
-	url := fmt.Sprintf("%s/?%s", h.address, queryParams)
-	httpResponse, err := h.client.Get(url)
-
+	httpDNSMessage, err := h.query(queryParams)
 	if err != nil {
-		h.logger.Error(h.logTag, "error connecting to '%s': %v", h.address, err)
 		responseMsg.SetRcode(request, dns.RcodeServerFailure)
 		return responseMsg
 	}
 
+	responseMsg.Truncated = httpDNSMessage.Truncated
+	responseMsg.Answer = h.buildRecords(question, httpDNSMessage.Answer)
+	responseMsg.Ns = h.buildRecords(question, httpDNSMessage.Authority)
+	responseMsg.Extra = h.buildRecords(question, httpDNSMessage.Additional)
+
+	responseMsg.SetRcode(request, httpDNSMessage.Status)
+	return responseMsg
+}
+
+func (h HTTPJSONHandler) query(queryParams string) (*httpDNSMessage, error) {
+	var err error
+
+	for attempt := 0; attempt <= h.maxRetries; attempt++ {
+		for _, address := range h.addresses {
+			var httpDNSMessage *httpDNSMessage
+			httpDNSMessage, err = h.queryAddress(address, queryParams)
+			if err == nil {
+				return httpDNSMessage, nil
+			}
+		}
+	}
+
+	return nil, err
+}
+
+func (h HTTPJSONHandler) queryAddress(address, queryParams string) (*httpDNSMessage, error) {
+	url := fmt.Sprintf("%s/?%s", address, queryParams)
+	httpResponse, err := h.client.GetCustomized(url, func(request *http.Request) {
+		if h.authorization != "" {
+			request.Header.Set("Authorization", h.authorization)
+		}
+	})
+
+	if err != nil {
+		h.logger.Error(h.logTag, "error connecting to '%s': %v", address, err)
+		return nil, err
+	}
+
 	defer func() {
 		io.ReadAll(httpResponse.Body) //nolint:errcheck
 		httpResponse.Body.Close()
 	}()
 
 	if httpResponse.StatusCode != 200 {
-		h.logger.Error(h.logTag, "non successful response from server '%s': %v", h.address, httpResponse)
-		responseMsg.SetRcode(request, dns.RcodeServerFailure)
-		return responseMsg
+		h.logger.Error(h.logTag, "non successful response from server '%s': %v", address, httpResponse)
+		return nil, fmt.Errorf("non successful response from server '%s': %d", address, httpResponse.StatusCode)
 	}
 
 	httpDNSMessage := &httpDNSMessage{}
 	bytes, err := io.ReadAll(httpResponse.Body)
 	if err != nil {
 		h.logger.Error(h.logTag, "failed to read response message '%s': %v", string(bytes), err)
-		responseMsg.SetRcode(request, dns.RcodeServerFailure)
-		return responseMsg
+		return nil, err
 	}
 
 	err = json.Unmarshal(bytes, httpDNSMessage)
 	if err != nil {
 		h.logger.Error(h.logTag, "failed to unmarshal response message '%s': %v", string(bytes), err)
-		responseMsg.SetRcode(request, dns.RcodeServerFailure)
-		return responseMsg
+		return nil, err
 	}
 
-	responseMsg.Truncated = httpDNSMessage.Truncated
-	for _, answer := range httpDNSMessage.Answer {
-		responseMsg.Answer = append(responseMsg.Answer, &dns.A{
-			Hdr: dns.RR_Header{
-				Name:   question.Name,
-				Rrtype: answer.RRType,
-				Class:  dns.ClassINET,
-				Ttl:    answer.TTL,
-			},
-			A: net.ParseIP(answer.Data),
-		})
+	return httpDNSMessage, nil
+}
+
+// buildRecords parses the records of a section from their presentation
+// format, skipping the ones which cannot be parsed.
+func (h HTTPJSONHandler) buildRecords(question dns.Question, answers []Answer) []dns.RR {
+	var records []dns.RR
+
+	for _, answer := range answers {
+		if answer.RRType == dns.TypeOPT {
+			continue
+		}
+
+		rrType, ok := dns.TypeToString[answer.RRType]
+		if !ok {
+			h.logger.Error(h.logTag, "unsupported record type %d for '%s'", answer.RRType, answer.Name)
+			continue
+		}
+
+		// keep the case of the question, which clients may verify
+		name := dns.Fqdn(answer.Name)
+		if answer.Name == "" || strings.EqualFold(name, question.Name) {
+			name = question.Name
+		}
+
+		data := answer.Data
+		if answer.RRType == dns.TypeTXT && !strings.HasPrefix(data, `"`) {
+			data = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(data) + `"`
+		}
+
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, answer.TTL, rrType, data))
+		if err != nil || rr == nil {
+			h.logger.Error(h.logTag, "failed to parse %s record '%s' for '%s': %v", rrType, answer.Data, answer.Name, err)
+			continue
+		}
+
+		records = append(records, rr)
 	}
 
-	responseMsg.SetRcode(request, dns.RcodeSuccess)
-	return responseMsg
+	return records
 }
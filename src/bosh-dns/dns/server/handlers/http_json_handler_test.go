@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-utils/httpclient"
 	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
@@ -75,19 +77,19 @@ var _ = Describe("HttpJsonHandler", func() {
 		})
 
 		JustBeforeEach(func() {
-			handler = NewHTTPJSONHandler("http://example.com", client, fakeLogger, fakeTruncater)
+			handler = NewHTTPJSONHandler([]string{"http://example.com"}, "", 0, client, fakeLogger, fakeTruncater)
 		})
 
 		Context("when the request to the http server fails", func() {
 			It("closes the response body", func() {
 				fakeResponse := &http.Response{StatusCode: 500, Body: fakeBody}
-				client.GetReturns(fakeResponse, nil)
+				client.GetCustomizedReturns(fakeResponse, nil)
 
 				req := &dns.Msg{}
 				SetQuestion(req, nil, "app-id.internal-domain.", dns.TypeA)
 
 				handler.ServeDNS(fakeWriter, req)
-				Expect(client.GetCallCount()).To(Equal(1))
+				Expect(client.GetCustomizedCallCount()).To(Equal(1))
 
 				Expect(fakeBody.Closed).To(Equal(true))
 			})
@@ -96,19 +98,85 @@ var _ = Describe("HttpJsonHandler", func() {
 		Context("when the request is successful", func() {
 			It("closes the response body", func() {
 				fakeResponse := &http.Response{StatusCode: 200, Body: fakeBody}
-				client.GetReturns(fakeResponse, nil)
+				client.GetCustomizedReturns(fakeResponse, nil)
 
 				req := &dns.Msg{}
 				SetQuestion(req, nil, "app-id.internal-domain.", dns.TypeA)
 
 				handler.ServeDNS(fakeWriter, req)
-				Expect(client.GetCallCount()).To(Equal(1))
+				Expect(client.GetCustomizedCallCount()).To(Equal(1))
 
 				Expect(fakeBody.Closed).To(Equal(true))
 			})
 		})
 	})
 
+	Context("with several addresses", func() {
+		var (
+			client    *handlersfakes.FakeHTTPClient
+			requested []string
+		)
+
+		BeforeEach(func() {
+			requested = []string{}
+			client = &handlersfakes.FakeHTTPClient{}
+			client.GetCustomizedStub = func(endpoint string, f func(*http.Request)) (*http.Response, error) {
+				requested = append(requested, endpoint)
+				if strings.HasPrefix(endpoint, "http://healthy.example.com/") {
+					return &http.Response{StatusCode: 200, Body: &closingBuffer{bytes.NewBufferString(`{"Status": 0, "Answer": []}`), false}}, nil
+				}
+				return nil, errors.New("connection refused")
+			}
+		})
+
+		It("fails over to the next address", func() {
+			handler = NewHTTPJSONHandler([]string{"http://down.example.com", "http://healthy.example.com"}, "", 0, client, fakeLogger, fakeTruncater)
+
+			req := &dns.Msg{}
+			req.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, req)
+
+			Expect(requested).To(HaveLen(2))
+			Expect(requested[0]).To(HavePrefix("http://down.example.com/?"))
+			Expect(requested[1]).To(HavePrefix("http://healthy.example.com/?"))
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeSuccess))
+		})
+
+		It("retries every address up to the maximum number of retries", func() {
+			handler = NewHTTPJSONHandler([]string{"http://down.example.com", "http://other-down.example.com"}, "", 2, client, fakeLogger, fakeTruncater)
+
+			req := &dns.Msg{}
+			req.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, req)
+
+			Expect(requested).To(HaveLen(6))
+
+			Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+			Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeServerFailure))
+		})
+	})
+
+	Context("with an authorization", func() {
+		It("sends it with every request", func() {
+			client := &handlersfakes.FakeHTTPClient{}
+			client.GetCustomizedReturns(&http.Response{StatusCode: 200, Body: &closingBuffer{bytes.NewBufferString(`{"Status": 0}`), false}}, nil)
+			handler = NewHTTPJSONHandler([]string{"http://example.com"}, "Bearer some-token", 0, client, fakeLogger, fakeTruncater)
+
+			req := &dns.Msg{}
+			req.SetQuestion("app-id.internal-domain.", dns.TypeA)
+			handler.ServeDNS(fakeWriter, req)
+
+			Expect(client.GetCustomizedCallCount()).To(Equal(1))
+			_, customize := client.GetCustomizedArgsForCall(0)
+
+			request := httptest.NewRequest("GET", "http://example.com", nil)
+			customize(request)
+			Expect(request.Header.Get("Authorization")).To(Equal("Bearer some-token"))
+		})
+	})
+
 	Context("when requesting to a running server", func() {
 		var (
 			server             *ghttp.Server
@@ -120,7 +188,7 @@ var _ = Describe("HttpJsonHandler", func() {
 			server.AppendHandlers(fakeServerResponse)
 			server.HTTPTestServer.Start()
 			httpClient := httpclient.NewHTTPClient(httpclient.DefaultClient, fakeLogger)
-			handler = NewHTTPJSONHandler(server.URL(), httpClient, fakeLogger, fakeTruncater)
+			handler = NewHTTPJSONHandler([]string{server.URL()}, "", 0, httpClient, fakeLogger, fakeTruncater)
 		})
 
 		AfterEach(func() {
@@ -190,14 +258,14 @@ var _ = Describe("HttpJsonHandler", func() {
 					A: net.ParseIP("192.168.0.1"),
 				}))
 
-				Expect(resp.Answer[1]).To(Equal(&dns.A{
+				Expect(resp.Answer[1]).To(Equal(&dns.AAAA{
 					Hdr: dns.RR_Header{
 						Name:   casedName,
 						Rrtype: dns.TypeAAAA,
 						Class:  dns.ClassINET,
 						Ttl:    224,
 					},
-					A: net.ParseIP("::1"),
+					AAAA: net.ParseIP("::1"),
 				}))
 			})
 
@@ -214,10 +282,103 @@ var _ = Describe("HttpJsonHandler", func() {
 			})
 		})
 
+		Context("when the backend server answers with other record types", func() {
+			BeforeEach(func() {
+				fakeServerResponse = ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/", "name=app-id.internal-domain.&type=255"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"Status": 0,
+						"Answer":
+						[
+							{ "name": "app-id.internal-domain.", "type": 5, "TTL": 60, "data": "app.internal-domain." },
+							{ "name": "app.internal-domain.", "type": 16, "TTL": 60, "data": "\"v=spf1 -all\"" },
+							{ "name": "app.internal-domain.", "type": 16, "TTL": 60, "data": "unquoted text" },
+							{ "name": "_http._tcp.app.internal-domain.", "type": 33, "TTL": 60, "data": "10 5 8080 app.internal-domain." },
+							{ "name": "app.internal-domain.", "type": 15, "TTL": 60, "data": "10 mail.internal-domain." },
+							{ "name": "1.0.168.192.in-addr.arpa.", "type": 12, "TTL": 60, "data": "app.internal-domain." },
+							{ "name": "app.internal-domain.", "type": 28, "TTL": 60, "data": "not-an-ip" }
+						],
+						"Authority":
+						[
+							{ "name": "internal-domain.", "type": 2, "TTL": 300, "data": "ns.internal-domain." }
+						],
+						"Additional":
+						[
+							{ "name": "ns.internal-domain.", "type": 1, "TTL": 300, "data": "10.0.0.53" }
+						]
+					}`))
+			})
+
+			It("builds records of the type given by the backend server", func() {
+				req := &dns.Msg{}
+				req.SetQuestion("app-id.internal-domain.", dns.TypeANY)
+
+				handler.ServeDNS(fakeWriter, req)
+
+				Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+				resp := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+
+				Expect(resp.Answer).To(HaveLen(6))
+				Expect(resp.Answer[0].String()).To(Equal("app-id.internal-domain.\t60\tIN\tCNAME\tapp.internal-domain."))
+				Expect(resp.Answer[1].String()).To(Equal("app.internal-domain.\t60\tIN\tTXT\t\"v=spf1 -all\""))
+				Expect(resp.Answer[2].String()).To(Equal("app.internal-domain.\t60\tIN\tTXT\t\"unquoted text\""))
+				Expect(resp.Answer[3].String()).To(Equal("_http._tcp.app.internal-domain.\t60\tIN\tSRV\t10 5 8080 app.internal-domain."))
+				Expect(resp.Answer[4].String()).To(Equal("app.internal-domain.\t60\tIN\tMX\t10 mail.internal-domain."))
+				Expect(resp.Answer[5].String()).To(Equal("1.0.168.192.in-addr.arpa.\t60\tIN\tPTR\tapp.internal-domain."))
+
+				Expect(resp.Ns).To(HaveLen(1))
+				Expect(resp.Ns[0].String()).To(Equal("internal-domain.\t300\tIN\tNS\tns.internal-domain."))
+
+				Expect(resp.Extra).To(HaveLen(1))
+				Expect(resp.Extra[0].String()).To(Equal("ns.internal-domain.\t300\tIN\tA\t10.0.0.53"))
+			})
+
+			It("logs the records which cannot be parsed", func() {
+				req := &dns.Msg{}
+				req.SetQuestion("app-id.internal-domain.", dns.TypeANY)
+
+				handler.ServeDNS(fakeWriter, req)
+
+				Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+				tag, message, args := fakeLogger.ErrorArgsForCall(0)
+				Expect(tag).To(Equal("HTTPJSONHandler"))
+				Expect(fmt.Sprintf(message, args...)).To(ContainSubstring("failed to parse AAAA record 'not-an-ip' for 'app.internal-domain.'"))
+			})
+		})
+
+		Context("when the backend server answers with a failure status", func() {
+			BeforeEach(func() {
+				fakeServerResponse = ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/", "name=missing.internal-domain.&type=1"),
+					ghttp.RespondWith(http.StatusOK, `{
+						"Status": 3,
+						"Authority":
+						[
+							{ "name": "internal-domain.", "type": 6, "TTL": 300, "data": "ns.internal-domain. hostmaster.internal-domain. 1 3600 600 86400 30" }
+						]
+					}`))
+			})
+
+			It("returns the rcode and authority given by the backend server", func() {
+				req := &dns.Msg{}
+				req.SetQuestion("missing.internal-domain.", dns.TypeA)
+
+				handler.ServeDNS(fakeWriter, req)
+
+				Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+				resp := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(resp.Ns).To(HaveLen(1))
+				Expect(resp.Ns[0].Header().Rrtype).To(Equal(dns.TypeSOA))
+			})
+		})
+
 		Context("when it cannot reach the http server", func() {
 			JustBeforeEach(func() {
 				httpClient := httpclient.NewHTTPClient(httpclient.DefaultClient, fakeLogger)
-				handler = NewHTTPJSONHandler("bogus-address", httpClient, fakeLogger, fakeTruncater)
+				handler = NewHTTPJSONHandler([]string{"bogus-address"}, "", 0, httpClient, fakeLogger, fakeTruncater)
 			})
 
 			It("logs the error ", func() {
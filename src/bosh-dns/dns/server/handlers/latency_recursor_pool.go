@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	// LatencyReprobeInterval is how long a recursor may go without being
+	// queried before it is queried once alongside the fastest recursor to
+	// refresh its statistics.
+	LatencyReprobeInterval = 30 * time.Second
+
+	// LatencyUnhealthyFailureRate is the failure rate at which a recursor is
+	// ranked behind all recursors which mostly succeed.
+	LatencyUnhealthyFailureRate = 0.5
+
+	latencySmoothingFactor = 0.3
+)
+
+//counterfeiter:generate . RankedRecursorPool
+
+type RankedRecursorPool interface {
+	RecursorPool
+	Rankings() []RecursorRanking
+}
+
+// RecursorRanking describes how a recursor has performed so far. Latency and
+// FailureRate are exponentially weighted moving averages.
+type RecursorRanking struct {
+	Recursor    string
+	Rank        int
+	Latency     time.Duration
+	FailureRate float64
+	Samples     int
+}
+
+type latencyRecursorPool struct {
+	mutex     sync.Mutex
+	recursors []*recursorStats
+	best      string
+
+	clock                 clock.Clock
+	logger                logger.Logger
+	logTag                string
+	recursorRetrySettings recursorRetrySettings
+}
+
+type recursorStats struct {
+	name        string
+	latency     time.Duration
+	failureRate float64
+	samples     int
+	lastAttempt time.Time
+}
+
+// NewLatencyRecursorPool creates a recursor pool which prefers the fastest
+// healthy recursor.
+//
+// Every exchange updates the moving averages of the response time and the
+// failure rate of the recursor. Recursors failing less than
+// LatencyUnhealthyFailureRate are ranked by response time, followed by the
+// remaining recursors ranked by failure rate. Recursors which were never
+// measured rank first so that every recursor gets measured, and a recursor
+// which has not been queried within LatencyReprobeInterval is queried once in
+// parallel with the fastest recursor, so that a recursor which became faster
+// regains its rank without delaying the answer.
+//
+// Each recursor will be queried in order of rank until one succeeds or all
+// recursors were tried.
+func NewLatencyRecursorPool(recursors []string, recursorMaxRetries int, clock clock.Clock, logger logger.Logger) RankedRecursorPool {
+	stats := []*recursorStats{}
+	now := clock.Now()
+
+	for _, name := range recursors {
+		stats = append(stats, &recursorStats{name: name, lastAttempt: now})
+	}
+
+	return &latencyRecursorPool{
+		recursors:             stats,
+		clock:                 clock,
+		logger:                logger,
+		logTag:                "LatencyRecursor",
+		recursorRetrySettings: recursorRetrySettings{maxRetries: recursorMaxRetries},
+	}
+}
+
+func (q *latencyRecursorPool) PerformStrategically(work func(string) error) error {
+	ranked, reprobe := q.attemptOrder()
+
+	if reprobe != nil {
+		if q.race(work, ranked[0], reprobe) == nil {
+			return nil
+		}
+		ranked = ranked[1:]
+	}
+
+	for _, recursor := range ranked {
+		if q.attempt(work, recursor) == nil {
+			return nil
+		}
+	}
+
+	return ErrNoRecursorResponse
+}
+
+func (q *latencyRecursorPool) attempt(work func(string) error, recursor *recursorStats) error {
+	before := q.clock.Now()
+	err := performWithRetryLogic(work, recursor.name, q.recursorRetrySettings.maxRetries, q.logTag, q.logger)
+	q.registerResult(recursor, q.clock.Since(before), err != nil)
+
+	return err
+}
+
+// race performs the work on both recursors at once. The first success wins;
+// the work of the slower recursor still completes in the background.
+func (q *latencyRecursorPool) race(work func(string) error, fastest, reprobe *recursorStats) error {
+	results := make(chan error, 2)
+	for _, recursor := range []*recursorStats{fastest, reprobe} {
+		go func(recursor *recursorStats) {
+			results <- q.attempt(work, recursor)
+		}(recursor)
+	}
+
+	if err := <-results; err == nil {
+		return nil
+	}
+	return <-results
+}
+
+func (q *latencyRecursorPool) Rankings() []RecursorRanking {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	rankings := []RecursorRanking{}
+	for i, recursor := range q.ranked() {
+		rankings = append(rankings, RecursorRanking{
+			Recursor:    recursor.name,
+			Rank:        i + 1,
+			Latency:     recursor.latency,
+			FailureRate: recursor.failureRate,
+			Samples:     recursor.samples,
+		})
+	}
+
+	return rankings
+}
+
+// attemptOrder returns the recursors by rank, apart from a recursor which is
+// due to be probed again alongside the first one.
+func (q *latencyRecursorPool) attemptOrder() ([]*recursorStats, *recursorStats) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	ranked := q.ranked()
+	now := q.clock.Now()
+
+	for i := 1; i < len(ranked); i++ {
+		if now.Sub(ranked[i].lastAttempt) < LatencyReprobeInterval {
+			continue
+		}
+
+		// claim the reprobe so that concurrent exchanges do not repeat it
+		ranked[i].lastAttempt = now
+		reprobe := ranked[i]
+		return append(ranked[:i], ranked[i+1:]...), reprobe
+	}
+
+	return ranked, nil
+}
+
+func (q *latencyRecursorPool) registerResult(recursor *recursorStats, latency time.Duration, wasError bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	failure := 0.0
+	if wasError {
+		failure = 1.0
+	}
+
+	if recursor.samples == 0 {
+		recursor.failureRate = failure
+	} else {
+		recursor.failureRate = latencySmoothingFactor*failure + (1-latencySmoothingFactor)*recursor.failureRate
+	}
+
+	// a failed exchange says little about the response time of a recursor
+	if !wasError {
+		if recursor.latency == 0 {
+			recursor.latency = latency
+		} else {
+			recursor.latency = time.Duration(latencySmoothingFactor*float64(latency) + (1-latencySmoothingFactor)*float64(recursor.latency))
+		}
+	}
+
+	recursor.samples++
+	recursor.lastAttempt = q.clock.Now()
+
+	if ranked := q.ranked(); len(ranked) > 0 && ranked[0].name != q.best {
+		q.best = ranked[0].name
+		q.logger.Info(q.logTag, fmt.Sprintf("preferring recursor: %s (latency %s, failure rate %.2f)\n", q.best, ranked[0].latency, ranked[0].failureRate))
+	}
+}
+
+// ranked must be called with the mutex held.
+func (q *latencyRecursorPool) ranked() []*recursorStats {
+	ranked := make([]*recursorStats, len(q.recursors))
+	copy(ranked, q.recursors)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+
+		aHealthy := a.failureRate < LatencyUnhealthyFailureRate
+		bHealthy := b.failureRate < LatencyUnhealthyFailureRate
+		if aHealthy != bHealthy {
+			return aHealthy
+		}
+
+		if !aHealthy {
+			return a.failureRate < b.failureRate
+		}
+
+		return a.latency < b.latency
+	})
+
+	return ranked
+}
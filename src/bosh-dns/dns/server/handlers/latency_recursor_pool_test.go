@@ -0,0 +1,179 @@
+package handlers_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("LatencyRecursorPool", func() {
+	var (
+		fakeLogger *loggerfakes.FakeLogger
+		fakeClock  *fakeclock.FakeClock
+		pool       RankedRecursorPool
+
+		attemptsMutex sync.Mutex
+		attempts      []string
+		latencies     map[string]time.Duration
+		failing       map[string]bool
+		blocking      map[string]chan struct{}
+	)
+
+	work := func(recursor string) error {
+		attemptsMutex.Lock()
+		attempts = append(attempts, recursor)
+		attemptsMutex.Unlock()
+
+		if release, ok := blocking[recursor]; ok {
+			<-release
+		}
+		fakeClock.Increment(latencies[recursor])
+
+		if failing[recursor] {
+			return errors.New("failed")
+		}
+
+		return nil
+	}
+
+	attempted := func() []string {
+		attemptsMutex.Lock()
+		defer attemptsMutex.Unlock()
+
+		return append([]string{}, attempts...)
+	}
+
+	order := func() []string {
+		rankings := pool.Rankings()
+		recursors := []string{}
+		for _, ranking := range rankings {
+			recursors = append(recursors, ranking.Recursor)
+		}
+		return recursors
+	}
+
+	BeforeEach(func() {
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		attempts = []string{}
+		latencies = map[string]time.Duration{
+			"one":   30 * time.Millisecond,
+			"two":   10 * time.Millisecond,
+			"three": 20 * time.Millisecond,
+		}
+		failing = map[string]bool{}
+		blocking = map[string]chan struct{}{}
+
+		pool = NewLatencyRecursorPool([]string{"one", "two", "three"}, 0, fakeClock, fakeLogger)
+	})
+
+	It("returns an error if there are no recursors configured", func() {
+		pool = NewLatencyRecursorPool([]string{}, 0, fakeClock, fakeLogger)
+		Expect(pool.PerformStrategically(work)).To(MatchError(ErrNoRecursorResponse))
+	})
+
+	It("uses the configured order while nothing is known about the recursors", func() {
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Expect(attempts).To(Equal([]string{"one"}))
+		Expect(order()).To(Equal([]string{"two", "three", "one"}))
+	})
+
+	It("measures every recursor before preferring the fastest", func() {
+		for i := 0; i < 4; i++ {
+			Expect(pool.PerformStrategically(work)).To(Succeed())
+		}
+		Expect(attempts).To(Equal([]string{"one", "two", "three", "two"}))
+		Expect(order()).To(Equal([]string{"two", "three", "one"}))
+
+		Expect(fakeLogger.InfoCallCount()).To(BeNumerically(">", 0))
+		tag, msg, _ := fakeLogger.InfoArgsForCall(fakeLogger.InfoCallCount() - 1)
+		Expect(tag).To(Equal("LatencyRecursor"))
+		Expect(msg).To(ContainSubstring("preferring recursor: two"))
+	})
+
+	It("ranks failing recursors behind healthy ones regardless of latency", func() {
+		latencies["one"] = time.Millisecond
+		failing["one"] = true
+
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Expect(attempts).To(Equal([]string{"one", "two"}))
+
+		rankings := pool.Rankings()
+		Expect(rankings).To(HaveLen(3))
+		Expect(rankings[2].Recursor).To(Equal("one"))
+		Expect(rankings[2].Rank).To(Equal(3))
+		Expect(rankings[2].FailureRate).To(Equal(1.0))
+		Expect(rankings[2].Samples).To(Equal(1))
+	})
+
+	It("reports the moving averages in the rankings", func() {
+		pool = NewLatencyRecursorPool([]string{"one"}, 0, fakeClock, fakeLogger)
+
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		latencies["one"] = 40 * time.Millisecond
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+
+		rankings := pool.Rankings()
+		Expect(rankings).To(HaveLen(1))
+		Expect(rankings[0].Latency).To(BeNumerically("~", 33*time.Millisecond, time.Microsecond))
+		Expect(rankings[0].FailureRate).To(Equal(0.0))
+		Expect(rankings[0].Samples).To(Equal(2))
+	})
+
+	It("probes recursors again alongside the fastest one when they have not been used for a while", func() {
+		failing["one"] = true
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Expect(attempts).To(Equal([]string{"one", "two", "three"}))
+		Expect(order()).To(Equal([]string{"two", "three", "one"}))
+
+		fakeClock.Increment(LatencyReprobeInterval)
+		failing["one"] = false
+		release := make(chan struct{})
+		blocking["three"] = release
+
+		attempts = []string{}
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Eventually(attempted).Should(ConsistOf("two", "three"))
+		close(release)
+
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Eventually(attempted).Should(ConsistOf("two", "three", "two", "one"))
+
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+		Eventually(attempted).Should(ConsistOf("two", "three", "two", "one", "two"))
+	})
+
+	Context("when the fastest recursor fails while another one is probed again", func() {
+		It("tries the remaining recursors", func() {
+			for i := 0; i < 3; i++ {
+				Expect(pool.PerformStrategically(work)).To(Succeed())
+			}
+			Expect(order()).To(Equal([]string{"two", "three", "one"}))
+
+			fakeClock.Increment(LatencyReprobeInterval)
+			failing["two"] = true
+			failing["three"] = true
+
+			attempts = []string{}
+			Expect(pool.PerformStrategically(work)).To(Succeed())
+			Expect(attempted()).To(ConsistOf("two", "three", "one"))
+		})
+	})
+
+	It("returns an error when all recursors fail", func() {
+		failing["one"] = true
+		failing["two"] = true
+		failing["three"] = true
+
+		Expect(pool.PerformStrategically(work)).To(MatchError(ErrNoRecursorResponse))
+		Expect(attempts).To(Equal([]string{"one", "two", "three"}))
+	})
+})
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultPipelineTimeout = 2 * time.Second
+
+	// pipelineIdleTimeout is how long a connection is kept open after
+	// its last query timed out or was answered.
+	pipelineIdleTimeout = 10 * time.Second
+
+	// pipelineMaxTimeouts is the number of consecutive unanswered queries
+	// after which a connection is considered unusable. A single slow answer
+	// must not fail every other query in flight on the same connection.
+	pipelineMaxTimeouts = 3
+)
+
+// errPipelineClosed is a net.Error so that the recursor pool retries and
+// fails over on it like on any other connection failure.
+var errPipelineClosed error = pipelineClosedError{}
+
+// PipeliningExchanger keeps one connection per recursor alive across
+// exchanges and pipelines concurrent queries over it as described in RFC
+// 7766. Queries are sent with an ID that is unique on the connection, and
+// responses are matched back to their query by that ID.
+type PipeliningExchanger struct {
+	client      *dns.Client
+	idleTimeout time.Duration
+
+	mutex sync.Mutex
+	slots map[string]*pipelineSlot
+}
+
+// pipelineSlot holds the connection to one recursor. Dialing a recursor
+// only blocks the queries waiting for that recursor.
+type pipelineSlot struct {
+	mutex sync.Mutex
+	conn  *pipelinedConn
+}
+
+func NewPipeliningExchanger(client *dns.Client, idleTimeout time.Duration) *PipeliningExchanger {
+	return &PipeliningExchanger{
+		client:      client,
+		idleTimeout: idleTimeout,
+		slots:       map[string]*pipelineSlot{},
+	}
+}
+
+func (e *PipeliningExchanger) Exchange(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+	for attempt := 0; ; attempt++ {
+		conn, err := e.connection(recursor)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		answer, rtt, err := conn.exchange(m, e.timeout())
+		if err == errPipelineClosed && attempt == 0 {
+			// the recursor closed an idle connection; retry once on a new one
+			continue
+		}
+
+		return answer, rtt, err
+	}
+}
+
+func (e *PipeliningExchanger) timeout() time.Duration {
+	if e.client.Timeout == 0 {
+		return defaultPipelineTimeout
+	}
+
+	return e.client.Timeout
+}
+
+func (e *PipeliningExchanger) connection(recursor string) (*pipelinedConn, error) {
+	e.mutex.Lock()
+	slot, ok := e.slots[recursor]
+	if !ok {
+		slot = &pipelineSlot{}
+		e.slots[recursor] = slot
+	}
+	e.mutex.Unlock()
+
+	slot.mutex.Lock()
+	defer slot.mutex.Unlock()
+
+	if slot.conn != nil && !slot.conn.isClosed() {
+		return slot.conn, nil
+	}
+
+	dnsConn, err := e.client.Dial(recursor)
+	if err != nil {
+		return nil, err
+	}
+
+	slot.conn = newPipelinedConn(dnsConn, e.timeout()+e.idleTimeout)
+
+	return slot.conn, nil
+}
+
+type pipelinedConn struct {
+	conn *dns.Conn
+
+	// idleTimeout is how long the connection waits for a response after the
+	// last query was written before it is closed
+	idleTimeout time.Duration
+
+	writeMutex sync.Mutex
+
+	mutex    sync.Mutex
+	pending  map[uint16]chan *dns.Msg
+	timeouts int
+	closed   bool
+}
+
+func newPipelinedConn(conn *dns.Conn, idleTimeout time.Duration) *pipelinedConn {
+	c := &pipelinedConn{
+		conn:        conn,
+		idleTimeout: idleTimeout,
+		pending:     map[uint16]chan *dns.Msg{},
+	}
+
+	conn.SetReadDeadline(time.Now().Add(idleTimeout)) //nolint:errcheck
+	go c.readLoop()
+
+	return c
+}
+
+func (c *pipelinedConn) exchange(m *dns.Msg, timeout time.Duration) (*dns.Msg, time.Duration, error) {
+	id, responses, err := c.register()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer c.unregister(id)
+
+	query := m.Copy()
+	query.Id = id
+
+	before := time.Now()
+
+	c.writeMutex.Lock()
+	c.conn.SetWriteDeadline(before.Add(timeout))          //nolint:errcheck
+	c.conn.SetReadDeadline(time.Now().Add(c.idleTimeout)) //nolint:errcheck
+	err = c.conn.WriteMsg(query)
+	c.writeMutex.Unlock()
+
+	if err != nil {
+		c.close()
+		return nil, 0, errPipelineClosed
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case answer, ok := <-responses:
+		if !ok {
+			return nil, 0, errPipelineClosed
+		}
+
+		answer.Id = m.Id
+		return answer, time.Since(before), nil
+	case <-timer.C:
+		c.timedOut()
+		return nil, 0, os.ErrDeadlineExceeded
+	}
+}
+
+// timedOut closes the connection once several queries in a row went
+// unanswered, as the recursor has then most likely stopped reading it.
+func (c *pipelinedConn) timedOut() {
+	c.mutex.Lock()
+	c.timeouts++
+	unusable := c.timeouts >= pipelineMaxTimeouts
+	c.mutex.Unlock()
+
+	if unusable {
+		c.close()
+	}
+}
+
+func (c *pipelinedConn) register() (uint16, chan *dns.Msg, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return 0, nil, errPipelineClosed
+	}
+
+	for {
+		id := uint16(rand.Intn(65536)) //nolint:gosec
+		if _, inUse := c.pending[id]; !inUse {
+			responses := make(chan *dns.Msg, 1)
+			c.pending[id] = responses
+			return id, responses, nil
+		}
+	}
+}
+
+func (c *pipelinedConn) unregister(id uint16) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.pending, id)
+}
+
+func (c *pipelinedConn) readLoop() {
+	for {
+		answer, err := c.conn.ReadMsg()
+		if err != nil {
+			c.close()
+			return
+		}
+
+		c.mutex.Lock()
+		c.timeouts = 0
+		if responses, ok := c.pending[answer.Id]; ok {
+			delete(c.pending, answer.Id)
+			responses <- answer
+		}
+		c.mutex.Unlock()
+	}
+}
+
+func (c *pipelinedConn) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.closed
+}
+
+func (c *pipelinedConn) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	c.closed = true
+	c.conn.Close() //nolint:errcheck
+
+	for id, responses := range c.pending {
+		close(responses)
+		delete(c.pending, id)
+	}
+}
+
+type pipelineClosedError struct{}
+
+func (pipelineClosedError) Error() string   { return "pipelined connection closed" }
+func (pipelineClosedError) Timeout() bool   { return false }
+func (pipelineClosedError) Temporary() bool { return true }
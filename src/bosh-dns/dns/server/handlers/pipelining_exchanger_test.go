@@ -0,0 +1,271 @@
+package handlers_test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("PipeliningExchanger", func() {
+	var (
+		listener    net.Listener
+		connections int32
+		serve       func(*dns.Conn)
+		exchanger   *handlers.PipeliningExchanger
+	)
+
+	answerFor := func(req *dns.Msg) *dns.Msg {
+		m := &dns.Msg{}
+		m.SetReply(req)
+		m.Answer = []dns.RR{&dns.TXT{
+			Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 5},
+			Txt: []string{req.Question[0].Name},
+		}}
+		return m
+	}
+
+	query := func(name string, id uint16) (*dns.Msg, error) {
+		m := &dns.Msg{}
+		m.SetQuestion(name, dns.TypeTXT)
+		m.Id = id
+
+		answer, _, err := exchanger.Exchange(m, listener.Addr().String())
+		return answer, err
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		atomic.StoreInt32(&connections, 0)
+		serve = func(conn *dns.Conn) {
+			for {
+				req, err := conn.ReadMsg()
+				if err != nil {
+					return
+				}
+				Expect(conn.WriteMsg(answerFor(req))).To(Succeed())
+			}
+		}
+
+		exchanger = handlers.NewPipeliningExchanger(&dns.Client{Net: "tcp", Timeout: time.Second}, time.Minute)
+	})
+
+	JustBeforeEach(func() {
+		listener, serve := listener, serve
+		connections := &connections
+
+		go func() {
+			defer GinkgoRecover()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				atomic.AddInt32(connections, 1)
+				go func() {
+					defer GinkgoRecover()
+					defer conn.Close()
+					serve(&dns.Conn{Conn: conn})
+				}()
+			}
+		}()
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("sends concurrent queries over a single connection", func() {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				name := dns.Fqdn(string(rune('a'+i%26)) + ".example.com")
+				answer, err := query(name, 42)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(answer.Id).To(Equal(uint16(42)))
+				Expect(answer.Answer[0].(*dns.TXT).Txt).To(Equal([]string{name}))
+			}(i)
+		}
+		wg.Wait()
+
+		Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+	})
+
+	Context("when the recursor answers out of order", func() {
+		BeforeEach(func() {
+			serve = func(conn *dns.Conn) {
+				first, err := conn.ReadMsg()
+				Expect(err).NotTo(HaveOccurred())
+				second, err := conn.ReadMsg()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(conn.WriteMsg(answerFor(second))).To(Succeed())
+				Expect(conn.WriteMsg(answerFor(first))).To(Succeed())
+			}
+		})
+
+		It("matches answers to their queries", func() {
+			answers := make(chan *dns.Msg, 2)
+			for _, name := range []string{"first.example.com.", "second.example.com."} {
+				go func(name string) {
+					defer GinkgoRecover()
+					answer, err := query(name, 7)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(answer.Question[0].Name).To(Equal(name))
+					answers <- answer
+				}(name)
+			}
+
+			Eventually(answers).Should(Receive())
+			Eventually(answers).Should(Receive())
+		})
+	})
+
+	Context("when the recursor closes the connection", func() {
+		BeforeEach(func() {
+			serve = func(conn *dns.Conn) {
+				req, err := conn.ReadMsg()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(conn.WriteMsg(answerFor(req))).To(Succeed())
+			}
+		})
+
+		It("reconnects for the next query", func() {
+			_, err := query("first.example.com.", 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() error {
+				_, err := query("second.example.com.", 2)
+				return err
+			}).Should(Succeed())
+
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(2)))
+		})
+	})
+
+	Context("when the recursor does not answer", func() {
+		BeforeEach(func() {
+			serve = func(conn *dns.Conn) {
+				for {
+					if _, err := conn.ReadMsg(); err != nil {
+						return
+					}
+				}
+			}
+			exchanger = handlers.NewPipeliningExchanger(&dns.Client{Net: "tcp", Timeout: 50 * time.Millisecond}, time.Minute)
+		})
+
+		It("returns a timeout error", func() {
+			_, err := query("example.com.", 1)
+
+			var netErr net.Error
+			Expect(errors.As(err, &netErr)).To(BeTrue())
+			Expect(netErr.Timeout()).To(BeTrue())
+		})
+
+		It("reconnects after several queries in a row timed out", func() {
+			for i := 0; i < 3; i++ {
+				_, err := query("example.com.", 1)
+				Expect(err).To(HaveOccurred())
+			}
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+
+			_, err := query("example.com.", 1)
+			Expect(err).To(HaveOccurred())
+			Eventually(func() int32 { return atomic.LoadInt32(&connections) }).Should(Equal(int32(2)))
+		})
+	})
+
+	Context("when the recursor does not answer one of the queries", func() {
+		BeforeEach(func() {
+			serve = func(conn *dns.Conn) {
+				for {
+					req, err := conn.ReadMsg()
+					if err != nil {
+						return
+					}
+					if req.Question[0].Name != "slow.example.com." {
+						Expect(conn.WriteMsg(answerFor(req))).To(Succeed())
+					}
+				}
+			}
+			exchanger = handlers.NewPipeliningExchanger(&dns.Client{Net: "tcp", Timeout: 100 * time.Millisecond}, time.Minute)
+		})
+
+		It("keeps answering the other queries on the same connection", func() {
+			slow := make(chan error, 1)
+			go func() {
+				_, err := query("slow.example.com.", 1)
+				slow <- err
+			}()
+
+			for i := 0; i < 5; i++ {
+				_, err := query("fast.example.com.", 2)
+				Expect(err).NotTo(HaveOccurred())
+				time.Sleep(30 * time.Millisecond)
+			}
+
+			Eventually(slow).Should(Receive(HaveOccurred()))
+
+			_, err := query("fast.example.com.", 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+		})
+	})
+
+	Context("when the connection is idle", func() {
+		var closed chan struct{}
+
+		BeforeEach(func() {
+			closed = make(chan struct{}, 2)
+			serve = func(conn *dns.Conn) {
+				defer func() { closed <- struct{}{} }()
+				for {
+					req, err := conn.ReadMsg()
+					if err != nil {
+						return
+					}
+					Expect(conn.WriteMsg(answerFor(req))).To(Succeed())
+				}
+			}
+			exchanger = handlers.NewPipeliningExchanger(&dns.Client{Net: "tcp", Timeout: 50 * time.Millisecond}, 100*time.Millisecond)
+		})
+
+		It("closes it", func() {
+			_, err := query("example.com.", 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			Consistently(closed, 100*time.Millisecond).ShouldNot(Receive())
+			Eventually(closed).Should(Receive())
+
+			_, err = query("example.com.", 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(atomic.LoadInt32(&connections)).To(Equal(int32(2)))
+		})
+	})
+
+	Context("when the recursor cannot be reached", func() {
+		BeforeEach(func() {
+			listener.Close()
+		})
+
+		It("returns the dial error", func() {
+			_, err := query("example.com.", 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/dnsresolver"
+)
+
+const (
+	// policyTTL applies to the addresses of sinkholed names
+	policyTTL = 60
+
+	PassthruPolicyAction = "passthru"
+	DropPolicyAction     = "drop"
+
+	// policyHitLogTag is the tag of the log lines of each blocked query, so
+	// that their level can be configured apart from other logs
+	policyHitLogTag = "PolicyHit"
+)
+
+// PolicyList is a block or allow list. RPZ lists are response policy zones
+// in the RFC 1035 master file format, whose QNAME triggers are applied.
+// Other lists hold one domain per line, which applies to its subdomains as
+// well; lines starting with @@ allow a domain, and lines in the hosts format
+// are accepted as well.
+type PolicyList struct {
+	Name       string
+	RPZ        bool
+	FileReader records.FileReader
+}
+
+// PolicyHit counts the queries a list applied an action to.
+type PolicyHit struct {
+	List   string
+	Action string
+	Count  uint64
+}
+
+type policyRule struct {
+	list      string
+	action    string
+	addresses []net.IP
+}
+
+type policyRules struct {
+	exact map[string]policyRule
+
+	// subdomains holds the rules applying to the subdomains of names
+	subdomains map[string]policyRule
+}
+
+// Policy holds the rules of block and allow lists, which are parsed again
+// whenever one of them changes. The most specific rule matching a name
+// applies, and allowing a name takes priority over blocking it.
+type Policy struct {
+	lists       []PolicyList
+	action      string
+	sinkholeIPs []net.IP
+	logger      logger.Logger
+	logTag      string
+
+	mutex sync.RWMutex
+	rules policyRules
+
+	// hits has a lock of its own, so counting blocked queries does not
+	// contend with matching the rules
+	hitsMutex sync.Mutex
+	hits      map[PolicyHit]uint64
+}
+
+func NewPolicy(lists []PolicyList, policyConfig config.PolicyConfig, shutdown chan struct{}, logger logger.Logger) *Policy {
+	p := &Policy{
+		lists:  lists,
+		action: policyConfig.Action,
+		logger: logger,
+		logTag: "Policy",
+		hits:   map[PolicyHit]uint64{},
+	}
+
+	if p.action == "" {
+		p.action = config.NXDomainPolicyAction
+	}
+
+	// the configuration has been validated when it was loaded
+	for _, ip := range policyConfig.SinkholeIPs {
+		p.sinkholeIPs = append(p.sinkholeIPs, net.ParseIP(ip))
+	}
+
+	p.load()
+
+	for _, list := range lists {
+		go func(fileReader records.FileReader) {
+			subscriptionChan := fileReader.Subscribe()
+
+			for {
+				select {
+				case <-shutdown:
+					return
+				case ok := <-subscriptionChan:
+					if !ok {
+						return
+					}
+
+					p.load()
+				}
+			}
+		}(list.FileReader)
+	}
+
+	return p
+}
+
+func (p *Policy) load() {
+	rules := policyRules{
+		exact:      map[string]policyRule{},
+		subdomains: map[string]policyRule{},
+	}
+
+	for _, list := range p.lists {
+		contents, err := list.FileReader.Get()
+		if err != nil {
+			p.logger.Error(p.logTag, "Skipping policy list %s: %s", list.Name, err.Error())
+			continue
+		}
+
+		if list.RPZ {
+			err = p.parseRPZ(list.Name, contents, rules)
+		} else {
+			p.parseDomains(list.Name, contents, rules)
+		}
+
+		if err != nil {
+			p.logger.Error(p.logTag, "Skipping policy list %s: %s", list.Name, err.Error())
+		}
+	}
+
+	p.mutex.Lock()
+	p.rules = rules
+	p.mutex.Unlock()
+}
+
+func (p *Policy) parseDomains(list string, contents []byte, rules policyRules) {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if comment := strings.Index(line, "#"); comment >= 0 {
+			line = line[:comment]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// hosts formatted blocklists point the names at an unroutable address
+		if net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+
+		for _, name := range fields {
+			rule := policyRule{list: list, action: p.action}
+			if strings.HasPrefix(name, "@@") {
+				name = strings.TrimPrefix(name, "@@")
+				rule.action = PassthruPolicyAction
+			}
+
+			name = strings.ToLower(dns.Fqdn(name))
+			if _, ok := dns.IsDomainName(name); !ok || name == "." {
+				p.logger.Error(p.logTag, "Skipping invalid name '%s' in policy list %s", name, list)
+				continue
+			}
+
+			rules.add(rules.exact, name, rule)
+			rules.add(rules.subdomains, name, rule)
+		}
+	}
+}
+
+func (p *Policy) parseRPZ(list string, contents []byte, rules policyRules) error {
+	origin := "."
+	triggers := map[string]policyRule{}
+
+	zp := dns.NewZoneParser(bytes.NewReader(contents), ".", "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		name := strings.ToLower(rr.Header().Name)
+
+		switch rr := rr.(type) {
+		case *dns.SOA:
+			origin = name
+		case *dns.NS:
+		case *dns.CNAME:
+			rule := policyRule{list: list}
+			switch strings.ToLower(rr.Target) {
+			case ".":
+				rule.action = config.NXDomainPolicyAction
+			case "*.":
+				rule.action = config.NoDataPolicyAction
+			case "rpz-passthru.":
+				rule.action = PassthruPolicyAction
+			case "rpz-drop.":
+				rule.action = DropPolicyAction
+			default:
+				p.logger.Error(p.logTag, "Skipping unsupported rewrite of %s to %s in policy list %s", rr.Hdr.Name, rr.Target, list)
+				continue
+			}
+			triggers[name] = rule
+		case *dns.A:
+			rule := triggers[name]
+			rule.list, rule.action, rule.addresses = list, config.SinkholePolicyAction, append(rule.addresses, rr.A)
+			triggers[name] = rule
+		case *dns.AAAA:
+			rule := triggers[name]
+			rule.list, rule.action, rule.addresses = list, config.SinkholePolicyAction, append(rule.addresses, rr.AAAA)
+			triggers[name] = rule
+		default:
+			p.logger.Error(p.logTag, "Skipping unsupported %s record of %s in policy list %s", dns.TypeToString[rr.Header().Rrtype], rr.Header().Name, list)
+		}
+	}
+
+	if err := zp.Err(); err != nil {
+		return err
+	}
+
+	for name, rule := range triggers {
+		if !dns.IsSubDomain(origin, name) || name == origin {
+			continue
+		}
+
+		// triggers are named relative to the origin of the zone
+		trigger := strings.TrimSuffix(strings.TrimSuffix(name, origin), ".") + "."
+		if origin == "." {
+			trigger = name
+		}
+
+		if strings.HasPrefix(trigger, "*.") {
+			rules.add(rules.subdomains, strings.TrimPrefix(trigger, "*."), rule)
+		} else {
+			rules.add(rules.exact, trigger, rule)
+		}
+	}
+
+	return nil
+}
+
+// add keeps a rule which allows the name over one which blocks it.
+func (r policyRules) add(rules map[string]policyRule, name string, rule policyRule) {
+	if existing, found := rules[name]; found && existing.action == PassthruPolicyAction {
+		return
+	}
+	rules[name] = rule
+}
+
+// match returns the most specific rule applying to a name, if any.
+func (p *Policy) match(name string) (policyRule, bool) {
+	name = strings.ToLower(name)
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if rule, found := p.rules.exact[name]; found {
+		return rule, true
+	}
+
+	for _, offset := range dns.Split(name)[1:] {
+		if rule, found := p.rules.subdomains[name[offset:]]; found {
+			return rule, true
+		}
+	}
+
+	return policyRule{}, false
+}
+
+func (p *Policy) countHit(rule policyRule) {
+	p.hitsMutex.Lock()
+	p.hits[PolicyHit{List: rule.list, Action: rule.action}]++
+	p.hitsMutex.Unlock()
+}
+
+// Hits returns how many queries each list applied an action to.
+func (p *Policy) Hits() []PolicyHit {
+	hits := []PolicyHit{}
+
+	p.hitsMutex.Lock()
+	for hit, count := range p.hits {
+		hit.Count = count
+		hits = append(hits, hit)
+	}
+	p.hitsMutex.Unlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].List != hits[j].List {
+			return hits[i].List < hits[j].List
+		}
+		return hits[i].Action < hits[j].Action
+	})
+
+	return hits
+}
+
+// PolicyHandler answers queries for the names blocked by a Policy according
+// to their action, and passes any other query on to the next handler.
+type PolicyHandler struct {
+	policy    *Policy
+	next      dns.Handler
+	logger    logger.Logger
+	logTag    string
+	truncater dnsresolver.ResponseTruncater
+}
+
+func NewPolicyHandler(policy *Policy, next dns.Handler, logger logger.Logger, truncater dnsresolver.ResponseTruncater) PolicyHandler {
+	return PolicyHandler{
+		policy:    policy,
+		next:      next,
+		logger:    logger,
+		logTag:    "PolicyHandler",
+		truncater: truncater,
+	}
+}
+
+func (h PolicyHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns.Msg) {
+	if len(request.Question) == 0 {
+		h.next.ServeDNS(responseWriter, request)
+		return
+	}
+
+	question := request.Question[0]
+	rule, found := h.policy.match(question.Name)
+	if !found || rule.action == PassthruPolicyAction {
+		h.next.ServeDNS(responseWriter, request)
+		return
+	}
+
+	h.policy.countHit(rule)
+	h.logger.Info(policyHitLogTag, "%s %s %s from %s by %s",
+		rule.action, question.Name, dns.TypeToString[question.Qtype], remoteAddress(responseWriter), rule.list)
+
+	if rule.action == DropPolicyAction {
+		return
+	}
+
+	responseMsg := &dns.Msg{}
+	responseMsg.SetReply(request)
+	responseMsg.RecursionAvailable = true
+
+	switch rule.action {
+	case config.NXDomainPolicyAction:
+		responseMsg.Rcode = dns.RcodeNameError
+	case config.SinkholePolicyAction:
+		addresses := rule.addresses
+		if len(addresses) == 0 {
+			addresses = h.policy.sinkholeIPs
+		}
+		responseMsg.Answer = sinkholeRecords(question, addresses)
+	}
+
+	h.truncater.TruncateIfNeeded(responseWriter, request, responseMsg)
+
+	if err := responseWriter.WriteMsg(responseMsg); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+func sinkholeRecords(question dns.Question, addresses []net.IP) []dns.RR {
+	var rrs []dns.RR
+	for _, ip := range addresses {
+		header := dns.RR_Header{Name: question.Name, Class: dns.ClassINET, Ttl: policyTTL}
+
+		if ip4 := ip.To4(); ip4 != nil && (question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY) {
+			header.Rrtype = dns.TypeA
+			rrs = append(rrs, &dns.A{Hdr: header, A: ip4})
+		} else if ip4 == nil && (question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY) {
+			header.Rrtype = dns.TypeAAAA
+			rrs = append(rrs, &dns.AAAA{Hdr: header, AAAA: ip})
+		}
+	}
+	return rrs
+}
+
+func remoteAddress(responseWriter dns.ResponseWriter) string {
+	if addr := responseWriter.RemoteAddr(); addr != nil {
+		return addr.String()
+	}
+	return "unknown"
+}
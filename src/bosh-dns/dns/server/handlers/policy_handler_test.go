@@ -0,0 +1,344 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
+	"bosh-dns/dns/server/records/recordsfakes"
+)
+
+var _ = Describe("PolicyHandler", func() {
+	var (
+		policyHandler handlers.PolicyHandler
+		policy        *handlers.Policy
+		policyConfig  config.PolicyConfig
+		domainsReader *recordsfakes.FakeFileReader
+		rpzReader     *recordsfakes.FakeFileReader
+		fakeNext      *handlersfakes.FakeDNSHandler
+		fakeWriter    *internalfakes.FakeResponseWriter
+		fakeLogger    *loggerfakes.FakeLogger
+		fakeTruncater *dnsresolverfakes.FakeResponseTruncater
+		subscription  chan bool
+		shutdown      chan struct{}
+	)
+
+	BeforeEach(func() {
+		domainsReader = &recordsfakes.FakeFileReader{}
+		domainsReader.GetReturns([]byte(`# advertising
+ads.example.com
+0.0.0.0 tracker.example.net # hosts format
+@@good.ads.example.com
+bad..name
+`), nil)
+		subscription = make(chan bool)
+		domainsReader.SubscribeReturns(subscription)
+
+		rpzReader = &recordsfakes.FakeFileReader{}
+		rpzReader.GetReturns([]byte(`$TTL 60
+@                      SOA  localhost. root.localhost. 1 3600 600 86400 60
+@                      NS   localhost.
+nxdomain.example.org   CNAME .
+*.nodata.example.org   CNAME *.
+drop.example.org       CNAME rpz-drop.
+ok.example.org         CNAME rpz-passthru.
+*.example.org          CNAME .
+local.example.org      A    192.0.2.1
+local.example.org      AAAA 2001:db8::1
+rewrite.example.org    CNAME www.example.com.
+`), nil)
+
+		fakeNext = &handlersfakes.FakeDNSHandler{}
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 5353})
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
+		shutdown = make(chan struct{})
+
+		policyConfig = config.PolicyConfig{}
+	})
+
+	JustBeforeEach(func() {
+		policy = handlers.NewPolicy([]handlers.PolicyList{
+			{Name: "ads", FileReader: domainsReader},
+			{Name: "policy.rpz", RPZ: true, FileReader: rpzReader},
+		}, policyConfig, shutdown, fakeLogger)
+		policyHandler = handlers.NewPolicyHandler(policy, fakeNext, fakeLogger, fakeTruncater)
+	})
+
+	AfterEach(func() {
+		close(shutdown)
+	})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := &dns.Msg{}
+		req.SetQuestion(name, qtype)
+
+		callCount := fakeWriter.WriteMsgCallCount()
+		policyHandler.ServeDNS(fakeWriter, req)
+		if fakeWriter.WriteMsgCallCount() == callCount {
+			return nil
+		}
+
+		resp := fakeWriter.WriteMsgArgsForCall(callCount)
+		Expect(resp.Id).To(Equal(req.Id))
+		Expect(resp.Question).To(Equal(req.Question))
+		return resp
+	}
+
+	records := func(rrs []dns.RR) []string {
+		strs := []string{}
+		for _, rr := range rrs {
+			strs = append(strs, rr.String())
+		}
+		return strs
+	}
+
+	Context("plain domain lists", func() {
+		It("answers NXDOMAIN for blocked names and their subdomains", func() {
+			for _, name := range []string{"ads.example.com.", "cdn.ADS.example.com.", "tracker.example.net."} {
+				resp := query(name, dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeNameError), name)
+				Expect(resp.RecursionAvailable).To(BeTrue())
+				Expect(resp.Answer).To(BeEmpty())
+			}
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(0))
+		})
+
+		It("passes allowed names on to the next handler", func() {
+			Expect(query("www.good.ads.example.com.", dns.TypeA)).To(BeNil())
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+			writer, req := fakeNext.ServeDNSArgsForCall(0)
+			Expect(writer).To(Equal(fakeWriter))
+			Expect(req.Question[0].Name).To(Equal("www.good.ads.example.com."))
+		})
+
+		It("passes names which are not listed on to the next handler", func() {
+			Expect(query("example.com.", dns.TypeA)).To(BeNil())
+			Expect(query("notads.example.com.", dns.TypeA)).To(BeNil())
+
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(2))
+		})
+
+		It("logs invalid names", func() {
+			tag, template, args := fakeLogger.ErrorArgsForCall(0)
+			Expect(tag).To(Equal("Policy"))
+			Expect(fmt.Sprintf(template, args...)).To(Equal("Skipping invalid name 'bad..name.' in policy list ads"))
+		})
+
+		Context("when the action is nodata", func() {
+			BeforeEach(func() {
+				policyConfig.Action = config.NoDataPolicyAction
+			})
+
+			It("answers without records", func() {
+				resp := query("ads.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+			})
+		})
+
+		Context("when the action is sinkhole", func() {
+			BeforeEach(func() {
+				policyConfig.Action = config.SinkholePolicyAction
+				policyConfig.SinkholeIPs = []string{"0.0.0.0", "::"}
+			})
+
+			It("answers with the sinkhole address of the query type", func() {
+				Expect(records(query("ads.example.com.", dns.TypeA).Answer)).To(ConsistOf("ads.example.com.\t60\tIN\tA\t0.0.0.0"))
+				Expect(records(query("ads.example.com.", dns.TypeAAAA).Answer)).To(ConsistOf("ads.example.com.\t60\tIN\tAAAA\t::"))
+			})
+
+			It("answers without records for other query types", func() {
+				resp := query("ads.example.com.", dns.TypeMX)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("RPZ lists", func() {
+		It("answers NXDOMAIN for names rewritten to the root", func() {
+			Expect(query("nxdomain.example.org.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+		})
+
+		It("answers without records for subdomains of wildcards rewritten to the wildcard root", func() {
+			resp := query("www.nodata.example.org.", dns.TypeA)
+
+			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(resp.Answer).To(BeEmpty())
+		})
+
+		It("does not apply wildcards to the name itself", func() {
+			Expect(query("example.org.", dns.TypeA)).To(BeNil())
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+		})
+
+		It("prefers the most specific trigger", func() {
+			Expect(query("other.example.org.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+
+			Expect(query("ok.example.org.", dns.TypeA)).To(BeNil())
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(1))
+		})
+
+		It("does not answer dropped names", func() {
+			Expect(query("drop.example.org.", dns.TypeA)).To(BeNil())
+			Expect(fakeNext.ServeDNSCallCount()).To(Equal(0))
+		})
+
+		It("answers with the local data of the list", func() {
+			Expect(records(query("local.example.org.", dns.TypeA).Answer)).To(ConsistOf("local.example.org.\t60\tIN\tA\t192.0.2.1"))
+			Expect(records(query("local.example.org.", dns.TypeANY).Answer)).To(ConsistOf(
+				"local.example.org.\t60\tIN\tA\t192.0.2.1",
+				"local.example.org.\t60\tIN\tAAAA\t2001:db8::1",
+			))
+		})
+
+		It("logs unsupported rules", func() {
+			var messages []string
+			for i := 0; i < fakeLogger.ErrorCallCount(); i++ {
+				_, template, args := fakeLogger.ErrorArgsForCall(i)
+				messages = append(messages, fmt.Sprintf(template, args...))
+			}
+
+			Expect(messages).To(ContainElement("Skipping unsupported rewrite of rewrite.example.org. to www.example.com. in policy list policy.rpz"))
+		})
+
+		Context("when the zone has an origin", func() {
+			BeforeEach(func() {
+				rpzReader.GetReturns([]byte(`$ORIGIN rpz.local.
+$TTL 60
+@                   SOA  localhost. root.localhost. 1 3600 600 86400 60
+blocked.example.io  CNAME .
+`), nil)
+			})
+
+			It("matches the names relative to the origin", func() {
+				Expect(query("blocked.example.io.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+			})
+		})
+
+		Context("when the zone cannot be parsed", func() {
+			BeforeEach(func() {
+				rpzReader.GetReturns([]byte("blocked.example.io. IN CNAME"), nil)
+			})
+
+			It("logs the error and applies the other lists", func() {
+				Expect(query("ads.example.com.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+
+				tag, template, args := fakeLogger.ErrorArgsForCall(fakeLogger.ErrorCallCount() - 1)
+				Expect(tag).To(Equal("Policy"))
+				Expect(fmt.Sprintf(template, args...)).To(HavePrefix("Skipping policy list policy.rpz: "))
+			})
+		})
+	})
+
+	It("logs each hit with a dedicated tag", func() {
+		query("ads.example.com.", dns.TypeAAAA)
+
+		Expect(fakeLogger.InfoCallCount()).To(Equal(1))
+		tag, template, args := fakeLogger.InfoArgsForCall(0)
+		Expect(tag).To(Equal("PolicyHit"))
+		Expect(fmt.Sprintf(template, args...)).To(Equal("nxdomain ads.example.com. AAAA from 10.0.0.5:5353 by ads"))
+	})
+
+	It("counts the hits of each list and action", func() {
+		query("ads.example.com.", dns.TypeA)
+		query("tracker.example.net.", dns.TypeA)
+		query("nxdomain.example.org.", dns.TypeA)
+		query("drop.example.org.", dns.TypeA)
+		query("ok.example.org.", dns.TypeA)
+
+		Expect(policy.Hits()).To(Equal([]handlers.PolicyHit{
+			{List: "ads", Action: "nxdomain", Count: 2},
+			{List: "policy.rpz", Action: "drop", Count: 1},
+			{List: "policy.rpz", Action: "nxdomain", Count: 1},
+		}))
+
+		registry := prometheus.NewRegistry()
+		Expect(registry.Register(handlers.NewPolicyHitsCollector(policy))).To(Succeed())
+
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(families).To(HaveLen(1))
+		Expect(families[0].GetName()).To(Equal("boshdns_policy_hits_total"))
+		Expect(families[0].GetType()).To(Equal(dto.MetricType_COUNTER))
+
+		counters := map[string]float64{}
+		for _, metric := range families[0].GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			counters[labels["list"]+" "+labels["action"]] = metric.GetCounter().GetValue()
+		}
+		Expect(counters).To(Equal(map[string]float64{"ads nxdomain": 2, "policy.rpz drop": 1, "policy.rpz nxdomain": 1}))
+	})
+
+	It("truncates the response if needed", func() {
+		resp := query("ads.example.com.", dns.TypeA)
+
+		Expect(fakeTruncater.TruncateIfNeededCallCount()).To(Equal(1))
+		_, _, truncated := fakeTruncater.TruncateIfNeededArgsForCall(0)
+		Expect(truncated).To(Equal(resp))
+	})
+
+	Context("when a list changes", func() {
+		It("applies the new rules", func() {
+			domainsReader.GetReturns([]byte("example.com\n"), nil)
+			subscription <- true
+
+			Eventually(func() *dns.Msg {
+				return query("www.example.com.", dns.TypeA)
+			}).ShouldNot(BeNil())
+
+			Expect(query("tracker.example.net.", dns.TypeA)).To(BeNil())
+		})
+	})
+
+	Context("when a list cannot be read", func() {
+		BeforeEach(func() {
+			domainsReader.GetReturns(nil, errors.New("no such file"))
+		})
+
+		It("applies the other lists and logs the error", func() {
+			Expect(query("ads.example.com.", dns.TypeA)).To(BeNil())
+			Expect(query("nxdomain.example.org.", dns.TypeA).Rcode).To(Equal(dns.RcodeNameError))
+
+			tag, template, args := fakeLogger.ErrorArgsForCall(0)
+			Expect(tag).To(Equal("Policy"))
+			Expect(fmt.Sprintf(template, args...)).To(Equal("Skipping policy list ads: no such file"))
+		})
+	})
+
+	Context("when it cannot write the response message", func() {
+		BeforeEach(func() {
+			fakeWriter.WriteMsgReturns(errors.New("failed to write message"))
+		})
+
+		It("logs the error", func() {
+			query("ads.example.com.", dns.TypeA)
+
+			tag, msg, _ := fakeLogger.ErrorArgsForCall(fakeLogger.ErrorCallCount() - 1)
+			Expect(tag).To(Equal("PolicyHandler"))
+			Expect(msg).To(Equal("failed to write message"))
+		})
+	})
+})
@@ -0,0 +1,32 @@
+package handlers
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PolicyHitsCollector exports how many queries the lists of a Policy applied
+// an action to as prometheus metrics.
+type PolicyHitsCollector struct {
+	policy *Policy
+
+	hitsDesc *prometheus.Desc
+}
+
+func NewPolicyHitsCollector(policy *Policy) *PolicyHitsCollector {
+	return &PolicyHitsCollector{
+		policy: policy,
+		hitsDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("boshdns", "policy", "hits_total"),
+			"The number of queries a policy list applied an action to.",
+			[]string{"list", "action"}, nil,
+		),
+	}
+}
+
+func (c *PolicyHitsCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.hitsDesc
+}
+
+func (c *PolicyHitsCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, hit := range c.policy.Hits() {
+		metrics <- prometheus.MustNewConstMetric(c.hitsDesc, prometheus.CounterValue, float64(hit.Count), hit.List, hit.Action)
+	}
+}
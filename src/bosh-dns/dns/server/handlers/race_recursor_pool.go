@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type raceRecursorPool struct {
+	*smartFailoverRecursorPool
+
+	hedgeDelay time.Duration
+}
+
+// NewRaceRecursorPool creates a recursor pool which hedges slow recursors.
+//
+// The work is started on the preferred recursor. Whenever no recursor has
+// succeeded within `hedgeDelay`, or all recursors tried so far have failed,
+// the work is additionally started on the next recursor, so that several
+// recursors may be working in parallel. The first success wins; the work of
+// slower recursors still completes in the background.
+//
+// Preference starts at the first recursor and shifts like it does for the
+// "smart" selection, so that a stalling recursor stops being queried first.
+func NewRaceRecursorPool(recursors []string, hedgeDelay time.Duration, recursorMaxRetries int, logger logger.Logger) RecursorPool {
+	return &raceRecursorPool{
+		smartFailoverRecursorPool: newSmartFailoverRecursorPool(recursors, recursorRetrySettings{maxRetries: recursorMaxRetries}, logger),
+		hedgeDelay:                hedgeDelay,
+	}
+}
+
+func (q *raceRecursorPool) PerformStrategically(work func(string) error) error {
+	offset := atomic.LoadUint64(&q.preferredRecursorIndex)
+	uintRecursorCount := uint64(len(q.recursors))
+	if uintRecursorCount == 0 {
+		return ErrNoRecursorResponse
+	}
+
+	results := make(chan error, uintRecursorCount)
+	started, failed := uint64(0), uint64(0)
+
+	start := func() {
+		i := started
+		index := int((i + offset) % uintRecursorCount)
+		started++
+
+		go func() {
+			err := performWithRetryLogic(work, q.recursors[index].name, q.recursorRetrySettings.maxRetries, q.logTag, q.logger)
+
+			failures := q.registerResult(index, err != nil)
+			if err != nil && i == 0 && failures >= FailHistoryThreshold {
+				q.shiftPreference()
+			}
+
+			results <- err
+		}()
+	}
+
+	start()
+
+	for {
+		var hedge <-chan time.Time
+		var timer *time.Timer
+		if started < uintRecursorCount {
+			timer = time.NewTimer(q.hedgeDelay)
+			hedge = timer.C
+		}
+
+		select {
+		case err := <-results:
+			if timer != nil {
+				timer.Stop()
+			}
+
+			if err == nil {
+				return nil
+			}
+
+			failed++
+			if failed == uintRecursorCount {
+				return ErrNoRecursorResponse
+			}
+
+			if failed == started {
+				start()
+			}
+		case <-hedge:
+			start()
+		}
+	}
+}
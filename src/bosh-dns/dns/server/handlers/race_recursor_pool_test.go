@@ -0,0 +1,156 @@
+package handlers_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "bosh-dns/dns/server/handlers"
+)
+
+var _ = Describe("RaceRecursorPool", func() {
+	var (
+		fakeLogger *loggerfakes.FakeLogger
+		pool       RecursorPool
+		hedgeDelay time.Duration
+
+		mutex    sync.Mutex
+		attempts map[string]int
+		behavior map[string]func() error
+	)
+
+	work := func(recursor string) error {
+		mutex.Lock()
+		attempts[recursor]++
+		do := behavior[recursor]
+		mutex.Unlock()
+
+		return do()
+	}
+
+	attemptsOf := func(recursor string) int {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return attempts[recursor]
+	}
+
+	succeed := func() error { return nil }
+	fail := func() error { return errors.New("failed") }
+
+	BeforeEach(func() {
+		fakeLogger = &loggerfakes.FakeLogger{}
+		hedgeDelay = 50 * time.Millisecond
+		attempts = map[string]int{}
+		behavior = map[string]func() error{
+			"one":   succeed,
+			"two":   succeed,
+			"three": succeed,
+		}
+	})
+
+	JustBeforeEach(func() {
+		pool = NewRaceRecursorPool([]string{"one", "two", "three"}, hedgeDelay, 0, fakeLogger)
+	})
+
+	It("returns an error if there are no recursors configured", func() {
+		pool = NewRaceRecursorPool([]string{}, hedgeDelay, 0, fakeLogger)
+		Expect(pool.PerformStrategically(func(string) error { return nil })).To(MatchError(ErrNoRecursorResponse))
+	})
+
+	It("only uses the preferred recursor when it answers quickly", func() {
+		Expect(pool.PerformStrategically(work)).To(Succeed())
+
+		Consistently(func() int { return attemptsOf("two") }, 2*hedgeDelay).Should(Equal(0))
+		Expect(attemptsOf("one")).To(Equal(1))
+		Expect(attemptsOf("three")).To(Equal(0))
+	})
+
+	Context("when the preferred recursor stalls", func() {
+		var stall chan struct{}
+
+		BeforeEach(func() {
+			stall = make(chan struct{})
+			stalled := stall
+			behavior["one"] = func() error {
+				<-stalled
+				return fail()
+			}
+		})
+
+		AfterEach(func() {
+			close(stall)
+		})
+
+		It("races the next recursor after the hedge delay", func() {
+			before := time.Now()
+			Expect(pool.PerformStrategically(work)).To(Succeed())
+
+			Expect(time.Since(before)).To(BeNumerically(">=", hedgeDelay))
+			Expect(attemptsOf("one")).To(Equal(1))
+			Expect(attemptsOf("two")).To(Equal(1))
+			Expect(attemptsOf("three")).To(Equal(0))
+		})
+
+		Context("and the next recursor stalls too", func() {
+			BeforeEach(func() {
+				behavior["two"] = behavior["one"]
+			})
+
+			It("keeps adding recursors until one answers", func() {
+				Expect(pool.PerformStrategically(work)).To(Succeed())
+
+				Expect(attemptsOf("one")).To(Equal(1))
+				Expect(attemptsOf("two")).To(Equal(1))
+				Expect(attemptsOf("three")).To(Equal(1))
+			})
+		})
+	})
+
+	Context("when the preferred recursor fails", func() {
+		BeforeEach(func() {
+			hedgeDelay = time.Minute
+			behavior["one"] = fail
+		})
+
+		It("tries the next recursor without waiting for the hedge delay", func() {
+			Expect(pool.PerformStrategically(work)).To(Succeed())
+
+			Expect(attemptsOf("one")).To(Equal(1))
+			Expect(attemptsOf("two")).To(Equal(1))
+		})
+
+		It("shifts preference after repeated failures", func() {
+			for i := 0; i < FailHistoryThreshold; i++ {
+				Expect(pool.PerformStrategically(work)).To(Succeed())
+			}
+
+			Eventually(fakeLogger.InfoCallCount).Should(Equal(2))
+			_, logMsg, _ := fakeLogger.InfoArgsForCall(1)
+			Expect(logMsg).To(ContainSubstring("shifting recursor preference: two\n"))
+
+			Expect(pool.PerformStrategically(work)).To(Succeed())
+			Expect(attemptsOf("one")).To(Equal(FailHistoryThreshold))
+		})
+	})
+
+	Context("when all recursors fail", func() {
+		BeforeEach(func() {
+			behavior["one"] = fail
+			behavior["two"] = fail
+			behavior["three"] = fail
+		})
+
+		It("returns an error", func() {
+			Expect(pool.PerformStrategically(work)).To(MatchError(ErrNoRecursorResponse))
+
+			Expect(attemptsOf("one")).To(Equal(1))
+			Expect(attemptsOf("two")).To(Equal(1))
+			Expect(attemptsOf("three")).To(Equal(1))
+		})
+	})
+})
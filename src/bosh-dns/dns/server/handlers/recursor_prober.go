@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+)
+
+var errRecursorDown = errors.New("recursor is down according to its last probe")
+
+// RecursorStatus is the outcome of the last probe of a recursor. Recursors
+// are considered up until a probe fails.
+type RecursorStatus struct {
+	Recursor  string
+	Up        bool
+	LastProbe time.Time
+	Error     string
+}
+
+// RecursorProber periodically sends a query to every recursor and tracks
+// which of them answer it.
+type RecursorProber struct {
+	recursors []string
+	exchanger Exchanger
+	domain    string
+	queryType uint16
+	interval  time.Duration
+
+	clock  clock.Clock
+	logger logger.Logger
+	logTag string
+
+	mutex    sync.RWMutex
+	statuses map[string]RecursorStatus
+}
+
+func NewRecursorProber(recursors []string, exchanger Exchanger, domain string, queryType uint16, interval time.Duration, clock clock.Clock, logger logger.Logger) *RecursorProber {
+	statuses := map[string]RecursorStatus{}
+	for _, recursor := range recursors {
+		statuses[recursor] = RecursorStatus{Recursor: recursor, Up: true}
+	}
+
+	return &RecursorProber{
+		recursors: recursors,
+		exchanger: exchanger,
+		domain:    domain,
+		queryType: queryType,
+		interval:  interval,
+		clock:     clock,
+		logger:    logger,
+		logTag:    "RecursorProber",
+		statuses:  statuses,
+	}
+}
+
+func (p *RecursorProber) Run(signal <-chan struct{}) {
+	p.ProbeAll()
+
+	timer := p.clock.NewTimer(p.interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			p.ProbeAll()
+			timer.Reset(p.interval)
+		case <-signal:
+			return
+		}
+	}
+}
+
+// ProbeAll probes all recursors in parallel and returns once every probe has
+// completed.
+func (p *RecursorProber) ProbeAll() {
+	wg := sync.WaitGroup{}
+
+	for _, recursor := range p.recursors {
+		wg.Add(1)
+		go func(recursor string) {
+			defer wg.Done()
+			p.probe(recursor)
+		}(recursor)
+	}
+
+	wg.Wait()
+}
+
+func (p *RecursorProber) probe(recursor string) {
+	m := &dns.Msg{}
+	m.SetQuestion(p.domain, p.queryType)
+
+	status := RecursorStatus{Recursor: recursor, Up: true}
+
+	answer, _, err := p.exchanger.Exchange(m, recursor)
+	if err == nil && (answer.Rcode == dns.RcodeServerFailure || answer.Rcode == dns.RcodeRefused) {
+		err = fmt.Errorf("received %s", dns.RcodeToString[answer.Rcode])
+	}
+
+	if err != nil {
+		status.Up = false
+		status.Error = err.Error()
+	}
+
+	p.mutex.Lock()
+	status.LastProbe = p.clock.Now()
+	previous := p.statuses[recursor]
+	p.statuses[recursor] = status
+	p.mutex.Unlock()
+
+	if previous.Up && !status.Up {
+		p.logger.Warn(p.logTag, fmt.Sprintf("marking recursor %s down: %s\n", recursor, status.Error))
+	} else if !previous.Up && status.Up {
+		p.logger.Info(p.logTag, fmt.Sprintf("marking recursor %s up\n", recursor))
+	}
+}
+
+func (p *RecursorProber) IsUp(recursor string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	status, found := p.statuses[recursor]
+	return !found || status.Up
+}
+
+func (p *RecursorProber) anyUp() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for _, status := range p.statuses {
+		if status.Up {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Statuses returns the status of every recursor in the configured order.
+func (p *RecursorProber) Statuses() []RecursorStatus {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	statuses := []RecursorStatus{}
+	for _, recursor := range p.recursors {
+		statuses = append(statuses, p.statuses[recursor])
+	}
+
+	return statuses
+}
+
+type probedRecursorPool struct {
+	pool   RecursorPool
+	prober *RecursorProber
+}
+
+// NewProbedRecursorPool wraps a recursor pool so that recursors which failed
+// their last probe are skipped without being queried. The wrapped pool sees
+// them fail immediately and adjusts its preference as it would for any other
+// failure. When every recursor is down the probes are ignored, so that
+// clients are not refused an answer because of probes alone.
+func NewProbedRecursorPool(pool RecursorPool, prober *RecursorProber) RecursorPool {
+	return &probedRecursorPool{
+		pool:   pool,
+		prober: prober,
+	}
+}
+
+func (q *probedRecursorPool) PerformStrategically(work func(string) error) error {
+	if !q.prober.anyUp() {
+		return q.pool.PerformStrategically(work)
+	}
+
+	return q.pool.PerformStrategically(func(recursor string) error {
+		if !q.prober.IsUp(recursor) {
+			return errRecursorDown
+		}
+
+		return work(recursor)
+	})
+}
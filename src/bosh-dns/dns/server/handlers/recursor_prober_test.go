@@ -0,0 +1,191 @@
+package handlers_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+)
+
+var _ = Describe("RecursorProber", func() {
+	var (
+		fakeLogger    *loggerfakes.FakeLogger
+		fakeClock     *fakeclock.FakeClock
+		fakeExchanger *handlersfakes.FakeExchanger
+		prober        *RecursorProber
+
+		mutex   sync.Mutex
+		rcodes  map[string]int
+		failing map[string]bool
+	)
+
+	BeforeEach(func() {
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeExchanger = &handlersfakes.FakeExchanger{}
+		rcodes = map[string]int{}
+		failing = map[string]bool{}
+
+		rcodes, failing := rcodes, failing
+		fakeExchanger.ExchangeStub = func(m *dns.Msg, recursor string) (*dns.Msg, time.Duration, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if failing[recursor] {
+				return nil, 0, errors.New("i/o timeout")
+			}
+
+			answer := &dns.Msg{}
+			answer.SetRcode(m, rcodes[recursor])
+			return answer, 0, nil
+		}
+
+		prober = NewRecursorProber([]string{"one", "two"}, fakeExchanger, ".", dns.TypeNS, 10*time.Second, fakeClock, fakeLogger)
+	})
+
+	setFailing := func(recursor string, fail bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failing[recursor] = fail
+	}
+
+	It("considers recursors up before they have been probed", func() {
+		Expect(prober.IsUp("one")).To(BeTrue())
+		Expect(prober.Statuses()).To(Equal([]RecursorStatus{
+			{Recursor: "one", Up: true},
+			{Recursor: "two", Up: true},
+		}))
+	})
+
+	It("sends the configured query to every recursor", func() {
+		prober.ProbeAll()
+
+		Expect(fakeExchanger.ExchangeCallCount()).To(Equal(2))
+		recursors := []string{}
+		for i := 0; i < 2; i++ {
+			m, recursor := fakeExchanger.ExchangeArgsForCall(i)
+			Expect(m.Question).To(Equal([]dns.Question{{Name: ".", Qtype: dns.TypeNS, Qclass: dns.ClassINET}}))
+			recursors = append(recursors, recursor)
+		}
+		Expect(recursors).To(ConsistOf("one", "two"))
+	})
+
+	It("marks recursors down which do not answer and up again once they do", func() {
+		setFailing("two", true)
+		prober.ProbeAll()
+
+		Expect(prober.IsUp("one")).To(BeTrue())
+		Expect(prober.IsUp("two")).To(BeFalse())
+		Expect(prober.Statuses()[1]).To(Equal(RecursorStatus{Recursor: "two", Up: false, LastProbe: fakeClock.Now(), Error: "i/o timeout"}))
+
+		Expect(fakeLogger.WarnCallCount()).To(Equal(1))
+		tag, msg, _ := fakeLogger.WarnArgsForCall(0)
+		Expect(tag).To(Equal("RecursorProber"))
+		Expect(msg).To(Equal("marking recursor two down: i/o timeout\n"))
+
+		setFailing("two", false)
+		prober.ProbeAll()
+
+		Expect(prober.IsUp("two")).To(BeTrue())
+		Expect(fakeLogger.InfoCallCount()).To(Equal(1))
+		_, msg, _ = fakeLogger.InfoArgsForCall(0)
+		Expect(msg).To(Equal("marking recursor two up\n"))
+	})
+
+	It("marks recursors down which answer with SERVFAIL or REFUSED", func() {
+		rcodes["one"] = dns.RcodeServerFailure
+		rcodes["two"] = dns.RcodeRefused
+		prober.ProbeAll()
+
+		Expect(prober.IsUp("one")).To(BeFalse())
+		Expect(prober.IsUp("two")).To(BeFalse())
+		Expect(prober.Statuses()[0].Error).To(Equal("received SERVFAIL"))
+	})
+
+	It("considers NXDOMAIN an answer", func() {
+		rcodes["one"] = dns.RcodeNameError
+		prober.ProbeAll()
+
+		Expect(prober.IsUp("one")).To(BeTrue())
+	})
+
+	It("probes periodically until signaled", func() {
+		signal := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			prober.Run(signal)
+			close(done)
+		}()
+
+		Eventually(fakeExchanger.ExchangeCallCount).Should(Equal(2))
+
+		Eventually(fakeClock.WatcherCount).Should(Equal(1))
+		fakeClock.Increment(10 * time.Second)
+		Eventually(fakeExchanger.ExchangeCallCount).Should(Equal(4))
+
+		close(signal)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("exports the status of every recursor as a metric", func() {
+		setFailing("two", true)
+		prober.ProbeAll()
+
+		registry := prometheus.NewRegistry()
+		Expect(registry.Register(NewRecursorStatusCollector(prober))).To(Succeed())
+
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(families).To(HaveLen(1))
+		Expect(families[0].GetName()).To(Equal("boshdns_recursors_up"))
+
+		up := map[string]float64{}
+		for _, metric := range families[0].GetMetric() {
+			up[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+		Expect(up).To(Equal(map[string]float64{"one": 1, "two": 0}))
+	})
+
+	Describe("ProbedRecursorPool", func() {
+		var (
+			pool     RecursorPool
+			attempts []string
+		)
+
+		work := func(recursor string) error {
+			attempts = append(attempts, recursor)
+			return errors.New("failed")
+		}
+
+		BeforeEach(func() {
+			attempts = []string{}
+			pool = NewProbedRecursorPool(NewFailoverRecursorPool([]string{"one", "two"}, "serial", 0, fakeLogger), prober)
+		})
+
+		It("skips recursors which are down", func() {
+			setFailing("one", true)
+			prober.ProbeAll()
+
+			Expect(pool.PerformStrategically(work)).To(MatchError(ErrNoRecursorResponse))
+			Expect(attempts).To(Equal([]string{"two"}))
+		})
+
+		It("queries all recursors when all of them are down", func() {
+			setFailing("one", true)
+			setFailing("two", true)
+			prober.ProbeAll()
+
+			Expect(pool.PerformStrategically(work)).To(MatchError(ErrNoRecursorResponse))
+			Expect(attempts).To(Equal([]string{"one", "two"}))
+		})
+	})
+})
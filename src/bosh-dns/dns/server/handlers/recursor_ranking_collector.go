@@ -0,0 +1,50 @@
+package handlers
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// RecursorRankingCollector exports the rankings of a RankedRecursorPool as
+// prometheus metrics.
+type RecursorRankingCollector struct {
+	pool RankedRecursorPool
+
+	rankDesc        *prometheus.Desc
+	latencyDesc     *prometheus.Desc
+	failureRateDesc *prometheus.Desc
+}
+
+func NewRecursorRankingCollector(pool RankedRecursorPool) *RecursorRankingCollector {
+	labels := []string{"recursor"}
+
+	return &RecursorRankingCollector{
+		pool: pool,
+		rankDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("boshdns", "recursors", "rank"),
+			"The rank of the recursor, starting at 1 for the preferred recursor.",
+			labels, nil,
+		),
+		latencyDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("boshdns", "recursors", "latency_seconds"),
+			"The moving average of the response time of the recursor.",
+			labels, nil,
+		),
+		failureRateDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("boshdns", "recursors", "failure_rate"),
+			"The moving average of the failure rate of the recursor.",
+			labels, nil,
+		),
+	}
+}
+
+func (c *RecursorRankingCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.rankDesc
+	descs <- c.latencyDesc
+	descs <- c.failureRateDesc
+}
+
+func (c *RecursorRankingCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, ranking := range c.pool.Rankings() {
+		metrics <- prometheus.MustNewConstMetric(c.rankDesc, prometheus.GaugeValue, float64(ranking.Rank), ranking.Recursor)
+		metrics <- prometheus.MustNewConstMetric(c.latencyDesc, prometheus.GaugeValue, ranking.Latency.Seconds(), ranking.Recursor)
+		metrics <- prometheus.MustNewConstMetric(c.failureRateDesc, prometheus.GaugeValue, ranking.FailureRate, ranking.Recursor)
+	}
+}
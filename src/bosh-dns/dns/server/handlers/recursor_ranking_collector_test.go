@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	. "bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/handlers/handlersfakes"
+)
+
+var _ = Describe("RecursorRankingCollector", func() {
+	var (
+		fakePool *handlersfakes.FakeRankedRecursorPool
+		registry *prometheus.Registry
+	)
+
+	BeforeEach(func() {
+		fakePool = &handlersfakes.FakeRankedRecursorPool{}
+		fakePool.RankingsReturns([]RecursorRanking{
+			{Recursor: "10.0.0.2:53", Rank: 1, Latency: 5 * time.Millisecond, FailureRate: 0.25, Samples: 4},
+			{Recursor: "10.0.0.1:53", Rank: 2, Latency: time.Second, FailureRate: 0.75, Samples: 2},
+		})
+
+		registry = prometheus.NewRegistry()
+		Expect(registry.Register(NewRecursorRankingCollector(fakePool))).To(Succeed())
+	})
+
+	gauges := func(families []*dto.MetricFamily, name string) map[string]float64 {
+		values := map[string]float64{}
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				values[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+			}
+		}
+		return values
+	}
+
+	It("exports the rankings of the pool", func() {
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(gauges(families, "boshdns_recursors_rank")).To(Equal(map[string]float64{"10.0.0.2:53": 1, "10.0.0.1:53": 2}))
+		Expect(gauges(families, "boshdns_recursors_latency_seconds")).To(Equal(map[string]float64{"10.0.0.2:53": 0.005, "10.0.0.1:53": 1}))
+		Expect(gauges(families, "boshdns_recursors_failure_rate")).To(Equal(map[string]float64{"10.0.0.2:53": 0.25, "10.0.0.1:53": 0.75}))
+	})
+})
@@ -0,0 +1,37 @@
+package handlers
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// RecursorStatusCollector exports the probe results of a RecursorProber as
+// prometheus metrics.
+type RecursorStatusCollector struct {
+	prober *RecursorProber
+
+	upDesc *prometheus.Desc
+}
+
+func NewRecursorStatusCollector(prober *RecursorProber) *RecursorStatusCollector {
+	return &RecursorStatusCollector{
+		prober: prober,
+		upDesc: prometheus.NewDesc(
+			prometheus.BuildFQName("boshdns", "recursors", "up"),
+			"Whether the recursor answered its last probe (1) or not (0).",
+			[]string{"recursor"}, nil,
+		),
+	}
+}
+
+func (c *RecursorStatusCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.upDesc
+}
+
+func (c *RecursorStatusCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, status := range c.prober.Statuses() {
+		up := 0.0
+		if status.Up {
+			up = 1.0
+		}
+
+		metrics <- prometheus.MustNewConstMetric(c.upDesc, prometheus.GaugeValue, up, status.Recursor)
+	}
+}
@@ -12,11 +12,13 @@ var localhostIPv6 = net.ParseIP("::1")
 
 type UpcheckHandler struct {
 	logger logger.Logger
+	ttl    uint32
 }
 
-func NewUpcheckHandler(logger logger.Logger) UpcheckHandler {
+func NewUpcheckHandler(logger logger.Logger, ttl uint32) UpcheckHandler {
 	return UpcheckHandler{
 		logger: logger,
+		ttl:    ttl,
 	}
 }
 
@@ -34,7 +36,7 @@ func (h UpcheckHandler) ServeDNS(resp dns.ResponseWriter, req *dns.Msg) {
 					Name:   req.Question[0].Name,
 					Rrtype: dns.TypeA,
 					Class:  dns.ClassINET,
-					Ttl:    0,
+					Ttl:    h.ttl,
 				},
 				A: localhostIP,
 			})
@@ -46,7 +48,7 @@ func (h UpcheckHandler) ServeDNS(resp dns.ResponseWriter, req *dns.Msg) {
 					Name:   req.Question[0].Name,
 					Rrtype: dns.TypeAAAA,
 					Class:  dns.ClassINET,
-					Ttl:    0,
+					Ttl:    h.ttl,
 				},
 				AAAA: localhostIPv6,
 			})
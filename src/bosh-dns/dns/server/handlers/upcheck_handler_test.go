@@ -23,7 +23,7 @@ var _ = Describe("UpcheckHandler", func() {
 
 	BeforeEach(func() {
 		fakeLogger = &loggerfakes.FakeLogger{}
-		upcheckHandler = handlers.NewUpcheckHandler(fakeLogger)
+		upcheckHandler = handlers.NewUpcheckHandler(fakeLogger, 0)
 		fakeWriter = &internalfakes.FakeResponseWriter{}
 	})
 
@@ -123,6 +123,23 @@ var _ = Describe("UpcheckHandler", func() {
 			})
 		})
 
+		Context("when a TTL is configured", func() {
+			BeforeEach(func() {
+				upcheckHandler = handlers.NewUpcheckHandler(fakeLogger, 30)
+			})
+
+			It("returns answers with the TTL", func() {
+				m := &dns.Msg{}
+				SetQuestion(m, nil, "upcheck.bosh-dns.", dns.TypeANY)
+
+				upcheckHandler.ServeDNS(fakeWriter, m)
+				message := fakeWriter.WriteMsgArgsForCall(0)
+				Expect(len(message.Answer)).To(Equal(2))
+				Expect(message.Answer[0].Header().Ttl).To(Equal(uint32(30)))
+				Expect(message.Answer[1].Header().Ttl).To(Equal(uint32(30)))
+			})
+		})
+
 		Context("when no question", func() {
 			It("returns something", func() {
 				m := &dns.Msg{}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/miekg/dns"
+
+	"bosh-dns/dns/server/records"
+	"bosh-dns/dns/server/records/dnsresolver"
+)
+
+// zoneMaxCNAMEChain limits how many CNAME records are followed within a zone,
+// which also ends CNAME loops.
+const zoneMaxCNAMEChain = 8
+
+// ZoneHandler answers authoritatively for a zone loaded from an RFC 1035
+// master file. The file is parsed again whenever it changes; a file which
+// cannot be parsed is logged and the zone loaded before keeps being served.
+type ZoneHandler struct {
+	origin     string
+	fileReader records.FileReader
+	logger     logger.Logger
+	logTag     string
+	truncater  dnsresolver.ResponseTruncater
+
+	mutex sync.RWMutex
+	zone  *zone
+}
+
+type zone struct {
+	origin string
+	soa    *dns.SOA
+	names  map[string][]dns.RR
+
+	// nonTerminals holds the names which have no records of their own but
+	// subdomains which do
+	nonTerminals map[string]bool
+}
+
+func NewZoneHandler(origin string, fileReader records.FileReader, shutdown chan struct{}, logger logger.Logger, truncater dnsresolver.ResponseTruncater) (*ZoneHandler, error) {
+	h := &ZoneHandler{
+		origin:     strings.ToLower(dns.Fqdn(origin)),
+		fileReader: fileReader,
+		logger:     logger,
+		logTag:     "ZoneHandler",
+		truncater:  truncater,
+	}
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		subscriptionChan := fileReader.Subscribe()
+
+		for {
+			select {
+			case <-shutdown:
+				return
+			case ok := <-subscriptionChan:
+				if !ok {
+					return
+				}
+
+				if err := h.load(); err != nil {
+					h.logger.Error(h.logTag, "Keeping the previous zone %s: %s", h.origin, err.Error())
+				}
+			}
+		}
+	}()
+
+	return h, nil
+}
+
+func (h *ZoneHandler) load() error {
+	contents, err := h.fileReader.Get()
+	if err != nil {
+		return err
+	}
+
+	z, err := parseZone(h.origin, contents)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	h.zone = z
+	h.mutex.Unlock()
+
+	return nil
+}
+
+func (h *ZoneHandler) ServeDNS(responseWriter dns.ResponseWriter, request *dns.Msg) {
+	responseMsg := &dns.Msg{}
+	responseMsg.SetReply(request)
+	responseMsg.Authoritative = true
+	responseMsg.RecursionAvailable = true
+
+	if len(request.Question) > 0 {
+		h.mutex.RLock()
+		z := h.zone
+		h.mutex.RUnlock()
+
+		z.answer(request.Question[0], responseMsg)
+	}
+
+	h.truncater.TruncateIfNeeded(responseWriter, request, responseMsg)
+
+	if err := responseWriter.WriteMsg(responseMsg); err != nil {
+		h.logger.Error(h.logTag, err.Error())
+	}
+}
+
+func parseZone(origin string, contents []byte) (*zone, error) {
+	z := &zone{
+		origin:       origin,
+		names:        map[string][]dns.RR{},
+		nonTerminals: map[string]bool{},
+	}
+
+	zp := dns.NewZoneParser(bytes.NewReader(contents), origin, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		name := strings.ToLower(rr.Header().Name)
+		if !dns.IsSubDomain(origin, name) {
+			return nil, fmt.Errorf("record %s is outside of zone %s", rr.Header().Name, origin)
+		}
+
+		if soa, isSOA := rr.(*dns.SOA); isSOA && name == origin {
+			z.soa = soa
+		}
+
+		z.names[name] = append(z.names[name], rr)
+	}
+
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("parsing zone %s: %s", origin, err.Error())
+	}
+
+	if z.soa == nil {
+		return nil, fmt.Errorf("zone %s has no SOA record", origin)
+	}
+
+	for name := range z.names {
+		for _, offset := range dns.Split(name)[1:] {
+			ancestor := name[offset:]
+			if !dns.IsSubDomain(origin, ancestor) {
+				break
+			}
+
+			if _, found := z.names[ancestor]; !found {
+				z.nonTerminals[ancestor] = true
+			}
+		}
+	}
+
+	return z, nil
+}
+
+func (z *zone) answer(question dns.Question, responseMsg *dns.Msg) {
+	name := question.Name
+
+	for chain := 0; ; chain++ {
+		lowerName := strings.ToLower(name)
+		if !dns.IsSubDomain(z.origin, lowerName) {
+			// the client resolves CNAME targets outside of the zone
+			return
+		}
+
+		if cut := z.delegation(lowerName); cut != "" {
+			responseMsg.Authoritative = false
+			responseMsg.Ns = append(responseMsg.Ns, z.records(cut, cut, dns.TypeNS)...)
+			z.appendAdditional(responseMsg, responseMsg.Ns)
+			return
+		}
+
+		owned, found := z.names[lowerName]
+		if !found {
+			if z.nonTerminals[lowerName] {
+				z.appendSOA(responseMsg)
+				return
+			}
+
+			wildcard := z.wildcard(lowerName)
+			if wildcard == "" {
+				responseMsg.Rcode = dns.RcodeNameError
+				z.appendSOA(responseMsg)
+				return
+			}
+
+			owned = z.names[wildcard]
+		}
+
+		if matching := z.withOwner(filterRecords(owned, question.Qtype), name); len(matching) > 0 {
+			responseMsg.Answer = append(responseMsg.Answer, matching...)
+			z.appendAdditional(responseMsg, matching)
+			return
+		}
+
+		cnames := z.withOwner(filterRecords(owned, dns.TypeCNAME), name)
+		if len(cnames) == 0 || chain >= zoneMaxCNAMEChain {
+			z.appendSOA(responseMsg)
+			return
+		}
+
+		responseMsg.Answer = append(responseMsg.Answer, cnames[0])
+		name = cnames[0].(*dns.CNAME).Target
+	}
+}
+
+// delegation returns the zone cut closest to the origin between the origin
+// and name, if any.
+func (z *zone) delegation(name string) string {
+	offsets := dns.Split(name)
+	for i := len(offsets) - 1; i >= 0; i-- {
+		candidate := name[offsets[i]:]
+		if candidate == z.origin || !dns.IsSubDomain(z.origin, candidate) {
+			continue
+		}
+
+		if len(filterRecords(z.names[candidate], dns.TypeNS)) > 0 {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// wildcard returns the wildcard name at the closest encloser of name, if it
+// exists.
+func (z *zone) wildcard(name string) string {
+	for _, offset := range dns.Split(name)[1:] {
+		encloser := name[offset:]
+		if !dns.IsSubDomain(z.origin, encloser) {
+			return ""
+		}
+
+		if _, found := z.names[encloser]; !found && !z.nonTerminals[encloser] {
+			continue
+		}
+
+		wildcard := "*." + encloser
+		if _, found := z.names[wildcard]; found {
+			return wildcard
+		}
+		return ""
+	}
+
+	return ""
+}
+
+func (z *zone) records(name, owner string, qtype uint16) []dns.RR {
+	return z.withOwner(filterRecords(z.names[name], qtype), owner)
+}
+
+// withOwner copies records, naming them as they were asked for.
+func (z *zone) withOwner(rrs []dns.RR, owner string) []dns.RR {
+	copies := make([]dns.RR, 0, len(rrs))
+	for _, rr := range rrs {
+		rr = dns.Copy(rr)
+		rr.Header().Name = owner
+		copies = append(copies, rr)
+	}
+	return copies
+}
+
+// appendSOA adds the SOA record for negative answers, with the TTL they may
+// be cached for according to RFC 2308.
+func (z *zone) appendSOA(responseMsg *dns.Msg) {
+	soa := dns.Copy(z.soa).(*dns.SOA)
+	if soa.Minttl < soa.Hdr.Ttl {
+		soa.Hdr.Ttl = soa.Minttl
+	}
+	responseMsg.Ns = append(responseMsg.Ns, soa)
+}
+
+// appendAdditional adds the addresses the zone holds for the targets of NS,
+// MX and SRV records.
+func (z *zone) appendAdditional(responseMsg *dns.Msg, rrs []dns.RR) {
+	for _, rr := range rrs {
+		var target string
+		switch rr := rr.(type) {
+		case *dns.NS:
+			target = rr.Ns
+		case *dns.MX:
+			target = rr.Mx
+		case *dns.SRV:
+			target = rr.Target
+		default:
+			continue
+		}
+
+		target = strings.ToLower(target)
+		responseMsg.Extra = append(responseMsg.Extra, z.records(target, target, dns.TypeA)...)
+		responseMsg.Extra = append(responseMsg.Extra, z.records(target, target, dns.TypeAAAA)...)
+	}
+}
+
+func filterRecords(rrs []dns.RR, qtype uint16) []dns.RR {
+	var matching []dns.RR
+	for _, rr := range rrs {
+		if qtype == dns.TypeANY || rr.Header().Rrtype == qtype {
+			matching = append(matching, rr)
+		}
+	}
+	return matching
+}
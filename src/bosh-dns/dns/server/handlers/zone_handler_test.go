@@ -0,0 +1,318 @@
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-utils/logger/loggerfakes"
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/server/handlers"
+	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
+	"bosh-dns/dns/server/records/recordsfakes"
+)
+
+const testZone = `$ORIGIN corp.example.com.
+$TTL 300
+@          IN SOA   ns.corp.example.com. hostmaster.corp.example.com. 1 3600 600 86400 60
+@          IN NS    ns
+ns         IN A     10.0.0.53
+www        IN A     10.0.0.10
+www        IN AAAA  fd00::10
+alias      IN CNAME www
+chain      IN CNAME alias
+external   IN CNAME example.org.
+loop       IN CNAME loop
+@          IN MX    10 mx
+mx         IN A     10.0.0.25
+*.apps     IN A     10.0.1.1
+deep.empty IN A     10.0.0.99
+sub        IN NS    ns.sub
+ns.sub     IN A     10.0.2.53
+`
+
+var _ = Describe("ZoneHandler", func() {
+	var (
+		zoneHandler    *handlers.ZoneHandler
+		fakeFileReader *recordsfakes.FakeFileReader
+		fakeWriter     *internalfakes.FakeResponseWriter
+		fakeLogger     *loggerfakes.FakeLogger
+		fakeTruncater  *dnsresolverfakes.FakeResponseTruncater
+		subscription   chan bool
+		shutdown       chan struct{}
+	)
+
+	BeforeEach(func() {
+		fakeFileReader = &recordsfakes.FakeFileReader{}
+		fakeFileReader.GetReturns([]byte(testZone), nil)
+		subscription = make(chan bool)
+		fakeFileReader.SubscribeReturns(subscription)
+
+		fakeWriter = &internalfakes.FakeResponseWriter{}
+		fakeLogger = &loggerfakes.FakeLogger{}
+		fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
+		shutdown = make(chan struct{})
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		zoneHandler, err = handlers.NewZoneHandler("corp.example.com.", fakeFileReader, shutdown, fakeLogger, fakeTruncater)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		close(shutdown)
+	})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := &dns.Msg{}
+		req.SetQuestion(name, qtype)
+
+		callCount := fakeWriter.WriteMsgCallCount()
+		zoneHandler.ServeDNS(fakeWriter, req)
+		Expect(fakeWriter.WriteMsgCallCount()).To(Equal(callCount + 1))
+
+		resp := fakeWriter.WriteMsgArgsForCall(callCount)
+		Expect(resp.Id).To(Equal(req.Id))
+		Expect(resp.Question).To(Equal(req.Question))
+		return resp
+	}
+
+	records := func(rrs []dns.RR) []string {
+		strs := []string{}
+		for _, rr := range rrs {
+			strs = append(strs, rr.String())
+		}
+		return strs
+	}
+
+	Describe("ServeDNS", func() {
+		It("answers authoritatively with the records of the name", func() {
+			resp := query("www.corp.example.com.", dns.TypeA)
+
+			Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+			Expect(resp.Authoritative).To(BeTrue())
+			Expect(resp.RecursionAvailable).To(BeTrue())
+			Expect(records(resp.Answer)).To(ConsistOf("www.corp.example.com.\t300\tIN\tA\t10.0.0.10"))
+			Expect(resp.Ns).To(BeEmpty())
+		})
+
+		It("keeps the case of the question", func() {
+			resp := query("WwW.Corp.Example.com.", dns.TypeAAAA)
+
+			Expect(records(resp.Answer)).To(ConsistOf("WwW.Corp.Example.com.\t300\tIN\tAAAA\tfd00::10"))
+		})
+
+		It("answers with every record of the name for ANY queries", func() {
+			resp := query("www.corp.example.com.", dns.TypeANY)
+
+			Expect(resp.Answer).To(HaveLen(2))
+		})
+
+		It("truncates the response if needed", func() {
+			resp := query("www.corp.example.com.", dns.TypeA)
+
+			Expect(fakeTruncater.TruncateIfNeededCallCount()).To(Equal(1))
+			writer, _, truncated := fakeTruncater.TruncateIfNeededArgsForCall(0)
+			Expect(writer).To(Equal(fakeWriter))
+			Expect(truncated).To(Equal(resp))
+		})
+
+		It("answers the SOA and NS records of the zone with the addresses of the name servers", func() {
+			resp := query("corp.example.com.", dns.TypeSOA)
+			Expect(records(resp.Answer)).To(ConsistOf("corp.example.com.\t300\tIN\tSOA\tns.corp.example.com. hostmaster.corp.example.com. 1 3600 600 86400 60"))
+
+			resp = query("corp.example.com.", dns.TypeNS)
+			Expect(records(resp.Answer)).To(ConsistOf("corp.example.com.\t300\tIN\tNS\tns.corp.example.com."))
+			Expect(records(resp.Extra)).To(ConsistOf("ns.corp.example.com.\t300\tIN\tA\t10.0.0.53"))
+		})
+
+		It("adds the addresses of MX targets", func() {
+			resp := query("corp.example.com.", dns.TypeMX)
+
+			Expect(records(resp.Answer)).To(ConsistOf("corp.example.com.\t300\tIN\tMX\t10 mx.corp.example.com."))
+			Expect(records(resp.Extra)).To(ConsistOf("mx.corp.example.com.\t300\tIN\tA\t10.0.0.25"))
+		})
+
+		Context("when the name is an alias", func() {
+			It("follows the CNAME records within the zone", func() {
+				resp := query("chain.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(records(resp.Answer)).To(Equal([]string{
+					"chain.corp.example.com.\t300\tIN\tCNAME\talias.corp.example.com.",
+					"alias.corp.example.com.\t300\tIN\tCNAME\twww.corp.example.com.",
+					"www.corp.example.com.\t300\tIN\tA\t10.0.0.10",
+				}))
+			})
+
+			It("answers the CNAME record itself when asked for", func() {
+				resp := query("alias.corp.example.com.", dns.TypeCNAME)
+
+				Expect(records(resp.Answer)).To(Equal([]string{"alias.corp.example.com.\t300\tIN\tCNAME\twww.corp.example.com."}))
+			})
+
+			It("leaves targets outside of the zone to the client", func() {
+				resp := query("external.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(records(resp.Answer)).To(Equal([]string{"external.corp.example.com.\t300\tIN\tCNAME\texample.org."}))
+				Expect(resp.Ns).To(BeEmpty())
+			})
+
+			It("stops following CNAME loops", func() {
+				resp := query("loop.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(HaveLen(8))
+			})
+		})
+
+		Context("when the name matches a wildcard", func() {
+			It("answers with the wildcard records", func() {
+				resp := query("my-app.apps.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(records(resp.Answer)).To(ConsistOf("my-app.apps.corp.example.com.\t300\tIN\tA\t10.0.1.1"))
+			})
+
+			It("answers without records for other types", func() {
+				resp := query("my-app.apps.corp.example.com.", dns.TypeTXT)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(resp.Ns).To(HaveLen(1))
+			})
+		})
+
+		Context("when the name does not exist", func() {
+			It("answers NXDOMAIN with the SOA record for negative caching", func() {
+				resp := query("missing.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(resp.Authoritative).To(BeTrue())
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(records(resp.Ns)).To(ConsistOf("corp.example.com.\t60\tIN\tSOA\tns.corp.example.com. hostmaster.corp.example.com. 1 3600 600 86400 60"))
+			})
+
+			It("answers NXDOMAIN below names without a wildcard", func() {
+				resp := query("missing.www.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+			})
+
+			It("answers NXDOMAIN after following a CNAME record", func() {
+				fakeFileReader.GetReturns([]byte(testZone+"dangling IN CNAME missing\n"), nil)
+				subscription <- true
+
+				Eventually(func() int {
+					return query("dangling.corp.example.com.", dns.TypeA).Rcode
+				}).Should(Equal(dns.RcodeNameError))
+			})
+		})
+
+		Context("when the name has no records of the type", func() {
+			It("answers without records and with the SOA record", func() {
+				resp := query("www.corp.example.com.", dns.TypeTXT)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(records(resp.Ns)).To(ConsistOf("corp.example.com.\t60\tIN\tSOA\tns.corp.example.com. hostmaster.corp.example.com. 1 3600 600 86400 60"))
+			})
+
+			It("answers names which only have subdomains the same way", func() {
+				resp := query("empty.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(resp.Ns).To(HaveLen(1))
+			})
+		})
+
+		Context("when the name is delegated", func() {
+			It("refers to the name servers of the subzone", func() {
+				resp := query("host.sub.corp.example.com.", dns.TypeA)
+
+				Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(resp.Authoritative).To(BeFalse())
+				Expect(resp.Answer).To(BeEmpty())
+				Expect(records(resp.Ns)).To(ConsistOf("sub.corp.example.com.\t300\tIN\tNS\tns.sub.corp.example.com."))
+				Expect(records(resp.Extra)).To(ConsistOf("ns.sub.corp.example.com.\t300\tIN\tA\t10.0.2.53"))
+			})
+		})
+
+		Context("when there are no questions", func() {
+			It("answers successfully", func() {
+				zoneHandler.ServeDNS(fakeWriter, &dns.Msg{})
+
+				Expect(fakeWriter.WriteMsgCallCount()).To(Equal(1))
+				Expect(fakeWriter.WriteMsgArgsForCall(0).Rcode).To(Equal(dns.RcodeSuccess))
+			})
+		})
+
+		Context("when it cannot write the response message", func() {
+			BeforeEach(func() {
+				fakeWriter.WriteMsgReturns(errors.New("failed to write message"))
+			})
+
+			It("logs the error", func() {
+				zoneHandler.ServeDNS(fakeWriter, &dns.Msg{})
+
+				Expect(fakeLogger.ErrorCallCount()).To(Equal(1))
+				tag, msg, _ := fakeLogger.ErrorArgsForCall(0)
+				Expect(tag).To(Equal("ZoneHandler"))
+				Expect(msg).To(Equal("failed to write message"))
+			})
+		})
+	})
+
+	Context("when the zone file changes", func() {
+		It("serves the new zone", func() {
+			fakeFileReader.GetReturns([]byte(testZone+"new IN A 10.0.0.11\n"), nil)
+			subscription <- true
+
+			Eventually(func() []string {
+				return records(query("new.corp.example.com.", dns.TypeA).Answer)
+			}).Should(ConsistOf("new.corp.example.com.\t300\tIN\tA\t10.0.0.11"))
+		})
+
+		Context("and cannot be parsed", func() {
+			It("keeps serving the previous zone and logs the error", func() {
+				fakeFileReader.GetReturns([]byte("www IN A not-an-ip\n"), nil)
+				subscription <- true
+
+				Eventually(fakeLogger.ErrorCallCount).Should(Equal(1))
+				tag, template, args := fakeLogger.ErrorArgsForCall(0)
+				Expect(tag).To(Equal("ZoneHandler"))
+				Expect(fmt.Sprintf(template, args...)).To(ContainSubstring("Keeping the previous zone corp.example.com.: parsing zone corp.example.com.: "))
+
+				Expect(records(query("www.corp.example.com.", dns.TypeA).Answer)).To(ConsistOf("www.corp.example.com.\t300\tIN\tA\t10.0.0.10"))
+			})
+		})
+	})
+
+	Describe("NewZoneHandler", func() {
+		create := func(contents string) error {
+			fakeFileReader.GetReturns([]byte(contents), nil)
+			_, err := handlers.NewZoneHandler("corp.example.com.", fakeFileReader, shutdown, fakeLogger, fakeTruncater)
+			return err
+		}
+
+		It("errors when the file cannot be read", func() {
+			fakeFileReader.GetReturns(nil, errors.New("no such file"))
+			_, err := handlers.NewZoneHandler("corp.example.com.", fakeFileReader, shutdown, fakeLogger, fakeTruncater)
+			Expect(err).To(MatchError("no such file"))
+		})
+
+		It("errors when the zone has no SOA record", func() {
+			Expect(create("$ORIGIN corp.example.com.\nwww 300 IN A 10.0.0.10\n")).To(MatchError("zone corp.example.com. has no SOA record"))
+		})
+
+		It("errors when a record is outside of the zone", func() {
+			Expect(create(testZone + "www.example.org. IN A 10.0.0.10\n")).To(MatchError("record www.example.org. is outside of zone corp.example.com."))
+		})
+	})
+})
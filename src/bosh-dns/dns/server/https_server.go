@@ -0,0 +1,26 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// HTTPSServer runs an HTTPS listener, such as the DNS over HTTPS one, along
+// with the DNS listeners so that it fails startup and shuts down with them.
+type HTTPSServer struct {
+	server *http.Server
+}
+
+func NewHTTPSServer(server *http.Server) HTTPSServer {
+	return HTTPSServer{server: server}
+}
+
+// ListenAndServe serves HTTPS with the certificates of the TLS configuration
+// of the server.
+func (s HTTPSServer) ListenAndServe() error {
+	return s.server.ListenAndServeTLS("", "")
+}
+
+func (s HTTPSServer) ShutdownContext(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,50 @@
+package server_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/internal/testhelpers"
+	"bosh-dns/dns/server"
+)
+
+var _ = Describe("HTTPSServer", func() {
+	var tlsConfig *tls.Config
+
+	BeforeEach(func() {
+		cert, err := tls.LoadX509KeyPair("../api/assets/test_certs/test_server.pem", "../api/assets/test_certs/test_server.key")
+		Expect(err).NotTo(HaveOccurred())
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	})
+
+	It("returns the error of binding its address", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+
+		httpsServer := server.NewHTTPSServer(&http.Server{Addr: listener.Addr().String(), TLSConfig: tlsConfig})
+		Expect(httpsServer.ListenAndServe()).To(MatchError(ContainSubstring("address already in use")))
+	})
+
+	It("stops serving when shut down", func() {
+		port, err := testhelpers.GetFreePort()
+		Expect(err).NotTo(HaveOccurred())
+
+		httpsServer := server.NewHTTPSServer(&http.Server{Addr: fmt.Sprintf("127.0.0.1:%d", port), TLSConfig: tlsConfig})
+
+		served := make(chan error, 1)
+		go func() {
+			served <- httpsServer.ListenAndServe()
+		}()
+		Expect(testhelpers.WaitForListeningTCP(port)).To(Succeed())
+
+		Expect(httpsServer.ShutdownContext(context.Background())).To(Succeed())
+		Eventually(served).Should(Receive(MatchError(http.ErrServerClosed)))
+	})
+})
@@ -19,4 +19,14 @@ type Record struct {
 	AZID          string
 	AgentID       string
 	InstanceIndex string
+	Weight        string
+}
+
+// AZKey identifies the AZ of the record, preferring its AZ ID over its name.
+func (r Record) AZKey() string {
+	if r.AZID != "" {
+		return r.AZID
+	}
+
+	return r.AZ
 }
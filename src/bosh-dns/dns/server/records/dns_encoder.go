@@ -79,6 +79,10 @@ func NewQueryEncoder(d AliasDefinition) *QueryEncoder {
 	q.InitialHealthCheck = d.InitialHealthCheck
 	q.GroupID = d.GroupID
 	q.RootDomain = d.RootDomain
+	q.Port = d.Port
+	q.TTL = d.TTL
+	q.Order = d.Order
+	q.Limit = d.Limit
 	return &q
 }
 
@@ -87,10 +91,27 @@ func (q *QueryEncoder) encode() string {
 	var sb strings.Builder
 	sb.WriteString("q-")
 
+	if q.Limit != 0 {
+		sb.WriteString(fmt.Sprintf("c%d", q.Limit))
+	}
+
 	if q.NumID != "" {
 		sb.WriteString(fmt.Sprintf("m%s", q.NumID))
 	}
 
+	switch q.Order {
+	case "weighted":
+		sb.WriteString("o1")
+	case "az_affine":
+		sb.WriteString("o2")
+	case "sticky":
+		sb.WriteString("o3")
+	}
+
+	if q.Port != 0 {
+		sb.WriteString(fmt.Sprintf("p%d", q.Port))
+	}
+
 	switch q.HealthFilter {
 	case "unhealthy":
 		sb.WriteString("s1")
@@ -98,10 +119,16 @@ func (q *QueryEncoder) encode() string {
 		sb.WriteString("s3")
 	case "all":
 		sb.WriteString("s4")
+	case "local_az":
+		sb.WriteString("s5")
 	default:
 		sb.WriteString("s0")
 	}
 
+	if q.TTL != 0 {
+		sb.WriteString(fmt.Sprintf("t%d", q.TTL))
+	}
+
 	switch q.InitialHealthCheck {
 	case "asynchronous":
 		sb.WriteString("y0")
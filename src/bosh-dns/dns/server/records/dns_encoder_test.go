@@ -135,6 +135,35 @@ var _ = Describe("DNS encoding", func() {
 				})
 			})
 
+			Context("when local_az", func() {
+				BeforeEach(func() {
+					aliasDefinitions = map[string][]records.AliasDefinition{
+						"custom-alias.": []records.AliasDefinition{
+							{
+								GroupID:      "1",
+								RootDomain:   "a2_domain1",
+								HealthFilter: "local_az",
+							},
+						},
+					}
+				})
+
+				It("includes correct s filter", func() {
+					encodedAliases := aliasEncoder.EncodeAliasesIntoQueries(
+						[]record.Record{{
+							GroupIDs: []string{"1"},
+							Domain:   "a2_domain1.",
+						}},
+						aliasDefinitions,
+					)
+					Expect(encodedAliases).To(
+						Equal(
+							map[string][]string{"custom-alias.": {"q-s5.q-g1.a2_domain1."}},
+						),
+					)
+				})
+			})
+
 			Context("when unhealthy", func() {
 				BeforeEach(func() {
 					aliasDefinitions = map[string][]records.AliasDefinition{
@@ -222,6 +251,134 @@ var _ = Describe("DNS encoding", func() {
 			})
 		})
 
+		Context("with port", func() {
+			BeforeEach(func() {
+				aliasDefinitions = map[string][]records.AliasDefinition{
+					"custom-alias.": []records.AliasDefinition{
+						{
+							GroupID:    "1",
+							RootDomain: "a2_domain1",
+							Port:       8443,
+						},
+					},
+				}
+			})
+
+			It("includes correct p filter", func() {
+				encodedAliases := aliasEncoder.EncodeAliasesIntoQueries(
+					[]record.Record{{
+						GroupIDs: []string{"1"},
+						Domain:   "a2_domain1.",
+					}},
+					aliasDefinitions,
+				)
+				Expect(encodedAliases).To(
+					Equal(
+						map[string][]string{"custom-alias.": {"q-p8443s0.q-g1.a2_domain1."}},
+					),
+				)
+			})
+		})
+
+		Context("with limit", func() {
+			BeforeEach(func() {
+				aliasDefinitions = map[string][]records.AliasDefinition{
+					"custom-alias.": []records.AliasDefinition{
+						{
+							GroupID:      "1",
+							RootDomain:   "a2_domain1",
+							Limit:        3,
+							HealthFilter: "healthy",
+						},
+					},
+				}
+			})
+
+			It("includes correct c filter", func() {
+				encodedAliases := aliasEncoder.EncodeAliasesIntoQueries(
+					[]record.Record{{
+						GroupIDs: []string{"1"},
+						Domain:   "a2_domain1.",
+					}},
+					aliasDefinitions,
+				)
+				Expect(encodedAliases).To(
+					Equal(
+						map[string][]string{"custom-alias.": {"q-c3s3.q-g1.a2_domain1."}},
+					),
+				)
+			})
+		})
+
+		Context("with order", func() {
+			BeforeEach(func() {
+				aliasDefinitions = map[string][]records.AliasDefinition{
+					"custom-alias.": []records.AliasDefinition{
+						{
+							GroupID:    "1",
+							RootDomain: "a2_domain1",
+							Order:      "az_affine",
+						},
+						{
+							GroupID:    "2",
+							RootDomain: "a2_domain1",
+							Order:      "weighted",
+						},
+						{
+							GroupID:    "3",
+							RootDomain: "a2_domain1",
+							Order:      "sticky",
+						},
+					},
+				}
+			})
+
+			It("includes correct o filter", func() {
+				encodedAliases := aliasEncoder.EncodeAliasesIntoQueries(
+					[]record.Record{{
+						GroupIDs: []string{"1"},
+						Domain:   "a2_domain1.",
+					}},
+					aliasDefinitions,
+				)
+				Expect(encodedAliases).To(
+					Equal(
+						map[string][]string{"custom-alias.": {"q-o2s0.q-g1.a2_domain1.", "q-o1s0.q-g2.a2_domain1.", "q-o3s0.q-g3.a2_domain1."}},
+					),
+				)
+			})
+		})
+
+		Context("with ttl", func() {
+			BeforeEach(func() {
+				aliasDefinitions = map[string][]records.AliasDefinition{
+					"custom-alias.": []records.AliasDefinition{
+						{
+							GroupID:            "1",
+							RootDomain:         "a2_domain1",
+							TTL:                30,
+							InitialHealthCheck: "synchronous",
+						},
+					},
+				}
+			})
+
+			It("includes correct t filter", func() {
+				encodedAliases := aliasEncoder.EncodeAliasesIntoQueries(
+					[]record.Record{{
+						GroupIDs: []string{"1"},
+						Domain:   "a2_domain1.",
+					}},
+					aliasDefinitions,
+				)
+				Expect(encodedAliases).To(
+					Equal(
+						map[string][]string{"custom-alias.": {"q-s0t30y1.q-g1.a2_domain1."}},
+					),
+				)
+			})
+		})
+
 		Context("with placeholder_type", func() {
 			Context("when uuid", func() {
 				BeforeEach(func() {
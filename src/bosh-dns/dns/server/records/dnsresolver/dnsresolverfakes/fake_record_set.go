@@ -2,43 +2,360 @@
 package dnsresolverfakes
 
 import (
+	"bosh-dns/dns/server/record"
+	"bosh-dns/dns/server/records"
 	"bosh-dns/dns/server/records/dnsresolver"
 	"sync"
 )
 
 type FakeRecordSet struct {
-	ResolveStub        func(domain string) ([]string, error)
+	DomainsStub        func() []string
+	domainsMutex       sync.RWMutex
+	domainsArgsForCall []struct {
+	}
+	domainsReturns struct {
+		result1 []string
+	}
+	domainsReturnsOnCall map[int]struct {
+		result1 []string
+	}
+	ExpandAliasesStub        func(string) []string
+	expandAliasesMutex       sync.RWMutex
+	expandAliasesArgsForCall []struct {
+		arg1 string
+	}
+	expandAliasesReturns struct {
+		result1 []string
+	}
+	expandAliasesReturnsOnCall map[int]struct {
+		result1 []string
+	}
+	LocalAZStub        func() string
+	localAZMutex       sync.RWMutex
+	localAZArgsForCall []struct {
+	}
+	localAZReturns struct {
+		result1 string
+	}
+	localAZReturnsOnCall map[int]struct {
+		result1 string
+	}
+	QueryTTLStub        func(string) (uint32, bool)
+	queryTTLMutex       sync.RWMutex
+	queryTTLArgsForCall []struct {
+		arg1 string
+	}
+	queryTTLReturns struct {
+		result1 uint32
+		result2 bool
+	}
+	queryTTLReturnsOnCall map[int]struct {
+		result1 uint32
+		result2 bool
+	}
+	ResolveStub        func(string) (records.Resolution, error)
 	resolveMutex       sync.RWMutex
 	resolveArgsForCall []struct {
-		domain string
+		arg1 string
 	}
 	resolveReturns struct {
-		result1 []string
+		result1 records.Resolution
 		result2 error
 	}
 	resolveReturnsOnCall map[int]struct {
-		result1 []string
+		result1 records.Resolution
+		result2 error
+	}
+	ResolveRecordsStub        func([]string, bool) ([]record.Record, error)
+	resolveRecordsMutex       sync.RWMutex
+	resolveRecordsArgsForCall []struct {
+		arg1 []string
+		arg2 bool
+	}
+	resolveRecordsReturns struct {
+		result1 []record.Record
+		result2 error
+	}
+	resolveRecordsReturnsOnCall map[int]struct {
+		result1 []record.Record
+		result2 error
+	}
+	ResolveServicesStub        func(string, string) ([]records.ServiceRecord, error)
+	resolveServicesMutex       sync.RWMutex
+	resolveServicesArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	resolveServicesReturns struct {
+		result1 []records.ServiceRecord
+		result2 error
+	}
+	resolveServicesReturnsOnCall map[int]struct {
+		result1 []records.ServiceRecord
 		result2 error
 	}
+	VersionStub        func() uint64
+	versionMutex       sync.RWMutex
+	versionArgsForCall []struct {
+	}
+	versionReturns struct {
+		result1 uint64
+	}
+	versionReturnsOnCall map[int]struct {
+		result1 uint64
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeRecordSet) Resolve(domain string) ([]string, error) {
+func (fake *FakeRecordSet) Domains() []string {
+	fake.domainsMutex.Lock()
+	ret, specificReturn := fake.domainsReturnsOnCall[len(fake.domainsArgsForCall)]
+	fake.domainsArgsForCall = append(fake.domainsArgsForCall, struct {
+	}{})
+	stub := fake.DomainsStub
+	fakeReturns := fake.domainsReturns
+	fake.recordInvocation("Domains", []interface{}{})
+	fake.domainsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecordSet) DomainsCallCount() int {
+	fake.domainsMutex.RLock()
+	defer fake.domainsMutex.RUnlock()
+	return len(fake.domainsArgsForCall)
+}
+
+func (fake *FakeRecordSet) DomainsCalls(stub func() []string) {
+	fake.domainsMutex.Lock()
+	defer fake.domainsMutex.Unlock()
+	fake.DomainsStub = stub
+}
+
+func (fake *FakeRecordSet) DomainsReturns(result1 []string) {
+	fake.domainsMutex.Lock()
+	defer fake.domainsMutex.Unlock()
+	fake.DomainsStub = nil
+	fake.domainsReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) DomainsReturnsOnCall(i int, result1 []string) {
+	fake.domainsMutex.Lock()
+	defer fake.domainsMutex.Unlock()
+	fake.DomainsStub = nil
+	if fake.domainsReturnsOnCall == nil {
+		fake.domainsReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.domainsReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) ExpandAliases(arg1 string) []string {
+	fake.expandAliasesMutex.Lock()
+	ret, specificReturn := fake.expandAliasesReturnsOnCall[len(fake.expandAliasesArgsForCall)]
+	fake.expandAliasesArgsForCall = append(fake.expandAliasesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ExpandAliasesStub
+	fakeReturns := fake.expandAliasesReturns
+	fake.recordInvocation("ExpandAliases", []interface{}{arg1})
+	fake.expandAliasesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecordSet) ExpandAliasesCallCount() int {
+	fake.expandAliasesMutex.RLock()
+	defer fake.expandAliasesMutex.RUnlock()
+	return len(fake.expandAliasesArgsForCall)
+}
+
+func (fake *FakeRecordSet) ExpandAliasesCalls(stub func(string) []string) {
+	fake.expandAliasesMutex.Lock()
+	defer fake.expandAliasesMutex.Unlock()
+	fake.ExpandAliasesStub = stub
+}
+
+func (fake *FakeRecordSet) ExpandAliasesArgsForCall(i int) string {
+	fake.expandAliasesMutex.RLock()
+	defer fake.expandAliasesMutex.RUnlock()
+	argsForCall := fake.expandAliasesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRecordSet) ExpandAliasesReturns(result1 []string) {
+	fake.expandAliasesMutex.Lock()
+	defer fake.expandAliasesMutex.Unlock()
+	fake.ExpandAliasesStub = nil
+	fake.expandAliasesReturns = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) ExpandAliasesReturnsOnCall(i int, result1 []string) {
+	fake.expandAliasesMutex.Lock()
+	defer fake.expandAliasesMutex.Unlock()
+	fake.ExpandAliasesStub = nil
+	if fake.expandAliasesReturnsOnCall == nil {
+		fake.expandAliasesReturnsOnCall = make(map[int]struct {
+			result1 []string
+		})
+	}
+	fake.expandAliasesReturnsOnCall[i] = struct {
+		result1 []string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) LocalAZ() string {
+	fake.localAZMutex.Lock()
+	ret, specificReturn := fake.localAZReturnsOnCall[len(fake.localAZArgsForCall)]
+	fake.localAZArgsForCall = append(fake.localAZArgsForCall, struct {
+	}{})
+	stub := fake.LocalAZStub
+	fakeReturns := fake.localAZReturns
+	fake.recordInvocation("LocalAZ", []interface{}{})
+	fake.localAZMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecordSet) LocalAZCallCount() int {
+	fake.localAZMutex.RLock()
+	defer fake.localAZMutex.RUnlock()
+	return len(fake.localAZArgsForCall)
+}
+
+func (fake *FakeRecordSet) LocalAZCalls(stub func() string) {
+	fake.localAZMutex.Lock()
+	defer fake.localAZMutex.Unlock()
+	fake.LocalAZStub = stub
+}
+
+func (fake *FakeRecordSet) LocalAZReturns(result1 string) {
+	fake.localAZMutex.Lock()
+	defer fake.localAZMutex.Unlock()
+	fake.LocalAZStub = nil
+	fake.localAZReturns = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) LocalAZReturnsOnCall(i int, result1 string) {
+	fake.localAZMutex.Lock()
+	defer fake.localAZMutex.Unlock()
+	fake.LocalAZStub = nil
+	if fake.localAZReturnsOnCall == nil {
+		fake.localAZReturnsOnCall = make(map[int]struct {
+			result1 string
+		})
+	}
+	fake.localAZReturnsOnCall[i] = struct {
+		result1 string
+	}{result1}
+}
+
+func (fake *FakeRecordSet) QueryTTL(arg1 string) (uint32, bool) {
+	fake.queryTTLMutex.Lock()
+	ret, specificReturn := fake.queryTTLReturnsOnCall[len(fake.queryTTLArgsForCall)]
+	fake.queryTTLArgsForCall = append(fake.queryTTLArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.QueryTTLStub
+	fakeReturns := fake.queryTTLReturns
+	fake.recordInvocation("QueryTTL", []interface{}{arg1})
+	fake.queryTTLMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRecordSet) QueryTTLCallCount() int {
+	fake.queryTTLMutex.RLock()
+	defer fake.queryTTLMutex.RUnlock()
+	return len(fake.queryTTLArgsForCall)
+}
+
+func (fake *FakeRecordSet) QueryTTLCalls(stub func(string) (uint32, bool)) {
+	fake.queryTTLMutex.Lock()
+	defer fake.queryTTLMutex.Unlock()
+	fake.QueryTTLStub = stub
+}
+
+func (fake *FakeRecordSet) QueryTTLArgsForCall(i int) string {
+	fake.queryTTLMutex.RLock()
+	defer fake.queryTTLMutex.RUnlock()
+	argsForCall := fake.queryTTLArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRecordSet) QueryTTLReturns(result1 uint32, result2 bool) {
+	fake.queryTTLMutex.Lock()
+	defer fake.queryTTLMutex.Unlock()
+	fake.QueryTTLStub = nil
+	fake.queryTTLReturns = struct {
+		result1 uint32
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) QueryTTLReturnsOnCall(i int, result1 uint32, result2 bool) {
+	fake.queryTTLMutex.Lock()
+	defer fake.queryTTLMutex.Unlock()
+	fake.QueryTTLStub = nil
+	if fake.queryTTLReturnsOnCall == nil {
+		fake.queryTTLReturnsOnCall = make(map[int]struct {
+			result1 uint32
+			result2 bool
+		})
+	}
+	fake.queryTTLReturnsOnCall[i] = struct {
+		result1 uint32
+		result2 bool
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) Resolve(arg1 string) (records.Resolution, error) {
 	fake.resolveMutex.Lock()
 	ret, specificReturn := fake.resolveReturnsOnCall[len(fake.resolveArgsForCall)]
 	fake.resolveArgsForCall = append(fake.resolveArgsForCall, struct {
-		domain string
-	}{domain})
-	fake.recordInvocation("Resolve", []interface{}{domain})
+		arg1 string
+	}{arg1})
+	stub := fake.ResolveStub
+	fakeReturns := fake.resolveReturns
+	fake.recordInvocation("Resolve", []interface{}{arg1})
 	fake.resolveMutex.Unlock()
-	if fake.ResolveStub != nil {
-		return fake.ResolveStub(domain)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.resolveReturns.result1, fake.resolveReturns.result2
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeRecordSet) ResolveCallCount() int {
@@ -47,40 +364,257 @@ func (fake *FakeRecordSet) ResolveCallCount() int {
 	return len(fake.resolveArgsForCall)
 }
 
+func (fake *FakeRecordSet) ResolveCalls(stub func(string) (records.Resolution, error)) {
+	fake.resolveMutex.Lock()
+	defer fake.resolveMutex.Unlock()
+	fake.ResolveStub = stub
+}
+
 func (fake *FakeRecordSet) ResolveArgsForCall(i int) string {
 	fake.resolveMutex.RLock()
 	defer fake.resolveMutex.RUnlock()
-	return fake.resolveArgsForCall[i].domain
+	argsForCall := fake.resolveArgsForCall[i]
+	return argsForCall.arg1
 }
 
-func (fake *FakeRecordSet) ResolveReturns(result1 []string, result2 error) {
+func (fake *FakeRecordSet) ResolveReturns(result1 records.Resolution, result2 error) {
+	fake.resolveMutex.Lock()
+	defer fake.resolveMutex.Unlock()
 	fake.ResolveStub = nil
 	fake.resolveReturns = struct {
-		result1 []string
+		result1 records.Resolution
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeRecordSet) ResolveReturnsOnCall(i int, result1 []string, result2 error) {
+func (fake *FakeRecordSet) ResolveReturnsOnCall(i int, result1 records.Resolution, result2 error) {
+	fake.resolveMutex.Lock()
+	defer fake.resolveMutex.Unlock()
 	fake.ResolveStub = nil
 	if fake.resolveReturnsOnCall == nil {
 		fake.resolveReturnsOnCall = make(map[int]struct {
-			result1 []string
+			result1 records.Resolution
 			result2 error
 		})
 	}
 	fake.resolveReturnsOnCall[i] = struct {
-		result1 []string
+		result1 records.Resolution
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) ResolveRecords(arg1 []string, arg2 bool) ([]record.Record, error) {
+	var arg1Copy []string
+	if arg1 != nil {
+		arg1Copy = make([]string, len(arg1))
+		copy(arg1Copy, arg1)
+	}
+	fake.resolveRecordsMutex.Lock()
+	ret, specificReturn := fake.resolveRecordsReturnsOnCall[len(fake.resolveRecordsArgsForCall)]
+	fake.resolveRecordsArgsForCall = append(fake.resolveRecordsArgsForCall, struct {
+		arg1 []string
+		arg2 bool
+	}{arg1Copy, arg2})
+	stub := fake.ResolveRecordsStub
+	fakeReturns := fake.resolveRecordsReturns
+	fake.recordInvocation("ResolveRecords", []interface{}{arg1Copy, arg2})
+	fake.resolveRecordsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRecordSet) ResolveRecordsCallCount() int {
+	fake.resolveRecordsMutex.RLock()
+	defer fake.resolveRecordsMutex.RUnlock()
+	return len(fake.resolveRecordsArgsForCall)
+}
+
+func (fake *FakeRecordSet) ResolveRecordsCalls(stub func([]string, bool) ([]record.Record, error)) {
+	fake.resolveRecordsMutex.Lock()
+	defer fake.resolveRecordsMutex.Unlock()
+	fake.ResolveRecordsStub = stub
+}
+
+func (fake *FakeRecordSet) ResolveRecordsArgsForCall(i int) ([]string, bool) {
+	fake.resolveRecordsMutex.RLock()
+	defer fake.resolveRecordsMutex.RUnlock()
+	argsForCall := fake.resolveRecordsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRecordSet) ResolveRecordsReturns(result1 []record.Record, result2 error) {
+	fake.resolveRecordsMutex.Lock()
+	defer fake.resolveRecordsMutex.Unlock()
+	fake.ResolveRecordsStub = nil
+	fake.resolveRecordsReturns = struct {
+		result1 []record.Record
 		result2 error
 	}{result1, result2}
 }
 
+func (fake *FakeRecordSet) ResolveRecordsReturnsOnCall(i int, result1 []record.Record, result2 error) {
+	fake.resolveRecordsMutex.Lock()
+	defer fake.resolveRecordsMutex.Unlock()
+	fake.ResolveRecordsStub = nil
+	if fake.resolveRecordsReturnsOnCall == nil {
+		fake.resolveRecordsReturnsOnCall = make(map[int]struct {
+			result1 []record.Record
+			result2 error
+		})
+	}
+	fake.resolveRecordsReturnsOnCall[i] = struct {
+		result1 []record.Record
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) ResolveServices(arg1 string, arg2 string) ([]records.ServiceRecord, error) {
+	fake.resolveServicesMutex.Lock()
+	ret, specificReturn := fake.resolveServicesReturnsOnCall[len(fake.resolveServicesArgsForCall)]
+	fake.resolveServicesArgsForCall = append(fake.resolveServicesArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ResolveServicesStub
+	fakeReturns := fake.resolveServicesReturns
+	fake.recordInvocation("ResolveServices", []interface{}{arg1, arg2})
+	fake.resolveServicesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRecordSet) ResolveServicesCallCount() int {
+	fake.resolveServicesMutex.RLock()
+	defer fake.resolveServicesMutex.RUnlock()
+	return len(fake.resolveServicesArgsForCall)
+}
+
+func (fake *FakeRecordSet) ResolveServicesCalls(stub func(string, string) ([]records.ServiceRecord, error)) {
+	fake.resolveServicesMutex.Lock()
+	defer fake.resolveServicesMutex.Unlock()
+	fake.ResolveServicesStub = stub
+}
+
+func (fake *FakeRecordSet) ResolveServicesArgsForCall(i int) (string, string) {
+	fake.resolveServicesMutex.RLock()
+	defer fake.resolveServicesMutex.RUnlock()
+	argsForCall := fake.resolveServicesArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRecordSet) ResolveServicesReturns(result1 []records.ServiceRecord, result2 error) {
+	fake.resolveServicesMutex.Lock()
+	defer fake.resolveServicesMutex.Unlock()
+	fake.ResolveServicesStub = nil
+	fake.resolveServicesReturns = struct {
+		result1 []records.ServiceRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) ResolveServicesReturnsOnCall(i int, result1 []records.ServiceRecord, result2 error) {
+	fake.resolveServicesMutex.Lock()
+	defer fake.resolveServicesMutex.Unlock()
+	fake.ResolveServicesStub = nil
+	if fake.resolveServicesReturnsOnCall == nil {
+		fake.resolveServicesReturnsOnCall = make(map[int]struct {
+			result1 []records.ServiceRecord
+			result2 error
+		})
+	}
+	fake.resolveServicesReturnsOnCall[i] = struct {
+		result1 []records.ServiceRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeRecordSet) Version() uint64 {
+	fake.versionMutex.Lock()
+	ret, specificReturn := fake.versionReturnsOnCall[len(fake.versionArgsForCall)]
+	fake.versionArgsForCall = append(fake.versionArgsForCall, struct {
+	}{})
+	stub := fake.VersionStub
+	fakeReturns := fake.versionReturns
+	fake.recordInvocation("Version", []interface{}{})
+	fake.versionMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeRecordSet) VersionCallCount() int {
+	fake.versionMutex.RLock()
+	defer fake.versionMutex.RUnlock()
+	return len(fake.versionArgsForCall)
+}
+
+func (fake *FakeRecordSet) VersionCalls(stub func() uint64) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = stub
+}
+
+func (fake *FakeRecordSet) VersionReturns(result1 uint64) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = nil
+	fake.versionReturns = struct {
+		result1 uint64
+	}{result1}
+}
+
+func (fake *FakeRecordSet) VersionReturnsOnCall(i int, result1 uint64) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = nil
+	if fake.versionReturnsOnCall == nil {
+		fake.versionReturnsOnCall = make(map[int]struct {
+			result1 uint64
+		})
+	}
+	fake.versionReturnsOnCall[i] = struct {
+		result1 uint64
+	}{result1}
+}
+
 func (fake *FakeRecordSet) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.domainsMutex.RLock()
+	defer fake.domainsMutex.RUnlock()
+	fake.expandAliasesMutex.RLock()
+	defer fake.expandAliasesMutex.RUnlock()
+	fake.localAZMutex.RLock()
+	defer fake.localAZMutex.RUnlock()
+	fake.queryTTLMutex.RLock()
+	defer fake.queryTTLMutex.RUnlock()
 	fake.resolveMutex.RLock()
 	defer fake.resolveMutex.RUnlock()
-	return fake.invocations
+	fake.resolveRecordsMutex.RLock()
+	defer fake.resolveRecordsMutex.RUnlock()
+	fake.resolveServicesMutex.RLock()
+	defer fake.resolveServicesMutex.RUnlock()
+	fake.versionMutex.RLock()
+	defer fake.versionMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
 }
 
 func (fake *FakeRecordSet) recordInvocation(key string, args []interface{}) {
@@ -2,104 +2,343 @@ package dnsresolver
 
 import (
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/cloudfoundry/bosh-utils/logger"
 	"github.com/miekg/dns"
 
+	"bosh-dns/dns/config"
+	"bosh-dns/dns/server/record"
 	"bosh-dns/dns/server/records"
 )
 
+const (
+	zoneNameserver = "localhost."
+	zoneMailbox    = "nobody.invalid."
+)
+
 type LocalDomain struct {
-	logger    logger.Logger
-	logTag    string
-	recordSet RecordSet
-	truncater ResponseTruncater
+	logger            logger.Logger
+	logTag            string
+	recordSet         RecordSet
+	healthStateGetter HealthStateGetter
+	ttls              config.InternalTTLConfig
+	truncater         ResponseTruncater
 }
 
 //counterfeiter:generate . RecordSet
 
 type RecordSet interface {
-	Resolve(domain string) ([]string, error)
+	Resolve(domain string) (records.Resolution, error)
+	ResolveServices(service, domain string) ([]records.ServiceRecord, error)
+	ResolveRecords(domains []string, shouldTrack bool) ([]record.Record, error)
+	ExpandAliases(fqdn string) []string
+	QueryTTL(fqdn string) (uint32, bool)
+	LocalAZ() string
+	Domains() []string
+	Version() uint64
+}
+
+//counterfeiter:generate . HealthStateGetter
+
+type HealthStateGetter interface {
+	HealthStateString(ip string) string
 }
 
-func NewLocalDomain(logger logger.Logger, recordSet RecordSet, truncater ResponseTruncater) LocalDomain {
+func NewLocalDomain(logger logger.Logger, recordSet RecordSet, healthStateGetter HealthStateGetter, ttls config.InternalTTLConfig, truncater ResponseTruncater) LocalDomain {
 	return LocalDomain{
-		logger:    logger,
-		logTag:    "LocalDomain",
-		recordSet: recordSet,
-		truncater: truncater,
+		logger:            logger,
+		logTag:            "LocalDomain",
+		recordSet:         recordSet,
+		healthStateGetter: healthStateGetter,
+		ttls:              ttls,
+		truncater:         truncater,
 	}
 }
 
 func (d LocalDomain) Resolve(responseWriter dns.ResponseWriter, requestMsg *dns.Msg) *dns.Msg {
-	answers, rCode := d.resolve(requestMsg.Question[0])
+	var answers, extras []dns.RR
+	var rCode int
+
+	question := requestMsg.Question[0]
+	switch question.Qtype {
+	case dns.TypeSRV:
+		answers, extras, rCode = d.resolveServices(question)
+	case dns.TypeTXT:
+		answers, rCode = d.resolveMetadata(question)
+	case dns.TypeSOA, dns.TypeNS:
+		if zone := d.zone(question.Name); zone != "" && zone == strings.ToLower(question.Name) {
+			answers, rCode = d.resolveZone(question, zone)
+		} else {
+			answers, rCode = d.resolve(question, clientIP(responseWriter))
+		}
+	default:
+		answers, rCode = d.resolve(question, clientIP(responseWriter))
+	}
 
 	responseMsg := &dns.Msg{}
 	responseMsg.RecursionAvailable = true
 	responseMsg.Authoritative = true
 	responseMsg.Answer = answers
+	responseMsg.Extra = extras
 	responseMsg.SetRcode(requestMsg, rCode)
 
+	if rCode == dns.RcodeNameError || (rCode == dns.RcodeSuccess && len(answers) == 0) {
+		if zone := d.zone(question.Name); zone != "" {
+			responseMsg.Ns = []dns.RR{d.soa(zone)}
+		}
+	}
+
 	d.truncater.TruncateIfNeeded(responseWriter, requestMsg, responseMsg)
 
 	return responseMsg
 }
 
-func (d LocalDomain) resolve(question dns.Question) ([]dns.RR, int) {
+func (d LocalDomain) resolve(question dns.Question, client string) ([]dns.RR, int) {
 	var lowercaseName = strings.ToLower(question.Name)
 
 	d.logger.Debug(d.logTag, "query lower-cased from '%s' to '%s'", question.Name, lowercaseName)
 
 	answers := []dns.RR{}
 
-	ipStrs, err := d.recordSet.Resolve(lowercaseName)
+	resolution, err := d.recordSet.Resolve(lowercaseName)
 	if err != nil {
 		d.logger.Debug(d.logTag, "failed to get ip addresses: %v", err)
-		if errors.Is(err, records.CriteriaError) {
-			return nil, dns.RcodeFormatError
-		} else if errors.Is(err, records.DomainError) {
-			return nil, dns.RcodeNameError
-		} else {
-			return nil, dns.RcodeServerFailure
+		return nil, rcodeFromError(err)
+	}
+
+	var ipStrs []string
+	order, limit := resolution.Order, resolution.Limit
+	switch order {
+	case records.RandomOrder, records.StickyOrder:
+		ipStrs = ofFamily(resolution.IPs(), question.Qtype)
+		limited := limit > 0 && uint(len(ipStrs)) > limit
+		if order == records.StickyOrder || limited {
+			ipStrs = rendezvousSort(ipStrs, client)
+		}
+		if limited {
+			ipStrs = ipStrs[:limit]
 		}
+	default:
+		ipStrs = d.arrange(resolution, question.Qtype, client)
 	}
 
+	ttl := d.ttls.ForDomain(lowercaseName)
+	if resolution.HasTTL {
+		ttl = resolution.TTL
+	}
 	for _, ipStr := range ipStrs {
-		var answer dns.RR
-
-		ip := net.ParseIP(ipStr)
-
-		if ip.To4() != nil {
-			if question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY {
-				answer = &dns.A{
-					Hdr: dns.RR_Header{
-						Name:   question.Name,
-						Rrtype: dns.TypeA,
-						Class:  dns.ClassINET,
-						Ttl:    0,
-					},
-					A: ip,
-				}
-			}
+		if answer := addressRR(question.Name, question.Qtype, ipStr, ttl); answer != nil {
+			answers = append(answers, answer)
+		}
+	}
+
+	if order == records.RandomOrder {
+		rand.Shuffle(len(answers), func(i, j int) {
+			answers[i], answers[j] = answers[j], answers[i]
+		})
+	}
+
+	return answers, dns.RcodeSuccess
+}
+
+// arrange returns the addresses answering qtype of the instances a name
+// resolves to, arranged as requested by its query. Addresses the name is
+// aliased to directly come last. When limited, the instances ranking highest
+// for the client are kept.
+func (d LocalDomain) arrange(resolution records.Resolution, qtype uint16, client string) []string {
+	order, limit := resolution.Order, resolution.Limit
+
+	aliasIPs := ofFamily(resolution.AliasIPs, qtype)
+	recs := []record.Record{}
+	for _, rec := range resolution.Records {
+		if inFamily(rec.IP, qtype) {
+			recs = append(recs, rec)
+		}
+	}
+
+	if limit > 0 && uint(len(recs)) > limit {
+		recs = rendezvousSortRecords(recs, client)
+		if order == records.AZAffineOrder {
+			recs = d.localAZFirst(recs)
+		}
+		recs = recs[:limit]
+	}
+
+	recs = weightedShuffle(recs)
+	if order == records.AZAffineOrder {
+		recs = d.localAZFirst(recs)
+	}
+
+	ipStrs := make([]string, 0, len(recs)+len(aliasIPs))
+	for _, rec := range recs {
+		ipStrs = append(ipStrs, rec.IP)
+	}
+	ipStrs = append(ipStrs, aliasIPs...)
+
+	if limit > 0 && uint(len(ipStrs)) > limit {
+		ipStrs = ipStrs[:limit]
+	}
+
+	return ipStrs
+}
+
+// localAZFirst moves the instances sharing the AZ of the record for one of
+// the addresses of this VM to the front, keeping their order.
+func (d LocalDomain) localAZFirst(recs []record.Record) []record.Record {
+	localAZ := d.recordSet.LocalAZ()
+	if localAZ == "" {
+		d.logger.Debug(d.logTag, "no AZ known for the addresses of this VM")
+		return recs
+	}
+
+	local := []record.Record{}
+	remote := []record.Record{}
+	for _, rec := range recs {
+		if rec.AZKey() == localAZ {
+			local = append(local, rec)
 		} else {
-			if question.Qtype == dns.TypeAAAA || question.Qtype == dns.TypeANY {
-				answer = &dns.AAAA{
-					Hdr: dns.RR_Header{
-						Name:   question.Name,
-						Rrtype: dns.TypeAAAA,
-						Class:  dns.ClassINET,
-						Ttl:    0,
-					},
-					AAAA: ip,
-				}
-			}
+			remote = append(remote, rec)
 		}
+	}
 
-		if answer != nil {
-			answers = append(answers, answer)
+	return append(local, remote...)
+}
+
+// weightedShuffle orders recs randomly such that each position is more likely
+// taken by instances with a higher weight. Instances without a weight count
+// as weighing 1 and instances weighing 0 always come last.
+func weightedShuffle(recs []record.Record) []record.Record {
+	keys := make([]float64, len(recs))
+	for i, rec := range recs {
+		keys[i] = math.Inf(1)
+		if weight := recordWeight(rec); weight > 0 {
+			keys[i] = rand.ExpFloat64() / float64(weight)
+		}
+	}
+
+	indices := make([]int, len(recs))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return keys[indices[i]] < keys[indices[j]]
+	})
+
+	shuffled := make([]record.Record, len(recs))
+	for i, index := range indices {
+		shuffled[i] = recs[index]
+	}
+
+	return shuffled
+}
+
+// rendezvousSort orders addresses by their highest random weight for the
+// client, so that each client sees a stable ranking of the same addresses.
+func rendezvousSort(ipStrs []string, client string) []string {
+	sorted := make([]string, len(ipStrs))
+	for i, index := range rendezvousRanking(ipStrs, client) {
+		sorted[i] = ipStrs[index]
+	}
+
+	return sorted
+}
+
+func rendezvousSortRecords(recs []record.Record, client string) []record.Record {
+	ipStrs := make([]string, len(recs))
+	for i, rec := range recs {
+		ipStrs[i] = rec.IP
+	}
+
+	sorted := make([]record.Record, len(recs))
+	for i, index := range rendezvousRanking(ipStrs, client) {
+		sorted[i] = recs[index]
+	}
+
+	return sorted
+}
+
+func rendezvousRanking(keys []string, client string) []int {
+	scores := make([]uint64, len(keys))
+	for i, key := range keys {
+		h := fnv.New64a()
+		h.Write([]byte(client + "/" + key)) //nolint:errcheck
+		scores[i] = h.Sum64()
+	}
+
+	indices := make([]int, len(keys))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return scores[indices[i]] > scores[indices[j]]
+	})
+
+	return indices
+}
+
+func recordWeight(rec record.Record) int {
+	if rec.Weight == "" {
+		return 1
+	}
+
+	weight, err := strconv.Atoi(rec.Weight)
+	if err != nil || weight < 0 {
+		return 1
+	}
+
+	return weight
+}
+
+// resolveServices answers `_service._proto.<query>` SRV questions with one
+// record per matching instance, targeting the instance's ID based FQDN. The
+// addresses of the targets are returned as glue for the additional section.
+// Instances without a known port are left out.
+func (d LocalDomain) resolveServices(question dns.Question) ([]dns.RR, []dns.RR, int) {
+	var lowercaseName = strings.ToLower(question.Name)
+
+	d.logger.Debug(d.logTag, "query lower-cased from '%s' to '%s'", question.Name, lowercaseName)
+
+	answers := []dns.RR{}
+	extras := []dns.RR{}
+
+	serviceName, host := splitService(lowercaseName)
+	services, err := d.recordSet.ResolveServices(serviceName, host)
+	if err != nil {
+		d.logger.Debug(d.logTag, "failed to get services: %v", err)
+		return nil, nil, rcodeFromError(err)
+	}
+
+	ttl := d.ttl(host)
+	for _, service := range services {
+		if service.Port == 0 {
+			d.logger.Warn(d.logTag, "leaving instance %s out of %s: no port is known from the query, its alias or the links of the instance", service.ID, question.Name)
+			continue
+		}
+
+		target := instanceFQDN(service.Record)
+		answers = append(answers, &dns.SRV{
+			Hdr: dns.RR_Header{
+				Name:   question.Name,
+				Rrtype: dns.TypeSRV,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			Priority: 0,
+			Weight:   0,
+			Port:     service.Port,
+			Target:   target,
+		})
+
+		if extra := addressRR(target, dns.TypeANY, service.IP, ttl); extra != nil {
+			extras = append(extras, extra)
 		}
 	}
 
@@ -107,5 +346,240 @@ func (d LocalDomain) resolve(question dns.Question) ([]dns.RR, int) {
 		answers[i], answers[j] = answers[j], answers[i]
 	})
 
+	return answers, extras, dns.RcodeSuccess
+}
+
+// resolveMetadata answers TXT questions with one record per matching
+// instance, describing it with key=value strings.
+func (d LocalDomain) resolveMetadata(question dns.Question) ([]dns.RR, int) {
+	var lowercaseName = strings.ToLower(question.Name)
+
+	d.logger.Debug(d.logTag, "query lower-cased from '%s' to '%s'", question.Name, lowercaseName)
+
+	answers := []dns.RR{}
+
+	recs, err := d.metadataRecords(lowercaseName)
+	if err != nil {
+		d.logger.Debug(d.logTag, "failed to get records: %v", err)
+		return nil, rcodeFromError(err)
+	}
+
+	ttl := d.ttl(lowercaseName)
+	for _, rec := range recs {
+		answers = append(answers, &dns.TXT{
+			Hdr: dns.RR_Header{
+				Name:   question.Name,
+				Rrtype: dns.TypeTXT,
+				Class:  dns.ClassINET,
+				Ttl:    ttl,
+			},
+			Txt: []string{
+				"id=" + rec.ID,
+				"instance_group=" + rec.Group,
+				"az=" + rec.AZ,
+				"az_id=" + rec.AZID,
+				"instance_index=" + rec.InstanceIndex,
+				"deployment=" + rec.Deployment,
+				"network=" + rec.Network,
+				"group_ids=" + strings.Join(rec.GroupIDs, ","),
+				"agent_id=" + rec.AgentID,
+				"health_state=" + d.healthStateGetter.HealthStateString(rec.IP),
+			},
+		})
+	}
+
 	return answers, dns.RcodeSuccess
 }
+
+// metadataRecords returns the records whose metadata a TXT question asks
+// for. Alias targets which are addresses or are not record queries have no
+// metadata, so they are skipped instead of failing the question.
+func (d LocalDomain) metadataRecords(name string) ([]record.Record, error) {
+	expansions := d.recordSet.ExpandAliases(name)
+	if len(expansions) == 1 && expansions[0] == name {
+		return d.recordSet.ResolveRecords(expansions, false)
+	}
+
+	recs := []record.Record{}
+	matched := false
+	var err error
+	for _, expansion := range expansions {
+		if net.ParseIP(expansion) != nil {
+			continue
+		}
+
+		expansionRecs, expansionErr := d.recordSet.ResolveRecords([]string{expansion}, false)
+		if errors.Is(expansionErr, records.CriteriaError) {
+			d.logger.Debug(d.logTag, "skipping alias target %s which is not a record query", expansion)
+			continue
+		}
+		if expansionErr != nil {
+			err = expansionErr
+			continue
+		}
+
+		matched = true
+		recs = append(recs, expansionRecs...)
+	}
+
+	if !matched && err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// resolveZone answers SOA and NS questions for the apex of an internal zone.
+func (d LocalDomain) resolveZone(question dns.Question, zone string) ([]dns.RR, int) {
+	if question.Qtype == dns.TypeSOA {
+		return []dns.RR{d.soa(zone)}, dns.RcodeSuccess
+	}
+
+	return []dns.RR{&dns.NS{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeNS,
+			Class:  dns.ClassINET,
+			Ttl:    d.ttls.ForDomain(zone),
+		},
+		Ns: zoneNameserver,
+	}}, dns.RcodeSuccess
+}
+
+// soa synthesizes the SOA of an internal zone. Its serial follows the
+// version of the records file and its minimum is the TTL of the zone, which
+// downstream resolvers use for negative caching.
+func (d LocalDomain) soa(zone string) dns.RR {
+	ttl := d.ttls.ForDomain(zone)
+
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   zone,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    ttl,
+		},
+		Ns:      zoneNameserver,
+		Mbox:    zoneMailbox,
+		Serial:  uint32(d.recordSet.Version()),
+		Refresh: 3600,
+		Retry:   1200,
+		Expire:  604800,
+		Minttl:  ttl,
+	}
+}
+
+// zone returns the most specific internal domain name belongs to, or an
+// empty string when there is none.
+func (d LocalDomain) zone(name string) string {
+	name = strings.ToLower(dns.Fqdn(name))
+
+	zone := ""
+	for _, domain := range d.recordSet.Domains() {
+		domain = strings.ToLower(dns.Fqdn(domain))
+		if dns.IsSubDomain(domain, name) && len(domain) > len(zone) {
+			zone = domain
+		}
+	}
+
+	return zone
+}
+
+// ttl prefers the TTL requested by the query itself over the one configured
+// for its domain.
+func (d LocalDomain) ttl(name string) uint32 {
+	if ttl, ok := d.recordSet.QueryTTL(name); ok {
+		return ttl
+	}
+
+	return d.ttls.ForDomain(name)
+}
+
+// ofFamily keeps the addresses answering qtype, so that limiting the answers
+// does not drop them in favour of addresses of the other family.
+func ofFamily(ipStrs []string, qtype uint16) []string {
+	kept := []string{}
+	for _, ipStr := range ipStrs {
+		if inFamily(ipStr, qtype) {
+			kept = append(kept, ipStr)
+		}
+	}
+
+	return kept
+}
+
+func inFamily(ipStr string, qtype uint16) bool {
+	return addressRR("", qtype, ipStr, 0) != nil
+}
+
+func addressRR(name string, qtype uint16, ipStr string, ttl uint32) dns.RR {
+	ip := net.ParseIP(ipStr)
+
+	if ip.To4() != nil {
+		if qtype == dns.TypeA || qtype == dns.TypeANY {
+			return &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   name,
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    ttl,
+				},
+				A: ip,
+			}
+		}
+	} else {
+		if qtype == dns.TypeAAAA || qtype == dns.TypeANY {
+			return &dns.AAAA{
+				Hdr: dns.RR_Header{
+					Name:   name,
+					Rrtype: dns.TypeAAAA,
+					Class:  dns.ClassINET,
+					Ttl:    ttl,
+				},
+				AAAA: ip,
+			}
+		}
+	}
+
+	return nil
+}
+
+func clientIP(responseWriter dns.ResponseWriter) string {
+	remoteAddr := responseWriter.RemoteAddr()
+	if remoteAddr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr.String())
+	if err != nil {
+		return remoteAddr.String()
+	}
+
+	return host
+}
+
+func rcodeFromError(err error) int {
+	if errors.Is(err, records.CriteriaError) {
+		return dns.RcodeFormatError
+	} else if errors.Is(err, records.DomainError) {
+		return dns.RcodeNameError
+	}
+
+	return dns.RcodeServerFailure
+}
+
+// splitService splits the RFC 2782 `_service._proto.` labels off an SRV
+// question, returning the service name and the host query that selects the
+// instances.
+func splitService(name string) (string, string) {
+	segments := strings.SplitN(name, ".", 3)
+	if len(segments) == 3 && strings.HasPrefix(segments[0], "_") && strings.HasPrefix(segments[1], "_") {
+		return strings.TrimPrefix(segments[0], "_"), segments[2]
+	}
+
+	return "", name
+}
+
+func instanceFQDN(rec record.Record) string {
+	return dns.Fqdn(fmt.Sprintf("%s.%s.%s.%s.%s", rec.ID, rec.Group, rec.Network, rec.Deployment, rec.Domain))
+}
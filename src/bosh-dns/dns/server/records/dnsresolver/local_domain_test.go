@@ -10,8 +10,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	"bosh-dns/dns/config"
 	. "bosh-dns/dns/internal/testhelpers/question_case_helpers"
 	"bosh-dns/dns/server/internal/internalfakes"
+	"bosh-dns/dns/server/record"
 	"bosh-dns/dns/server/records"
 	. "bosh-dns/dns/server/records/dnsresolver"
 	"bosh-dns/dns/server/records/dnsresolver/dnsresolverfakes"
@@ -23,6 +25,7 @@ var _ = Describe("LocalDomain", func() {
 			fakeLogger    *loggerfakes.FakeLogger
 			fakeWriter    *internalfakes.FakeResponseWriter
 			fakeRecordSet *dnsresolverfakes.FakeRecordSet
+			fakeHealth    *dnsresolverfakes.FakeHealthStateGetter
 			localDomain   LocalDomain
 			fakeTruncater *dnsresolverfakes.FakeResponseTruncater
 		)
@@ -31,23 +34,24 @@ var _ = Describe("LocalDomain", func() {
 			fakeLogger = &loggerfakes.FakeLogger{}
 			fakeWriter = &internalfakes.FakeResponseWriter{}
 			fakeRecordSet = &dnsresolverfakes.FakeRecordSet{}
+			fakeHealth = &dnsresolverfakes.FakeHealthStateGetter{}
 			fakeTruncater = &dnsresolverfakes.FakeResponseTruncater{}
 
 			fakeWriter.RemoteAddrReturns(&net.UDPAddr{})
-			localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeTruncater)
+			localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{}, fakeTruncater)
 		})
 
 		It("returns responses from the question domain", func() {
 			originalResolutionList := []string{"123.123.123.123", "123.123.123.124"}
-			fakeRecordSet.ResolveStub = func(domain string) ([]string, error) {
+			fakeRecordSet.ResolveStub = func(domain string) (records.Resolution, error) {
 				switch domain {
 				case "*.group-1.network-name.deployment-name.bosh.":
-					return originalResolutionList, nil
+					return resolutionOf(originalResolutionList...), nil
 				case "instance-2.group-2.network-name.deployment-name.bosh.":
-					return []string{"123.123.123.246"}, nil
+					return resolutionOf("123.123.123.246"), nil
 				}
 
-				return nil, errors.New("nope")
+				return records.Resolution{}, errors.New("nope")
 			}
 
 			var casedQname string
@@ -79,18 +83,18 @@ var _ = Describe("LocalDomain", func() {
 		It("shuffles the answers", func() {
 			originalResolutionList := []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4", "5.5.5.5"}
 
-			fakeRecordSet.ResolveStub = func(domain string) ([]string, error) {
+			fakeRecordSet.ResolveStub = func(domain string) (records.Resolution, error) {
 				switch domain {
 				case "*.group-1.network-name.deployment-name.bosh.":
-					return originalResolutionList, nil
+					return resolutionOf(originalResolutionList...), nil
 				case "instance-2.group-2.network-name.deployment-name.bosh.":
-					return []string{"123.123.123.246"}, nil
+					return resolutionOf("123.123.123.246"), nil
 				}
 
-				return nil, errors.New("nope")
+				return records.Resolution{}, errors.New("nope")
 			}
 
-			localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeTruncater)
+			localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{}, fakeTruncater)
 
 			req := &dns.Msg{}
 			SetQuestion(req, nil, "*.group-1.network-name.deployment-name.bosh.", dns.TypeA)
@@ -108,16 +112,441 @@ var _ = Describe("LocalDomain", func() {
 			Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
 		})
 
+		Context("when TTLs are configured", func() {
+			BeforeEach(func() {
+				fakeRecordSet.ResolveReturns(resolutionOf("123.123.123.123"), nil)
+				localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{
+					Default: 5,
+					Domains: map[string]uint32{
+						"bosh.":            10,
+						"deployment.bosh.": 20,
+					},
+				}, fakeTruncater)
+			})
+
+			It("uses the TTL of the most specific domain", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				Expect(responseMsg.Answer[0].Header().Ttl).To(Equal(uint32(20)))
+			})
+
+			It("uses the default TTL for other domains", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "alias.internal.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				Expect(responseMsg.Answer[0].Header().Ttl).To(Equal(uint32(5)))
+			})
+
+			It("prefers the TTL requested by the query", func() {
+				resolution := resolutionOf("123.123.123.123")
+				resolution.TTL, resolution.HasTTL = 300, true
+				fakeRecordSet.ResolveReturns(resolution, nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-s0t300.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(fakeRecordSet.ResolveArgsForCall(0)).To(Equal("q-s0t300.group.network.deployment.bosh."))
+				Expect(fakeRecordSet.QueryTTLCallCount()).To(Equal(0))
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				Expect(responseMsg.Answer[0].Header().Ttl).To(Equal(uint32(300)))
+			})
+		})
+
+		Context("when the query requests an answer order", func() {
+			var (
+				answerIPs  func(*dns.Msg) []string
+				resolveTo  func(records.Order, ...record.Record)
+				resolution records.Resolution
+			)
+
+			BeforeEach(func() {
+				resolveTo = func(order records.Order, recs ...record.Record) {
+					resolution = records.Resolution{Records: recs, QueryOptions: records.QueryOptions{Order: order}}
+					fakeRecordSet.ResolveReturns(resolution, nil)
+				}
+				resolveTo(records.AZAffineOrder,
+					record.Record{ID: "remote-1", IP: "10.0.1.1", AZID: "1"},
+					record.Record{ID: "local-1", IP: "10.0.2.1", AZID: "2"},
+					record.Record{ID: "remote-2", IP: "10.0.1.2", AZID: "1"},
+					record.Record{ID: "local-2", IP: "10.0.2.2", AZID: "2"},
+				)
+				fakeRecordSet.LocalAZReturns("2")
+				localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{}, fakeTruncater)
+
+				answerIPs = func(msg *dns.Msg) []string {
+					ips := []string{}
+					for _, answer := range msg.Answer {
+						ips = append(ips, answer.(*dns.A).A.String())
+					}
+					return ips
+				}
+			})
+
+			It("puts the instances in the local AZ first", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-o2s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(fakeRecordSet.ResolveCallCount()).To(Equal(1))
+				Expect(fakeRecordSet.ResolveArgsForCall(0)).To(Equal("q-o2s0.group.network.deployment.bosh."))
+
+				ips := answerIPs(responseMsg)
+				Expect(ips).To(HaveLen(4))
+				Expect(ips[:2]).To(ConsistOf("10.0.2.1", "10.0.2.2"))
+				Expect(ips[2:]).To(ConsistOf("10.0.1.1", "10.0.1.2"))
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+			})
+
+			It("falls back to the other AZs when the local AZ has no instances", func() {
+				resolveTo(records.AZAffineOrder,
+					record.Record{ID: "remote-1", IP: "10.0.1.1", AZID: "1"},
+					record.Record{ID: "remote-2", IP: "10.0.3.1", AZID: "3"},
+				)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-o2s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(answerIPs(responseMsg)).To(ConsistOf("10.0.1.1", "10.0.3.1"))
+			})
+
+			It("does not reorder by AZ when the local AZ is unknown", func() {
+				fakeRecordSet.LocalAZReturns("")
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-o2s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(answerIPs(responseMsg)).To(ConsistOf("10.0.1.1", "10.0.2.1", "10.0.1.2", "10.0.2.2"))
+			})
+
+			It("puts instances with a weight of zero last", func() {
+				resolveTo(records.WeightedOrder,
+					record.Record{ID: "drained", IP: "10.0.1.1", Weight: "0"},
+					record.Record{ID: "heavy", IP: "10.0.1.2", Weight: "100"},
+					record.Record{ID: "default", IP: "10.0.1.3"},
+				)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-o1s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				ips := answerIPs(responseMsg)
+				Expect(ips[:2]).To(ConsistOf("10.0.1.2", "10.0.1.3"))
+				Expect(ips[2]).To(Equal("10.0.1.1"))
+			})
+
+			It("favours instances with a higher weight", func() {
+				resolveTo(records.WeightedOrder,
+					record.Record{ID: "light", IP: "10.0.1.1", Weight: "1"},
+					record.Record{ID: "heavy", IP: "10.0.1.2", Weight: "99"},
+				)
+
+				heavyFirst := 0
+				for i := 0; i < 100; i++ {
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "q-o1s0.group.network.deployment.bosh.", dns.TypeA)
+					if answerIPs(localDomain.Resolve(fakeWriter, req))[0] == "10.0.1.2" {
+						heavyFirst++
+					}
+				}
+
+				Expect(heavyFirst).To(BeNumerically(">", 80))
+			})
+
+			It("appends addresses aliased directly", func() {
+				resolveTo(records.WeightedOrder)
+				resolution.AliasIPs = []string{"192.168.0.1"}
+				fakeRecordSet.ResolveReturns(resolution, nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "alias.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(answerIPs(responseMsg)).To(Equal([]string{"192.168.0.1"}))
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+			})
+
+			It("returns the error of resolving the records", func() {
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.DomainError)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-o1s0.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Answer).To(BeEmpty())
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeNameError))
+			})
+		})
+
+		Context("when the query requests a sticky order", func() {
+			var resolveFrom func(clientIP string) []string
+
+			BeforeEach(func() {
+				resolution := resolutionOf("10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5")
+				resolution.Order = records.StickyOrder
+				fakeRecordSet.ResolveReturns(resolution, nil)
+
+				resolveFrom = func(clientIP string) []string {
+					fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP(clientIP), Port: 53})
+
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "q-o3s0.group.network.deployment.bosh.", dns.TypeA)
+					responseMsg := localDomain.Resolve(fakeWriter, req)
+
+					ips := []string{}
+					for _, answer := range responseMsg.Answer {
+						ips = append(ips, answer.(*dns.A).A.String())
+					}
+					return ips
+				}
+			})
+
+			It("returns the answers in the same order to the same client", func() {
+				first := resolveFrom("192.168.0.1")
+				Expect(first).To(ConsistOf("10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"))
+				for i := 0; i < 10; i++ {
+					Expect(resolveFrom("192.168.0.1")).To(Equal(first))
+				}
+			})
+
+			It("prefers different instances for different clients", func() {
+				preferred := map[string]struct{}{}
+				for i := 1; i <= 50; i++ {
+					preferred[resolveFrom(fmt.Sprintf("192.168.0.%d", i))[0]] = struct{}{}
+				}
+
+				Expect(len(preferred)).To(BeNumerically(">", 1))
+			})
+
+			It("keeps the order of the remaining instances when one goes away", func() {
+				first := resolveFrom("192.168.0.1")
+
+				resolution := resolutionOf(first[1:]...)
+				resolution.Order = records.StickyOrder
+				fakeRecordSet.ResolveReturns(resolution, nil)
+				Expect(resolveFrom("192.168.0.1")).To(Equal(first[1:]))
+			})
+		})
+
+		Context("when the query limits the number of answers", func() {
+			var (
+				resolveFrom func(clientIP string) []string
+				resolveTo   func(records.Resolution)
+			)
+
+			BeforeEach(func() {
+				resolveTo = func(resolution records.Resolution) {
+					resolution.Limit = 2
+					fakeRecordSet.ResolveReturns(resolution, nil)
+				}
+				resolveTo(resolutionOf("10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"))
+
+				resolveFrom = func(clientIP string) []string {
+					fakeWriter.RemoteAddrReturns(&net.UDPAddr{IP: net.ParseIP(clientIP), Port: 53})
+
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "q-c2s0.group.network.deployment.bosh.", dns.TypeA)
+					responseMsg := localDomain.Resolve(fakeWriter, req)
+
+					ips := []string{}
+					for _, answer := range responseMsg.Answer {
+						ips = append(ips, answer.(*dns.A).A.String())
+					}
+					return ips
+				}
+			})
+
+			It("returns at most the requested number of answers", func() {
+				Expect(resolveFrom("192.168.0.1")).To(HaveLen(2))
+				Expect(fakeRecordSet.ResolveArgsForCall(0)).To(Equal("q-c2s0.group.network.deployment.bosh."))
+			})
+
+			It("returns the same answers to the same client", func() {
+				first := resolveFrom("192.168.0.1")
+				for i := 0; i < 10; i++ {
+					Expect(resolveFrom("192.168.0.1")).To(ConsistOf(first))
+				}
+			})
+
+			It("spreads different clients across the answers", func() {
+				seen := map[string]struct{}{}
+				for i := 1; i <= 50; i++ {
+					for _, ip := range resolveFrom(fmt.Sprintf("192.168.0.%d", i)) {
+						seen[ip] = struct{}{}
+					}
+				}
+
+				Expect(len(seen)).To(BeNumerically(">", 2))
+			})
+
+			It("limits the answers of the queried address family", func() {
+				resolveTo(resolutionOf("2001:db8::1", "2001:db8::2", "2001:db8::3", "10.0.0.1", "10.0.0.2"))
+
+				for i := 1; i <= 10; i++ {
+					Expect(resolveFrom(fmt.Sprintf("192.168.0.%d", i))).To(ConsistOf("10.0.0.1", "10.0.0.2"))
+				}
+			})
+
+			It("limits the answers of the queried address family when ordering by weight", func() {
+				resolution := resolutionOf("2001:db8::1", "2001:db8::2", "10.0.0.1", "2001:db8::3")
+				resolution.AliasIPs = []string{"2001:db8::4"}
+				resolution.Order = records.WeightedOrder
+				resolveTo(resolution)
+
+				for i := 1; i <= 10; i++ {
+					Expect(resolveFrom(fmt.Sprintf("192.168.0.%d", i))).To(ConsistOf("10.0.0.1"))
+				}
+			})
+
+			It("keeps the local AZ instances when ordering by AZ", func() {
+				fakeRecordSet.LocalAZReturns("2")
+				resolveTo(records.Resolution{
+					Records: []record.Record{
+						{IP: "10.0.1.1", AZID: "1"},
+						{IP: "10.0.2.1", AZID: "2"},
+						{IP: "10.0.1.2", AZID: "1"},
+						{IP: "10.0.2.2", AZID: "2"},
+						{IP: "10.0.1.3", AZID: "1"},
+					},
+					QueryOptions: records.QueryOptions{Order: records.AZAffineOrder},
+				})
+
+				Expect(resolveFrom("192.168.0.1")).To(ConsistOf("10.0.2.1", "10.0.2.2"))
+			})
+		})
+
+		Context("when the name belongs to an internal zone", func() {
+			BeforeEach(func() {
+				fakeRecordSet.DomainsReturns([]string{"bosh.", "internal.", "my.internal."})
+				fakeRecordSet.VersionReturns(42)
+				localDomain = NewLocalDomain(fakeLogger, fakeRecordSet, fakeHealth, config.InternalTTLConfig{
+					Default: 5,
+					Domains: map[string]uint32{"my.internal.": 30},
+				}, fakeTruncater)
+			})
+
+			expectSOA := func(rr dns.RR, zone string, ttl uint32) {
+				Expect(rr).To(BeAssignableToTypeOf(&dns.SOA{}))
+				soa := rr.(*dns.SOA)
+				Expect(soa.Hdr.Name).To(Equal(zone))
+				Expect(soa.Hdr.Rrtype).To(Equal(dns.TypeSOA))
+				Expect(soa.Hdr.Class).To(Equal(uint16(dns.ClassINET)))
+				Expect(soa.Hdr.Ttl).To(Equal(ttl))
+				Expect(soa.Ns).To(Equal("localhost."))
+				Expect(soa.Mbox).To(Equal("nobody.invalid."))
+				Expect(soa.Serial).To(Equal(uint32(42)))
+				Expect(soa.Minttl).To(Equal(ttl))
+			}
+
+			It("puts the SOA of the zone in the authority section of NXDOMAIN responses", func() {
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.DomainError)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "missing.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(responseMsg.Ns).To(HaveLen(1))
+				expectSOA(responseMsg.Ns[0], "bosh.", 5)
+			})
+
+			It("puts the SOA of the most specific zone in the authority section of empty responses", func() {
+				fakeRecordSet.ResolveReturns(resolutionOf("2601:646:102:95::25"), nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "alias.MY.internal.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(BeEmpty())
+				Expect(responseMsg.Ns).To(HaveLen(1))
+				expectSOA(responseMsg.Ns[0], "my.internal.", 30)
+			})
+
+			It("leaves the authority section of positive responses empty", func() {
+				fakeRecordSet.ResolveReturns(resolutionOf("123.123.123.123"), nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "instance.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				Expect(responseMsg.Ns).To(BeEmpty())
+			})
+
+			It("leaves the authority section of format errors empty", func() {
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.CriteriaError)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-&&&.group.network.deployment.bosh.", dns.TypeA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeFormatError))
+				Expect(responseMsg.Ns).To(BeEmpty())
+			})
+
+			It("answers SOA questions for the zone apex", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "BOSH.", dns.TypeSOA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(fakeRecordSet.ResolveCallCount()).To(Equal(0))
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Authoritative).To(BeTrue())
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				expectSOA(responseMsg.Answer[0], "bosh.", 5)
+				Expect(responseMsg.Ns).To(BeEmpty())
+			})
+
+			It("answers NS questions for the zone apex", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "internal.", dns.TypeNS)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(HaveLen(1))
+				Expect(responseMsg.Answer[0]).To(Equal(&dns.NS{
+					Hdr: dns.RR_Header{
+						Name:   "internal.",
+						Rrtype: dns.TypeNS,
+						Class:  dns.ClassINET,
+						Ttl:    5,
+					},
+					Ns: "localhost.",
+				}))
+			})
+
+			It("answers SOA questions below the zone apex with no data", func() {
+				fakeRecordSet.ResolveReturns(resolutionOf("123.123.123.123"), nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "instance.group.network.deployment.bosh.", dns.TypeSOA)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(BeEmpty())
+				Expect(responseMsg.Ns).To(HaveLen(1))
+				expectSOA(responseMsg.Ns[0], "bosh.", 5)
+			})
+		})
+
 		Context("when there are too many records to fit into 512 bytes", func() {
 			var (
 				request *dns.Msg
 			)
 
 			BeforeEach(func() {
-				fakeRecordSet.ResolveStub = func(domain string) ([]string, error) {
+				fakeRecordSet.ResolveStub = func(domain string) (records.Resolution, error) {
 					Expect(domain).To(Equal("my-instance.my-group.my-network.my-deployment.bosh."))
 
-					return []string{"123.123.123.123"}, nil
+					return resolutionOf("123.123.123.123"), nil
 				}
 				request = &dns.Msg{}
 				SetQuestion(request, nil, "my-instance.my-group.my-network.my-deployment.bosh.", dns.TypeA)
@@ -140,7 +569,7 @@ var _ = Describe("LocalDomain", func() {
 		It("returns only A records (no AAAA records) when the queried for A records", func() {
 			ipv6ResolutionList := []string{"2601:0646:0102:0095:0000:0000:0000:0025"}
 			ipv4ResolutionList := []string{"123.123.123.123", "123.123.123.246"}
-			fakeRecordSet.ResolveReturns(append(ipv6ResolutionList, ipv4ResolutionList...), nil)
+			fakeRecordSet.ResolveReturns(resolutionOf(append(ipv6ResolutionList, ipv4ResolutionList...)...), nil)
 
 			var casedQname string
 			req := &dns.Msg{}
@@ -174,7 +603,7 @@ var _ = Describe("LocalDomain", func() {
 			}
 			ipv4ResolutionList := []string{"123.123.123.246"}
 
-			fakeRecordSet.ResolveReturns(append(ipv6ResolutionList, ipv4ResolutionList...), nil)
+			fakeRecordSet.ResolveReturns(resolutionOf(append(ipv6ResolutionList, ipv4ResolutionList...)...), nil)
 
 			var casedQname string
 			req := &dns.Msg{}
@@ -213,7 +642,7 @@ var _ = Describe("LocalDomain", func() {
 			}
 			ipv4ResolutionList := []string{"123.123.123.246"}
 
-			fakeRecordSet.ResolveReturns(append(ipv6ResolutionList, ipv4ResolutionList...), nil)
+			fakeRecordSet.ResolveReturns(resolutionOf(append(ipv6ResolutionList, ipv4ResolutionList...)...), nil)
 
 			var casedQname string
 			req := &dns.Msg{}
@@ -270,11 +699,236 @@ var _ = Describe("LocalDomain", func() {
 			Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
 		})
 
+		Context("when queried for SRV records", func() {
+			BeforeEach(func() {
+				fakeRecordSet.ResolveServicesReturns([]records.ServiceRecord{
+					{
+						Record: record.Record{
+							ID:         "instance-1",
+							Group:      "group-1",
+							Network:    "network-name",
+							Deployment: "deployment-name",
+							Domain:     "bosh.",
+							IP:         "123.123.123.123",
+						},
+						Port: 8080,
+					},
+					{
+						Record: record.Record{
+							ID:         "instance-2",
+							Group:      "group-1",
+							Network:    "network-name",
+							Deployment: "deployment-name",
+							Domain:     "bosh.",
+							IP:         "2601:0646:0102:0095:0000:0000:0000:0026",
+						},
+						Port: 8080,
+					},
+				}, nil)
+			})
+
+			It("returns one SRV record per instance with address glue", func() {
+				var casedQname string
+				req := &dns.Msg{}
+				SetQuestion(req, &casedQname, "_http._tcp.q-p8080s0.group-1.network-name.deployment-name.bosh.", dns.TypeSRV)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(fakeRecordSet.ResolveServicesCallCount()).To(Equal(1))
+				service, host := fakeRecordSet.ResolveServicesArgsForCall(0)
+				Expect(service).To(Equal("http"))
+				Expect(host).To(Equal("q-p8080s0.group-1.network-name.deployment-name.bosh."))
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(HaveLen(2))
+
+				var targets []string
+				for _, a := range responseMsg.Answer {
+					Expect(a).To(BeAssignableToTypeOf(&dns.SRV{}))
+					srv := a.(*dns.SRV)
+					Expect(srv.Hdr.Name).To(Equal(casedQname))
+					Expect(srv.Hdr.Rrtype).To(Equal(dns.TypeSRV))
+					Expect(srv.Port).To(Equal(uint16(8080)))
+					targets = append(targets, srv.Target)
+				}
+				Expect(targets).To(ConsistOf(
+					"instance-1.group-1.network-name.deployment-name.bosh.",
+					"instance-2.group-1.network-name.deployment-name.bosh.",
+				))
+
+				Expect(responseMsg.Extra).To(HaveLen(2))
+				Expect(responseMsg.Extra).To(ContainElement(&dns.A{
+					Hdr: dns.RR_Header{Name: "instance-1.group-1.network-name.deployment-name.bosh.", Rrtype: dns.TypeA, Class: dns.ClassINET},
+					A:   net.ParseIP("123.123.123.123"),
+				}))
+				Expect(responseMsg.Extra).To(ContainElement(&dns.AAAA{
+					Hdr:  dns.RR_Header{Name: "instance-2.group-1.network-name.deployment-name.bosh.", Rrtype: dns.TypeAAAA, Class: dns.ClassINET},
+					AAAA: net.ParseIP("2601:0646:0102:0095:0000:0000:0000:0026"),
+				}))
+			})
+
+			It("resolves names without service labels as they are", func() {
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-p8080s0.group-1.network-name.deployment-name.bosh.", dns.TypeSRV)
+				localDomain.Resolve(fakeWriter, req)
+
+				service, host := fakeRecordSet.ResolveServicesArgsForCall(0)
+				Expect(service).To(BeEmpty())
+				Expect(host).To(Equal("q-p8080s0.group-1.network-name.deployment-name.bosh."))
+			})
+
+			It("leaves out instances without a known port and warns about them", func() {
+				fakeRecordSet.ResolveServicesReturns([]records.ServiceRecord{
+					{Record: record.Record{ID: "instance-1", IP: "123.123.123.123"}},
+				}, nil)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "_http._tcp.q-s0.group-1.network-name.deployment-name.bosh.", dns.TypeSRV)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(BeEmpty())
+				Expect(responseMsg.Extra).To(BeEmpty())
+
+				Expect(fakeLogger.WarnCallCount()).To(Equal(1))
+				_, msg, args := fakeLogger.WarnArgsForCall(0)
+				Expect(fmt.Sprintf(msg, args...)).To(HavePrefix("leaving instance instance-1 out of "))
+			})
+
+			It("returns rcode name error when the domain is unknown", func() {
+				fakeRecordSet.ResolveServicesReturns(nil, records.DomainError)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "_http._tcp.q-s0.group-1.network-name.deployment-name.bosh.", dns.TypeSRV)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeNameError))
+			})
+		})
+
+		Context("when queried for TXT records", func() {
+			BeforeEach(func() {
+				fakeRecordSet.ExpandAliasesReturns([]string{"q-s0.group-1.network-name.deployment-name.bosh."})
+				fakeRecordSet.ResolveRecordsReturns([]record.Record{
+					{
+						ID:            "instance-1",
+						Group:         "group-1",
+						GroupIDs:      []string{"1", "7"},
+						Network:       "network-name",
+						Deployment:    "deployment-name",
+						Domain:        "bosh.",
+						IP:            "123.123.123.123",
+						AZ:            "z1",
+						AZID:          "2",
+						AgentID:       "agent-1",
+						InstanceIndex: "0",
+					},
+				}, nil)
+				fakeHealth.HealthStateStringReturns("running")
+			})
+
+			It("returns the instance metadata as key=value strings", func() {
+				var casedQname string
+				req := &dns.Msg{}
+				SetQuestion(req, &casedQname, "q-s0.group-1.network-name.deployment-name.bosh.", dns.TypeTXT)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(fakeRecordSet.ExpandAliasesArgsForCall(0)).To(Equal("q-s0.group-1.network-name.deployment-name.bosh."))
+				domains, shouldTrack := fakeRecordSet.ResolveRecordsArgsForCall(0)
+				Expect(domains).To(Equal([]string{"q-s0.group-1.network-name.deployment-name.bosh."}))
+				Expect(shouldTrack).To(BeFalse())
+				Expect(fakeHealth.HealthStateStringArgsForCall(0)).To(Equal("123.123.123.123"))
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+				Expect(responseMsg.Answer).To(Equal([]dns.RR{
+					&dns.TXT{
+						Hdr: dns.RR_Header{Name: casedQname, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+						Txt: []string{
+							"id=instance-1",
+							"instance_group=group-1",
+							"az=z1",
+							"az_id=2",
+							"instance_index=0",
+							"deployment=deployment-name",
+							"network=network-name",
+							"group_ids=1,7",
+							"agent_id=agent-1",
+							"health_state=running",
+						},
+					},
+				}))
+			})
+
+			It("returns rcode name error when the domain is unknown", func() {
+				fakeRecordSet.ResolveRecordsReturns(nil, records.DomainError)
+
+				req := &dns.Msg{}
+				SetQuestion(req, nil, "q-s0.group-1.network-name.deployment-name.bosh.", dns.TypeTXT)
+				responseMsg := localDomain.Resolve(fakeWriter, req)
+
+				Expect(responseMsg.Rcode).To(Equal(dns.RcodeNameError))
+				Expect(responseMsg.Answer).To(BeEmpty())
+			})
+
+			Context("when the domain is an alias", func() {
+				BeforeEach(func() {
+					fakeRecordSet.ExpandAliasesReturns([]string{"10.11.12.13", "upcheck.bosh-dns."})
+					fakeRecordSet.ResolveRecordsReturns(nil, records.CriteriaError)
+				})
+
+				It("returns no answers when no target is a record query", func() {
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "alias.internal.", dns.TypeTXT)
+					responseMsg := localDomain.Resolve(fakeWriter, req)
+
+					Expect(fakeRecordSet.ResolveRecordsCallCount()).To(Equal(1))
+					domains, _ := fakeRecordSet.ResolveRecordsArgsForCall(0)
+					Expect(domains).To(Equal([]string{"upcheck.bosh-dns."}))
+
+					Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(responseMsg.Answer).To(BeEmpty())
+				})
+
+				It("returns the metadata of the targets which are record queries", func() {
+					fakeRecordSet.ExpandAliasesReturns([]string{"10.11.12.13", "q-s0.group-1.network-name.deployment-name.bosh.", "upcheck.bosh-dns."})
+					fakeRecordSet.ResolveRecordsStub = func(domains []string, shouldTrack bool) ([]record.Record, error) {
+						if domains[0] != "q-s0.group-1.network-name.deployment-name.bosh." {
+							return nil, records.CriteriaError
+						}
+						return []record.Record{{ID: "instance-1", IP: "123.123.123.123"}}, nil
+					}
+
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "alias.internal.", dns.TypeTXT)
+					responseMsg := localDomain.Resolve(fakeWriter, req)
+
+					Expect(responseMsg.Rcode).To(Equal(dns.RcodeSuccess))
+					Expect(responseMsg.Answer).To(HaveLen(1))
+					Expect(responseMsg.Answer[0].(*dns.TXT).Txt).To(ContainElement("id=instance-1"))
+				})
+
+				It("returns rcode name error when no target matches a record", func() {
+					fakeRecordSet.ExpandAliasesReturns([]string{"q-s0.group-1.network-name.deployment-name.bosh.", "upcheck.bosh-dns."})
+					fakeRecordSet.ResolveRecordsStub = func(domains []string, shouldTrack bool) ([]record.Record, error) {
+						if domains[0] != "q-s0.group-1.network-name.deployment-name.bosh." {
+							return nil, records.CriteriaError
+						}
+						return nil, records.DomainError
+					}
+
+					req := &dns.Msg{}
+					SetQuestion(req, nil, "alias.internal.", dns.TypeTXT)
+					responseMsg := localDomain.Resolve(fakeWriter, req)
+
+					Expect(responseMsg.Rcode).To(Equal(dns.RcodeNameError))
+				})
+			})
+		})
+
 		Context("when loading the records returns criteria error", func() {
 			var dnsReturnCode int
 
 			BeforeEach(func() {
-				fakeRecordSet.ResolveReturns(nil, records.CriteriaError)
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.CriteriaError)
 
 				req := &dns.Msg{}
 				SetQuestion(req, nil, "instance-id-answer.group-1.network-name.deployment-name.bosh.", dns.TypeA)
@@ -302,7 +956,7 @@ var _ = Describe("LocalDomain", func() {
 			var dnsReturnCode int
 
 			BeforeEach(func() {
-				fakeRecordSet.ResolveReturns(nil, records.DomainError)
+				fakeRecordSet.ResolveReturns(records.Resolution{}, records.DomainError)
 
 				req := &dns.Msg{}
 				SetQuestion(req, nil, "instance-id-answer.group-1.network-name.deployment-name.bosh.", dns.TypeA)
@@ -330,7 +984,7 @@ var _ = Describe("LocalDomain", func() {
 			var dnsReturnCode int
 
 			BeforeEach(func() {
-				fakeRecordSet.ResolveReturns(nil, errors.New("i screwed up"))
+				fakeRecordSet.ResolveReturns(records.Resolution{}, errors.New("i screwed up"))
 
 				req := &dns.Msg{}
 				SetQuestion(req, nil, "instance-id-answer.group-1.network-name.deployment-name.bosh.", dns.TypeA)
@@ -355,3 +1009,11 @@ var _ = Describe("LocalDomain", func() {
 		})
 	})
 })
+
+func resolutionOf(ips ...string) records.Resolution {
+	resolution := records.Resolution{}
+	for _, ip := range ips {
+		resolution.Records = append(resolution.Records, record.Record{IP: ip})
+	}
+	return resolution
+}
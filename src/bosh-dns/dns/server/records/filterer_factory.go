@@ -18,7 +18,7 @@ type Filterer interface {
 
 //counterfeiter:generate . FiltererFactory
 type FiltererFactory interface {
-	NewHealthFilterer(healthChan chan record.Host, shouldTrack bool) Filterer
+	NewHealthFilterer(healthChan chan record.Host, shouldTrack bool, localAZ string) Filterer
 	NewQueryFilterer() Filterer
 }
 
@@ -27,8 +27,8 @@ type healthFiltererFactory struct {
 	synchronousCheckTimeout time.Duration
 }
 
-func (hff *healthFiltererFactory) NewHealthFilterer(healthChan chan record.Host, shouldTrack bool) Filterer {
-	hf := NewHealthFilter(hff.NewQueryFilterer(), healthChan, hff.healthWatcher, shouldTrack, clock.NewClock(), hff.synchronousCheckTimeout, &sync.WaitGroup{})
+func (hff *healthFiltererFactory) NewHealthFilterer(healthChan chan record.Host, shouldTrack bool, localAZ string) Filterer {
+	hf := NewHealthFilter(hff.NewQueryFilterer(), healthChan, hff.healthWatcher, shouldTrack, localAZ, clock.NewClock(), hff.synchronousCheckTimeout, &sync.WaitGroup{})
 	return &hf
 }
 
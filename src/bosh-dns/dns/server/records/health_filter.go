@@ -19,6 +19,7 @@ type healthFilter struct {
 	w                       healthWatcher
 	wg                      *sync.WaitGroup
 	shouldTrack             bool
+	localAZ                 string
 	domain                  string //nolint:deadcode,unused
 	filterWorkPool          *workpool.WorkPool
 	clock                   clock.Clock
@@ -35,7 +36,7 @@ type healthWatcher interface {
 	RunCheck(ip string) api.HealthResult
 }
 
-func NewHealthFilter(nextFilter Reducer, health chan<- record.Host, w healthWatcher, shouldTrack bool, clock clock.Clock, synchronousCheckTimeout time.Duration, wg *sync.WaitGroup) healthFilter {
+func NewHealthFilter(nextFilter Reducer, health chan<- record.Host, w healthWatcher, shouldTrack bool, localAZ string, clock clock.Clock, synchronousCheckTimeout time.Duration, wg *sync.WaitGroup) healthFilter {
 	wp, _ := workpool.NewWorkPool(1000)
 	return healthFilter{
 		nextFilter:              nextFilter,
@@ -43,6 +44,7 @@ func NewHealthFilter(nextFilter Reducer, health chan<- record.Host, w healthWatc
 		w:                       w,
 		wg:                      wg,
 		shouldTrack:             shouldTrack,
+		localAZ:                 localAZ,
 		filterWorkPool:          wp,
 		clock:                   clock,
 		synchronousCheckTimeout: synchronousCheckTimeout,
@@ -62,8 +64,8 @@ func (q *healthFilter) Filter(mm criteria.MatchMaker, recs []record.Record) []re
 	}
 
 	skipTracking := false
-	if healthStrategy == "0" && len(records) == 1 {
-		// if there's only 1 target the smart strategy will always return it, healthy or not
+	if (healthStrategy == "0" || healthStrategy == "5") && len(records) == 1 {
+		// if there's only 1 target the smart strategies will always return it, healthy or not
 		// there's no value in tracking the health for this fqdn
 		skipTracking = true
 	}
@@ -81,13 +83,44 @@ func (q *healthFilter) Filter(mm criteria.MatchMaker, recs []record.Record) []re
 		return healthyRecords
 	case "4": // all
 		return records
-	default: // smart strategy
-		if len(maybeHealthyRecords) == 0 {
-			return records
+	case "5": // healthy in the local AZ, then healthy elsewhere, then smart
+		localHealthyRecords := q.localAZRecords(healthyRecords)
+		if len(localHealthyRecords) > 0 {
+			return localHealthyRecords
+		}
+
+		if len(healthyRecords) > 0 {
+			return healthyRecords
 		}
 
-		return maybeHealthyRecords
+		return q.smartRecords(records, maybeHealthyRecords)
+	default: // smart strategy
+		return q.smartRecords(records, maybeHealthyRecords)
+	}
+}
+
+func (q *healthFilter) smartRecords(records, maybeHealthyRecords []record.Record) []record.Record {
+	if len(maybeHealthyRecords) == 0 {
+		return records
+	}
+
+	return maybeHealthyRecords
+}
+
+func (q *healthFilter) localAZRecords(records []record.Record) []record.Record {
+	var localRecords []record.Record
+
+	if q.localAZ == "" {
+		return localRecords
 	}
+
+	for _, r := range records {
+		if r.AZKey() == q.localAZ {
+			localRecords = append(localRecords, r)
+		}
+	}
+
+	return localRecords
 }
 
 func (q *healthFilter) processRecords(criteria criteria.Criteria, records []record.Record) {
@@ -28,6 +28,7 @@ var _ = Describe("HealthFilter", func() {
 		fakeFilter        *recordsfakes.FakeReducer
 		healthFilter      records.Reducer
 		shouldTrack       bool
+		localAZ           string
 		healthChan        chan record.Host
 		waitGroup         *sync.WaitGroup
 		fakeHealthWatcher *healthinessfakes.FakeHealthWatcher
@@ -45,10 +46,11 @@ var _ = Describe("HealthFilter", func() {
 		fakeHealthWatcher = &healthinessfakes.FakeHealthWatcher{}
 		fqdn = "my-domain.some.fqdn.bosh."
 		healthChan = make(chan record.Host, 2)
+		localAZ = ""
 
 		fakeHealthWatcher.HealthStateStub = func(ip string) api.HealthResult {
 			switch ip {
-			case "1.1.1.1":
+			case "1.1.1.1", "1.1.1.2":
 				return api.HealthResult{
 					State: api.StatusRunning,
 				}
@@ -71,7 +73,7 @@ var _ = Describe("HealthFilter", func() {
 	})
 
 	JustBeforeEach(func() {
-		hf := records.NewHealthFilter(fakeFilter, healthChan, fakeHealthWatcher, shouldTrack, clock, time.Second, waitGroup)
+		hf := records.NewHealthFilter(fakeFilter, healthChan, fakeHealthWatcher, shouldTrack, localAZ, clock, time.Second, waitGroup)
 		healthFilter = &hf
 		crit = criteria.Criteria{
 			"s":    []string{healthStrategy},
@@ -189,6 +191,70 @@ var _ = Describe("HealthFilter", func() {
 			)
 		})
 
+		Context("health strategy local AZ", func() {
+			var (
+				localHealthy    record.Record
+				localFailing    record.Record
+				remoteHealthy   record.Record
+				remoteFailing   record.Record
+				remoteUnknown   record.Record
+				remoteUnchecked record.Record
+			)
+
+			BeforeEach(func() {
+				healthStrategy = "5"
+				localAZ = "1"
+
+				localHealthy = record.Record{IP: "1.1.1.1", AZID: "1"}
+				localFailing = record.Record{IP: "2.2.2.2", AZID: "1"}
+				remoteHealthy = record.Record{IP: "1.1.1.2", AZID: "2"}
+				remoteFailing = record.Record{IP: "2.2.2.2", AZID: "2"}
+				remoteUnknown = record.Record{IP: "3.3.3.3", AZID: "2"}
+				remoteUnchecked = record.Record{IP: "4.4.4.4", AZID: "2"}
+			})
+
+			It("returns the healthy records in the local AZ", func() {
+				recs := []record.Record{localHealthy, localFailing, remoteHealthy}
+				fakeFilter.FilterReturns(recs)
+
+				Expect(healthFilter.Filter(crit, recs)).To(Equal([]record.Record{localHealthy}))
+			})
+
+			It("falls back to the healthy records in other AZs", func() {
+				recs := []record.Record{localFailing, remoteHealthy, remoteFailing}
+				fakeFilter.FilterReturns(recs)
+
+				Expect(healthFilter.Filter(crit, recs)).To(Equal([]record.Record{remoteHealthy}))
+			})
+
+			It("falls back to the smart strategy when no record is healthy", func() {
+				recs := []record.Record{localFailing, remoteUnknown, remoteUnchecked}
+				fakeFilter.FilterReturns(recs)
+
+				Expect(healthFilter.Filter(crit, recs)).To(Equal([]record.Record{remoteUnchecked}))
+			})
+
+			It("returns all records when none are healthy or unchecked", func() {
+				recs := []record.Record{localFailing, remoteUnknown}
+				fakeFilter.FilterReturns(recs)
+
+				Expect(healthFilter.Filter(crit, recs)).To(Equal(recs))
+			})
+
+			Context("when the local AZ is unknown", func() {
+				BeforeEach(func() {
+					localAZ = ""
+				})
+
+				It("returns all healthy records", func() {
+					recs := []record.Record{localHealthy, localFailing, remoteHealthy}
+					fakeFilter.FilterReturns(recs)
+
+					Expect(healthFilter.Filter(crit, recs)).To(Equal([]record.Record{localHealthy, remoteHealthy}))
+				})
+			})
+		})
+
 		Context("link health querying", func() {
 			Context("with healthy health-strategy", func() {
 
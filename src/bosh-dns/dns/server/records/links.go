@@ -0,0 +1,44 @@
+package records
+
+import (
+	"encoding/json"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/system"
+)
+
+// LinkDefinition describes a link provided by the instances whose group IDs
+// include GroupID, along with the port they serve it on. GroupID is the group
+// of the link's address, e.g. 7 for q-s0.q-g7.bosh.
+type LinkDefinition struct {
+	GroupID string `json:"group_id"`
+	Name    string `json:"name"`
+	Port    uint16 `json:"port,omitempty"`
+}
+
+// LinksFromGlob reads the link definitions listed by the files matching glob.
+// The records file written by the director names the groups of the links but
+// not their ports, so jobs declare them in such files.
+func LinksFromGlob(fs system.FileSystem, glob string) ([]LinkDefinition, error) {
+	files, err := fs.Glob(glob)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "glob pattern failed to compute")
+	}
+
+	links := []LinkDefinition{}
+	for _, linksFile := range files {
+		contents, err := fs.ReadFile(linksFile)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "missing links file")
+		}
+
+		var fileLinks []LinkDefinition
+		err = json.Unmarshal(contents, &fileLinks)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "links file malformed: %s", linksFile)
+		}
+		links = append(links, fileLinks...)
+	}
+
+	return links, nil
+}
@@ -0,0 +1,53 @@
+package records_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-utils/system/fakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"bosh-dns/dns/server/records"
+)
+
+var _ = Describe("LinksFromGlob", func() {
+	var fs *fakes.FakeFileSystem
+
+	BeforeEach(func() {
+		fs = fakes.NewFakeFileSystem()
+	})
+
+	It("merges the links of every matching file", func() {
+		fs.SetGlob("/jobs/*/dns/links.json", []string{"/jobs/web/dns/links.json", "/jobs/metrics/dns/links.json"})
+		Expect(fs.WriteFileString("/jobs/web/dns/links.json", `[{"group_id": "1", "name": "http", "port": 8080}]`)).To(Succeed())
+		Expect(fs.WriteFileString("/jobs/metrics/dns/links.json", `[{"group_id": "2", "name": "metrics", "port": 9100}]`)).To(Succeed())
+
+		links, err := records.LinksFromGlob(fs, "/jobs/*/dns/links.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(links).To(Equal([]records.LinkDefinition{
+			{GroupID: "1", Name: "http", Port: 8080},
+			{GroupID: "2", Name: "metrics", Port: 9100},
+		}))
+	})
+
+	It("returns no links when no file matches", func() {
+		links, err := records.LinksFromGlob(fs, "/jobs/*/dns/links.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(links).To(BeEmpty())
+	})
+
+	It("returns an error when the glob fails", func() {
+		fs.GlobStub = func(string) ([]string, error) { return nil, errors.New("bad glob") }
+
+		_, err := records.LinksFromGlob(fs, "/jobs/*/dns/links.json")
+		Expect(err).To(MatchError(ContainSubstring("bad glob")))
+	})
+
+	It("returns an error when a file is malformed", func() {
+		fs.SetGlob("/jobs/*/dns/links.json", []string{"/jobs/web/dns/links.json"})
+		Expect(fs.WriteFileString("/jobs/web/dns/links.json", `{"group_id": "1"`)).To(Succeed())
+
+		_, err := records.LinksFromGlob(fs, "/jobs/*/dns/links.json")
+		Expect(err).To(MatchError(ContainSubstring("links file malformed: /jobs/web/dns/links.json")))
+	})
+})
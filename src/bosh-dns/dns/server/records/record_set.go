@@ -26,6 +26,64 @@ type AliasDefinition struct {
 	PlaceholderType    string `json:"placeholder_type"`
 	HealthFilter       string `json:"health_filter"`
 	InitialHealthCheck string `json:"initial_health_check"`
+	Port               uint16 `json:"port,omitempty"`
+	TTL                uint32 `json:"ttl,omitempty"`
+	Order              string `json:"order,omitempty"`
+	Limit              uint   `json:"limit,omitempty"`
+}
+
+// Order is the way answers to a query are arranged.
+type Order int
+
+const (
+	// RandomOrder shuffles the answers uniformly.
+	RandomOrder Order = iota
+	// WeightedOrder shuffles the answers, favouring instances with a higher
+	// weight in the records file.
+	WeightedOrder
+	// AZAffineOrder puts the instances of the querying VM's AZ first, each
+	// part shuffled as with WeightedOrder.
+	AZAffineOrder
+	// StickyOrder ranks the answers by rendezvous hashing on the address of
+	// the client, so that it keeps preferring the same instances.
+	StickyOrder
+)
+
+// QueryOptions are the answer options requested by the segments of a query.
+type QueryOptions struct {
+	Order Order
+	// Limit is the maximum number of answers, no limit applying when zero
+	Limit uint
+	// TTL is the TTL of the answers, when HasTTL is set
+	TTL    uint32
+	HasTTL bool
+}
+
+// Resolution is what a name resolves to: the records matching the queries it
+// expands to, the addresses it is aliased to directly and the answer options
+// requested by its queries.
+type Resolution struct {
+	Records  []record.Record
+	AliasIPs []string
+	QueryOptions
+}
+
+// IPs returns the addresses of the records followed by the addresses the name
+// is aliased to directly.
+func (r Resolution) IPs() []string {
+	ips := make([]string, 0, len(r.Records)+len(r.AliasIPs))
+	for _, rec := range r.Records {
+		ips = append(ips, rec.IP)
+	}
+	return append(ips, r.AliasIPs...)
+}
+
+// ServiceRecord is a record matched by a service (SRV) lookup along with the
+// port advertised for it by the query, alias definition or its links. Port is
+// zero when none of them advertises one.
+type ServiceRecord struct {
+	record.Record
+	Port uint16
 }
 
 type recordGroup map[*record.Record]struct{} //nolint:deadcode,unused
@@ -48,22 +106,27 @@ type RecordSet struct {
 	trackerSubscription chan []record.Record
 	filtererFactory     FiltererFactory
 	aliasQueryEncoder   AliasQueryEncoder
+	localIPs            []string
+	links               []LinkDefinition
 
 	domains []string
 	records []record.Record
 	hosts   []record.Host
 	version uint64
+	localAZ string
 }
 
 func NewRecordSet(
 	recordFileReader FileReader,
 	aliasList aliases.Config,
+	links []LinkDefinition,
 	healthWatcher healthiness.HealthWatcher,
 	maximumTrackedDomains uint,
 	shutdownChan chan struct{},
 	logger boshlog.Logger,
 	filtererFactory FiltererFactory,
 	AliasQueryEncoder AliasQueryEncoder,
+	localIPs []string,
 ) (*RecordSet, error) {
 	r := &RecordSet{
 		recordFileReader:    recordFileReader,
@@ -75,6 +138,8 @@ func NewRecordSet(
 		healthChan:          make(chan record.Host, 2),
 		trackerSubscription: make(chan []record.Record),
 		filtererFactory:     filtererFactory,
+		localIPs:            localIPs,
+		links:               links,
 	}
 
 	trackedDomains := tracker.NewPriorityLimitedTranscript(maximumTrackedDomains)
@@ -124,7 +189,9 @@ func (r *RecordSet) Subscribe() <-chan bool {
 	return c
 }
 
-func (r *RecordSet) Resolve(fqdnRaw string) ([]string, error) {
+// Resolve returns the records and addresses fqdn resolves to, along with the
+// answer options requested by its queries.
+func (r *RecordSet) Resolve(fqdnRaw string) (Resolution, error) {
 	r.recordsMutex.RLock()
 	defer r.recordsMutex.RUnlock()
 
@@ -141,38 +208,208 @@ func (r *RecordSet) Resolve(fqdnRaw string) ([]string, error) {
 		}
 	}
 
-	finalRecords, err := r.unsafeResolveRecords(aliasExpansions, true)
+	allCriteria, err := r.parseCriteria(aliasExpansions)
+	if err != nil {
+		r.logger.Debug("RecordSet", "Error parsing domains %v: %v", aliasExpansions, err)
+		return Resolution{}, CriteriaError
+	}
+
+	finalRecords, err := r.unsafeFilterRecords(aliasExpansions, allCriteria, true)
 	if err != nil {
 		if !errors.Is(err, DomainError) || len(aliasIPs) == 0 {
-			return nil, err
+			return Resolution{}, err
+		}
+	}
+
+	return Resolution{
+		Records:      finalRecords,
+		AliasIPs:     aliasIPs,
+		QueryOptions: queryOptions(allCriteria),
+	}, nil
+}
+
+// ResolveServices returns the records fqdn resolves to along with the port of
+// service on them. A port requested by the query or its alias definition
+// overrides the ports of the links the records provide.
+func (r *RecordSet) ResolveServices(service, fqdnRaw string) ([]ServiceRecord, error) {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
+
+	var fqdn string = strings.ToLower(fqdnRaw)
+
+	domains := []string{}
+	for _, expansion := range r.unsafeExpandAliases(fqdn) {
+		if net.ParseIP(expansion) == nil {
+			domains = append(domains, expansion)
+		}
+	}
+	r.logger.Debug("RecordSet", "Expand service %s to %v", fqdn, domains)
+
+	allCriteria, err := r.parseCriteria(domains)
+	if err != nil {
+		r.logger.Debug("RecordSet", "Error parsing domains %v: %v", domains, err)
+		return nil, CriteriaError
+	}
+
+	domainFilter := r.filtererFactory.NewQueryFilterer()
+	healthFilter := r.filtererFactory.NewHealthFilterer(r.healthChan, true, r.localAZ)
+
+	matchedDomain := false
+	services := []ServiceRecord{}
+	for _, crit := range allCriteria {
+		domainRecords := domainFilter.Filter(crit, r.records)
+		if len(domainRecords) == 0 {
+			continue
+		}
+		matchedDomain = true
+
+		port := servicePort(crit)
+		for _, rec := range healthFilter.Filter(crit, domainRecords) {
+			recPort := port
+			if recPort == 0 {
+				recPort = r.linkPort(rec, crit["g"], service)
+			}
+			services = append(services, ServiceRecord{Record: rec, Port: recPort})
 		}
 	}
 
-	finalIPs := make([]string, len(finalRecords))
-	for i, rec := range finalRecords {
-		finalIPs[i] = rec.IP
+	if !matchedDomain {
+		r.logger.Debug("RecordSet", "No records match domains %v", domains)
+		return nil, DomainError
 	}
-	finalIPs = append(finalIPs, aliasIPs...)
 
-	return finalIPs, nil
+	return services, nil
 }
 
-func (r *RecordSet) ResolveRecords(domains []string, shouldTrack bool) ([]record.Record, error) {
+// QueryTTL returns the TTL requested by the `t` segment of the query fqdn
+// resolves to. When several queries declare one, the lowest TTL wins.
+func (r *RecordSet) QueryTTL(fqdnRaw string) (uint32, bool) {
 	r.recordsMutex.RLock()
 	defer r.recordsMutex.RUnlock()
 
-	return r.unsafeResolveRecords(domains, shouldTrack)
+	allCriteria, err := r.parseCriteria(r.unsafeExpandAliases(strings.ToLower(fqdnRaw)))
+	if err != nil {
+		return 0, false
+	}
+
+	options := queryOptions(allCriteria)
+	return options.TTL, options.HasTTL
 }
 
-func (r *RecordSet) unsafeResolveRecords(domains []string, shouldTrack bool) ([]record.Record, error) {
-	domainFilter := r.filtererFactory.NewQueryFilterer()
-	healthFilter := r.filtererFactory.NewHealthFilterer(r.healthChan, shouldTrack)
+// queryOptions returns the answer options requested by the segments of the
+// queries. The first query declaring an order wins, and the lowest limit and
+// TTL win when several queries declare one.
+func queryOptions(allCriteria []criteria.Criteria) QueryOptions {
+	options := QueryOptions{Order: RandomOrder}
+	orderSet := false
+
+	for _, crit := range allCriteria {
+		if order, found := criteriaValue(crit, "o", 8); found && !orderSet {
+			switch order {
+			case 1:
+				options.Order, orderSet = WeightedOrder, true
+			case 2:
+				options.Order, orderSet = AZAffineOrder, true
+			case 3:
+				options.Order, orderSet = StickyOrder, true
+			}
+		}
+
+		if limit, found := criteriaValue(crit, "c", 32); found && (options.Limit == 0 || uint(limit) < options.Limit) {
+			options.Limit = uint(limit)
+		}
+
+		if ttl, found := criteriaValue(crit, "t", 32); found && (!options.HasTTL || uint32(ttl) < options.TTL) {
+			options.TTL, options.HasTTL = uint32(ttl), true
+		}
+	}
+
+	return options
+}
+
+func criteriaValue(crit criteria.Criteria, key string, bitSize int) (uint64, bool) {
+	if len(crit[key]) == 0 {
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(crit[key][0], 10, bitSize)
+	if err != nil {
+		return 0, false
+	}
+
+	return value, true
+}
+
+func servicePort(crit criteria.Criteria) uint16 {
+	if len(crit["p"]) == 0 {
+		return 0
+	}
+
+	port, err := strconv.ParseUint(crit["p"][0], 10, 16)
+	if err != nil {
+		return 0
+	}
+
+	return uint16(port)
+}
+
+// linkPort returns the port of the link a record provides, restricted to the
+// queried groups if any. A link named after the service is preferred; other
+// links only count when they agree on the port.
+func (r *RecordSet) linkPort(rec record.Record, groupIDs []string, service string) uint16 {
+	var port uint16
+	ambiguous := false
+
+	for _, link := range r.links {
+		if link.Port == 0 || !contains(rec.GroupIDs, link.GroupID) {
+			continue
+		}
+		if len(groupIDs) > 0 && !contains(groupIDs, link.GroupID) {
+			continue
+		}
+
+		if link.Name == service {
+			return link.Port
+		}
+
+		if port != 0 && port != link.Port {
+			ambiguous = true
+		}
+		port = link.Port
+	}
+
+	if ambiguous {
+		return 0
+	}
+	return port
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RecordSet) ResolveRecords(domains []string, shouldTrack bool) ([]record.Record, error) {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
 
 	allCriteria, err := r.parseCriteria(domains)
 	if err != nil {
 		r.logger.Debug("RecordSet", "Error parsing domains %v: %v", domains, err)
 		return nil, CriteriaError
 	}
+
+	return r.unsafeFilterRecords(domains, allCriteria, shouldTrack)
+}
+
+func (r *RecordSet) unsafeFilterRecords(domains []string, allCriteria []criteria.Criteria, shouldTrack bool) ([]record.Record, error) {
+	domainFilter := r.filtererFactory.NewQueryFilterer()
+	healthFilter := r.filtererFactory.NewHealthFilterer(r.healthChan, shouldTrack, r.localAZ)
+
 	domainRecords := r.filterRecords(domainFilter, allCriteria, r.records)
 	if len(domainRecords) == 0 {
 		r.logger.Debug("RecordSet", "No records match domains %v", domains)
@@ -272,6 +509,23 @@ func (r *RecordSet) GetFQDNs(ip string) []string {
 	return fqdns
 }
 
+// LocalAZ returns the AZ of the record for one of the addresses of this VM,
+// or an empty string when there is none.
+func (r *RecordSet) LocalAZ() string {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
+
+	return r.localAZ
+}
+
+// Version returns the version of the records file currently loaded.
+func (r *RecordSet) Version() uint64 {
+	r.recordsMutex.RLock()
+	defer r.recordsMutex.RUnlock()
+
+	return r.version
+}
+
 func (r *RecordSet) Domains() []string {
 	r.recordsMutex.RLock()
 	defer r.recordsMutex.RUnlock()
@@ -299,6 +553,10 @@ func (r *RecordSet) update() {
 	r.version = version
 	r.records = records
 	r.hosts = hosts
+	r.localAZ = localAZ(records, r.localIPs)
+	if r.localAZ == "" && hasAZ(records) {
+		r.logger.Info("RecordSet", "No record with an AZ matches the addresses %v of this VM, queries preferring the local AZ use every AZ", r.localIPs)
+	}
 
 	r.mergedAliasList = aliases.NewConfig().Merge(r.aliasList).Merge(updatedAliases)
 
@@ -316,6 +574,47 @@ func (r *RecordSet) update() {
 	}
 }
 
+func localAZ(records []record.Record, localIPs []string) string {
+	for _, rec := range records {
+		for _, ip := range localIPs {
+			if rec.IP == ip && rec.AZKey() != "" {
+				return rec.AZKey()
+			}
+		}
+	}
+
+	return ""
+}
+
+func hasAZ(records []record.Record) bool {
+	for _, rec := range records {
+		if rec.AZKey() != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InterfaceIPs returns the addresses of the network interfaces of this VM,
+// which the record of its instance is addressed by. The addresses bosh-dns
+// listens on are usually link-local ones which no record holds.
+func InterfaceIPs() ([]string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	ips := []string{}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP.String())
+		}
+	}
+
+	return ips, nil
+}
+
 //counterfeiter:generate . AliasQueryEncoder
 type AliasQueryEncoder interface {
 	EncodeAliasesIntoQueries([]record.Record, map[string][]AliasDefinition) map[string][]string
@@ -353,6 +652,7 @@ func createFromJSON(j []byte, logger boshlog.Logger, aliasEncoder AliasQueryEnco
 	instanceIndexIndex := -1
 	groupIdsIndex := -1
 	agentIdIndex := -1
+	weightIndex := -1
 
 	for i, k := range swap.Keys {
 		switch k {
@@ -382,6 +682,8 @@ func createFromJSON(j []byte, logger boshlog.Logger, aliasEncoder AliasQueryEnco
 			instanceIndexIndex = i
 		case "agent_id":
 			agentIdIndex = i
+		case "weight":
+			weightIndex = i
 		default:
 			continue
 		}
@@ -430,6 +732,9 @@ func createFromJSON(j []byte, logger boshlog.Logger, aliasEncoder AliasQueryEnco
 		}
 
 		assertStringIntegerValue(&record.InstanceIndex, info, instanceIndexIndex, "instance_index", index, logger)
+		if weightIndex >= 0 && info[weightIndex] != nil {
+			assertStringIntegerValue(&record.Weight, info, weightIndex, "weight", index, logger)
+		}
 
 		records = append(records, record)
 	}
@@ -84,7 +84,7 @@ var _ = Describe("Record Set Performance", func() {
 
 		fileReader.GetReturns(jsonBytes, nil)
 
-		recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, filtererFactory, fakeAliasQueryEncoder)
+		recordSet, err = records.NewRecordSet(fileReader, aliasList, nil, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, filtererFactory, fakeAliasQueryEncoder, nil)
 
 		Expect(err).ToNot(HaveOccurred())
 	})
@@ -96,18 +96,18 @@ var _ = Describe("Record Set Performance", func() {
 
 		for count = 0; count < 1000; count++ {
 			startTime := time.Now()
-			ips, err := recordSet.Resolve("q-m0s0.my-group.my-network.my-deployment.domain.")
+			resolution, err := recordSet.Resolve("q-m0s0.my-group.my-network.my-deployment.domain.")
 			totalTime += time.Since(startTime)
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(ips).To(HaveExactElements("123.123.1.0"))
+			Expect(resolution.IPs()).To(HaveExactElements("123.123.1.0"))
 
 			startTime = time.Now()
-			ips, err = recordSet.Resolve("q-m1999s0.my-group.my-network.my-deployment.domain.")
+			resolution, err = recordSet.Resolve("q-m1999s0.my-group.my-network.my-deployment.domain.")
 			totalTimeLastRecord += time.Since(startTime)
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(ips).To(HaveExactElements("123.123.208.207"))
+			Expect(resolution.IPs()).To(HaveExactElements("123.123.208.207"))
 		}
 
 		averageTime := totalTime.Microseconds() / int64(count)
@@ -41,6 +41,7 @@ var _ = Describe("RecordSet", func() {
 		fakeLogger            *fakes.FakeLogger
 		fileReader            *recordsfakes.FakeFileReader
 		aliasList             aliases.Config
+		links                 []records.LinkDefinition
 		shutdownChan          chan struct{}
 		fakeHealthWatcher     *healthinessfakes.FakeHealthWatcher
 		fakeQueryFilterer     *recordsfakes.FakeFilterer
@@ -60,6 +61,7 @@ var _ = Describe("RecordSet", func() {
 		fakeAliasQueryEncoder = &recordsfakes.FakeAliasQueryEncoder{}
 
 		aliasList = mustNewConfigFromMap(map[string][]string{})
+		links = nil
 		fakeHealthWatcher = &healthinessfakes.FakeHealthWatcher{}
 		shutdownChan = make(chan struct{})
 		fakeHealthWatcher.HealthStateReturns(api.HealthResult{State: api.StatusRunning})
@@ -86,7 +88,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -121,6 +123,30 @@ var _ = Describe("RecordSet", func() {
 			})
 		})
 
+		Context("when the records json includes weight", func() {
+			BeforeEach(func() {
+				jsonBytes := []byte(`{
+									"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain", "weight"],
+									"record_infos": [
+										["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "domain.", 10],
+										["instance1", "my-group", "my-network", "my-deployment", "123.123.123.124", "domain.", null]
+									]
+								}`)
+				fileReader.GetReturns(jsonBytes, nil)
+
+				var err error
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("parses the weight", func() {
+				recs := recordSet.AllRecords()
+				Expect(recs).To(HaveLen(2))
+				Expect(recs[0].Weight).To(Equal("10"))
+				Expect(recs[1].Weight).To(Equal(""))
+			})
+		})
+
 		Context("when the records json does not include instance_index", func() {
 			BeforeEach(func() {
 				jsonBytes := []byte(`{
@@ -138,7 +164,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -283,7 +309,7 @@ var _ = Describe("RecordSet", func() {
 			fileReader.GetReturns(jsonBytes, nil)
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -315,7 +341,7 @@ var _ = Describe("RecordSet", func() {
 			fileReader.GetReturns(jsonBytes, nil)
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recordSet.Domains()).To(ConsistOf("withadot.", "nodot.", "domain.", "alias1."))
@@ -341,7 +367,7 @@ var _ = Describe("RecordSet", func() {
 			fileReader.GetReturns(jsonBytes, nil)
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recordSet.HasIP("123.123.123.123")).To(Equal(true))
@@ -377,7 +403,7 @@ var _ = Describe("RecordSet", func() {
 			fileReader.GetReturns(jsonBytes, nil)
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(recordSet.GetFQDNs("123.123.123.123")).To(ConsistOf("alias1.", "instance0.my-group.my-network.my-deployment.withadot.", "0.my-group.my-network.my-deployment.withadot."))
@@ -409,7 +435,7 @@ var _ = Describe("RecordSet", func() {
 			})
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			_, err = recordSet.Resolve("instance0.my-group.my-network.my-deployment.bosh.")
@@ -419,7 +445,7 @@ var _ = Describe("RecordSet", func() {
 			Expect(recs).To(HaveLen(1))
 			Expect(recs[0].IP).To(Equal("123.123.123.123"))
 
-			_, shouldTrack := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
+			_, shouldTrack, _ := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
 			Expect(shouldTrack).To(BeTrue())
 
 			Expect(err).NotTo(HaveOccurred())
@@ -572,12 +598,12 @@ var _ = Describe("RecordSet", func() {
 					fileReader.GetReturns(jsonBytes, nil)
 
 					var err error
-					recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+					recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 					Expect(err).ToNot(HaveOccurred())
 
 					_, err = recordSet.Resolve("q-s0.my-group.my-network.my-deployment.my-domain.")
 					Expect(err).ToNot(HaveOccurred())
-					_, shouldTrack := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
+					_, shouldTrack, _ := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
 					Expect(shouldTrack).To(BeTrue())
 					_, recs := fakeHealthFilterer.FilterArgsForCall(0)
 					Expect(recs).To(HaveLen(1))
@@ -619,7 +645,7 @@ var _ = Describe("RecordSet", func() {
 					fileReader.GetReturns(jsonBytes, nil)
 
 					var err error
-					recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+					recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 					Expect(err).ToNot(HaveOccurred())
 				})
@@ -628,7 +654,7 @@ var _ = Describe("RecordSet", func() {
 					_, err := recordSet.Resolve("q-s0.my-group.my-network.my-deployment.my-domain.")
 					Expect(err).ToNot(HaveOccurred())
 
-					_, shouldTrack := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
+					_, shouldTrack, _ := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
 					Expect(shouldTrack).To(BeTrue())
 					_, recs := fakeHealthFilterer.FilterArgsForCall(0)
 
@@ -669,7 +695,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				_, err = recordSet.ResolveRecords([]string{"dummy.my-group.my-network.my-deployment.bosh."}, true)
@@ -696,7 +722,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).NotTo(HaveOccurred())
 
 				_, err = recordSet.ResolveRecords([]string{"dummy.my-group.my-network.my-deployment.bosh."}, true)
@@ -738,7 +764,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				_, err = recordSet.ResolveRecords([]string{"dummy.my-group.my-network.my-deployment.bosh."}, true)
@@ -781,7 +807,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 
 				_, err = recordSet.ResolveRecords([]string{"dummy.my-group.my-network.my-deployment.bosh."}, true)
@@ -823,7 +849,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 				_, err = recordSet.ResolveRecords([]string{"dummy.my-group.my-network.my-deployment.bosh."}, true)
 				Expect(err).ToNot(HaveOccurred())
@@ -847,16 +873,16 @@ var _ = Describe("RecordSet", func() {
 			fileReader.GetReturns(jsonBytes, nil)
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
 		Context("when fqdn is already an IP address", func() {
 			It("return the IP back", func() {
-				records, err := recordSet.Resolve("123.123.123.123")
+				resolution, err := recordSet.Resolve("123.123.123.123")
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(records).To(ContainElement("123.123.123.123"))
+				Expect(resolution.IPs()).To(ContainElement("123.123.123.123"))
 			})
 		})
 
@@ -882,10 +908,10 @@ var _ = Describe("RecordSet", func() {
 				}
 			})
 			It("returns the IP", func() {
-				records, err := recordSet.Resolve("alias.my.")
+				resolution, err := recordSet.Resolve("alias.my.")
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(records).To(ContainElement("1.2.3.4"))
+				Expect(resolution.IPs()).To(ContainElement("1.2.3.4"))
 			})
 		})
 	})
@@ -903,7 +929,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -949,7 +975,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -971,7 +997,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -994,7 +1020,7 @@ var _ = Describe("RecordSet", func() {
 				fileReader.GetReturns(jsonBytes, nil)
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 				Expect(err).ToNot(HaveOccurred())
 			})
 
@@ -1005,6 +1031,318 @@ var _ = Describe("RecordSet", func() {
 		})
 	})
 
+	Describe("ResolveServices", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys":
+					["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+				"record_infos": [
+					["instance0", "0", "my-group", ["1"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 1]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+			links = []records.LinkDefinition{
+				{GroupID: "2", Name: "http", Port: 7070},
+			}
+			fakeHealthFilterer.FilterStub = func(mm criteria.MatchMaker, recs []record.Record) []record.Record {
+				return recs
+			}
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the matching records with the port from the query", func() {
+			services, err := recordSet.ResolveServices("http", "q-p8080s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(services).To(HaveLen(1))
+			Expect(services[0].ID).To(Equal("instance0"))
+			Expect(services[0].IP).To(Equal("123.123.123.123"))
+			Expect(services[0].Port).To(Equal(uint16(8080)))
+		})
+
+		It("returns a zero port when neither the query nor a link declares one", func() {
+			services, err := recordSet.ResolveServices("http", "q-s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(services).To(HaveLen(1))
+			Expect(services[0].Port).To(Equal(uint16(0)))
+		})
+
+		Context("when the ports of the links of the records are declared", func() {
+			BeforeEach(func() {
+				jsonBytes := []byte(`{
+					"record_keys":
+						["id", "num_id", "instance_group", "group_ids", "az", "az_id", "network", "network_id", "deployment", "ip", "domain", "instance_index"],
+					"record_infos": [
+						["instance0", "0", "my-group", ["1", "2"], "az1", "1", "my-network", "1", "my-deployment", "123.123.123.123", "my-domain", 1]
+					]
+				}`)
+				fileReader.GetReturns(jsonBytes, nil)
+				links = []records.LinkDefinition{
+					{GroupID: "1", Name: "http", Port: 8443},
+					{GroupID: "2", Name: "metrics", Port: 9100},
+					{GroupID: "3", Name: "other", Port: 1234},
+				}
+			})
+
+			It("takes the port of the link named after the service", func() {
+				services, err := recordSet.ResolveServices("metrics", "q-s0.my-group.my-network.my-deployment.my-domain.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(services).To(HaveLen(1))
+				Expect(services[0].Port).To(Equal(uint16(9100)))
+			})
+
+			It("takes the port of the link of the queried group", func() {
+				services, err := recordSet.ResolveServices("grpc", "q-s0.q-g2.my-domain.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(services).To(HaveLen(1))
+				Expect(services[0].Port).To(Equal(uint16(9100)))
+			})
+
+			It("returns a zero port when the links disagree on the port", func() {
+				services, err := recordSet.ResolveServices("grpc", "q-s0.my-group.my-network.my-deployment.my-domain.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(services).To(HaveLen(1))
+				Expect(services[0].Port).To(Equal(uint16(0)))
+			})
+
+			It("prefers the port from the query", func() {
+				services, err := recordSet.ResolveServices("http", "q-p8080s0.my-group.my-network.my-deployment.my-domain.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(services).To(HaveLen(1))
+				Expect(services[0].Port).To(Equal(uint16(8080)))
+			})
+		})
+
+		Context("when the fqdn is an alias", func() {
+			BeforeEach(func() {
+				aliasList = mustNewConfigFromMap(map[string][]string{
+					"alias.my.": {"q-p9090s0.my-group.my-network.my-deployment.my-domain.", "1.2.3.4"},
+				})
+			})
+
+			It("resolves the alias and ignores addresses", func() {
+				services, err := recordSet.ResolveServices("http", "alias.my.")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(services).To(HaveLen(1))
+				Expect(services[0].ID).To(Equal("instance0"))
+				Expect(services[0].Port).To(Equal(uint16(9090)))
+			})
+		})
+
+		Context("when there are no records matching the domain", func() {
+			BeforeEach(func() {
+				fakeQueryFilterer.FilterStub = func(mm criteria.MatchMaker, recs []record.Record) []record.Record {
+					return []record.Record{}
+				}
+			})
+
+			It("returns DomainError", func() {
+				_, err := recordSet.ResolveServices("http", "q-p8080s0.my-group.my-network.my-deployment.my-domain.")
+				Expect(err).To(MatchError(records.DomainError))
+			})
+		})
+
+		Context("when query can't be parsed", func() {
+			It("returns CriteriaError", func() {
+				_, err := recordSet.ResolveServices("http", "notaquery")
+				Expect(err).To(MatchError(records.CriteriaError))
+			})
+		})
+	})
+
+	Describe("QueryTTL", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+			aliasList = mustNewConfigFromMap(map[string][]string{
+				"alias.my.": {
+					"q-s0t60.my-group.my-network.my-deployment.my-domain.",
+					"q-s0t30.my-group.my-network.my-deployment.my-domain.",
+					"1.2.3.4",
+				},
+			})
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the TTL from the query", func() {
+			ttl, found := recordSet.QueryTTL("q-s0t120.my-group.my-network.my-deployment.my-domain.")
+			Expect(found).To(BeTrue())
+			Expect(ttl).To(Equal(uint32(120)))
+		})
+
+		It("returns the lowest TTL of the alias expansions", func() {
+			ttl, found := recordSet.QueryTTL("alias.my.")
+			Expect(found).To(BeTrue())
+			Expect(ttl).To(Equal(uint32(30)))
+		})
+
+		It("reports when the query does not declare a TTL", func() {
+			_, found := recordSet.QueryTTL("q-s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("LocalAZ", func() {
+		var localIPs []string
+
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "az", "az_id", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "z1", "1", "my-network", "my-deployment", "123.123.123.123", "my-domain"],
+					["instance1", "my-group", "z2", "2", "my-network", "my-deployment", "123.123.123.124", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+			localIPs = []string{"169.254.0.2", "123.123.123.124"}
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, localIPs)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the AZ of the record for a local address", func() {
+			Expect(recordSet.LocalAZ()).To(Equal("2"))
+			Expect(fakeLogger.InfoCallCount()).To(BeZero())
+		})
+
+		It("passes the local AZ to the health filter", func() {
+			_, err := recordSet.Resolve("q-s5.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _, localAZ := fakeFiltererFactory.NewHealthFiltererArgsForCall(0)
+			Expect(localAZ).To(Equal("2"))
+		})
+
+		Context("when no record matches a local address", func() {
+			BeforeEach(func() {
+				localIPs = []string{"169.254.0.2"}
+			})
+
+			It("returns an empty AZ", func() {
+				Expect(recordSet.LocalAZ()).To(Equal(""))
+			})
+
+			It("reports that the local AZ is unknown", func() {
+				messages := []string{}
+				for i := 0; i < fakeLogger.InfoCallCount(); i++ {
+					logTag, msg, logArgs := fakeLogger.InfoArgsForCall(i)
+					messages = append(messages, logTag+": "+fmt.Sprintf(msg, logArgs...))
+				}
+
+				Expect(messages).To(ContainElement("RecordSet: No record with an AZ matches the addresses [169.254.0.2] of this VM, queries preferring the local AZ use every AZ"))
+			})
+		})
+	})
+
+	Describe("InterfaceIPs", func() {
+		It("returns the addresses of the network interfaces", func() {
+			ips, err := records.InterfaceIPs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ips).To(ContainElement("127.0.0.1"))
+		})
+	})
+
+	Describe("Version", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "my-domain"]
+				],
+				"Version": 42
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the version of the records file", func() {
+			Expect(recordSet.Version()).To(Equal(uint64(42)))
+		})
+	})
+
+	Describe("query options", func() {
+		BeforeEach(func() {
+			jsonBytes := []byte(`{
+				"record_keys": ["id", "instance_group", "network", "deployment", "ip", "domain"],
+				"record_infos": [
+					["instance0", "my-group", "my-network", "my-deployment", "123.123.123.123", "my-domain"]
+				]
+			}`)
+			fileReader.GetReturns(jsonBytes, nil)
+			aliasList = mustNewConfigFromMap(map[string][]string{
+				"alias.my.": {
+					"1.2.3.4",
+					"q-c5s0.my-group.my-network.my-deployment.my-domain.",
+					"q-c3o2t60s0.my-group.my-network.my-deployment.my-domain.",
+					"q-o1t30s0.my-group.my-network.my-deployment.my-domain.",
+				},
+			})
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns the options requested by the query", func() {
+			resolution, err := recordSet.Resolve("q-c10o3t120s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution.QueryOptions).To(Equal(records.QueryOptions{
+				Order:  records.StickyOrder,
+				Limit:  10,
+				TTL:    120,
+				HasTTL: true,
+			}))
+
+			resolution, err = recordSet.Resolve("q-o1s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution.Order).To(Equal(records.WeightedOrder))
+		})
+
+		It("returns the first order and the lowest limit and TTL of the alias expansions", func() {
+			resolution, err := recordSet.Resolve("alias.my.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution.QueryOptions).To(Equal(records.QueryOptions{
+				Order:  records.AZAffineOrder,
+				Limit:  3,
+				TTL:    30,
+				HasTTL: true,
+			}))
+			Expect(resolution.AliasIPs).To(Equal([]string{"1.2.3.4"}))
+		})
+
+		It("defaults to a random order without limit or TTL", func() {
+			resolution, err := recordSet.Resolve("q-s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution.QueryOptions).To(Equal(records.QueryOptions{Order: records.RandomOrder}))
+
+			resolution, err = recordSet.Resolve("q-o9s0.my-group.my-network.my-deployment.my-domain.")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolution.Order).To(Equal(records.RandomOrder))
+		})
+	})
+
 	Context("aliases", func() {
 		Context("when the aliases are provided are seeded", func() {
 			BeforeEach(func() {
@@ -1046,7 +1384,7 @@ var _ = Describe("RecordSet", func() {
 				}
 
 				var err error
-				recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+				recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 				Expect(err).ToNot(HaveOccurred())
 			})
@@ -1065,7 +1403,7 @@ var _ = Describe("RecordSet", func() {
 					resolutions, err := recordSet.Resolve("q-s0.alias2.")
 
 					Expect(err).ToNot(HaveOccurred())
-					Expect(resolutions).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+					Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
 				})
 
 				It("returns a non successful return code when a resolution fails", func() {
@@ -1081,7 +1419,7 @@ var _ = Describe("RecordSet", func() {
 					resolutions, err := recordSet.Resolve("alias2.")
 
 					Expect(err).ToNot(HaveOccurred())
-					Expect(resolutions).To(Equal([]string{"1.1.1.1"}))
+					Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1"}))
 				})
 
 				Context("when alias points to an IP directly", func() {
@@ -1089,7 +1427,7 @@ var _ = Describe("RecordSet", func() {
 						resolutions, err := recordSet.Resolve("ipalias.")
 
 						Expect(err).ToNot(HaveOccurred())
-						Expect(resolutions).To(Equal([]string{"5.5.5.5"}))
+						Expect(resolutions.IPs()).To(Equal([]string{"5.5.5.5"}))
 					})
 				})
 
@@ -1098,7 +1436,7 @@ var _ = Describe("RecordSet", func() {
 						resolutions, err := recordSet.Resolve("alias1.")
 
 						Expect(err).ToNot(HaveOccurred())
-						Expect(resolutions).To(Equal([]string{"3.3.3.3", "4.4.4.4"}))
+						Expect(resolutions.IPs()).To(Equal([]string{"3.3.3.3", "4.4.4.4"}))
 					})
 
 					Context("and a subset of the resolutions fails", func() {
@@ -1106,7 +1444,7 @@ var _ = Describe("RecordSet", func() {
 							resolutions, err := recordSet.Resolve("aliaswithonefailure.")
 
 							Expect(err).ToNot(HaveOccurred())
-							Expect(resolutions).To(Equal([]string{"3.3.3.3"}))
+							Expect(resolutions.IPs()).To(Equal([]string{"3.3.3.3"}))
 						})
 					})
 				})
@@ -1165,7 +1503,7 @@ var _ = Describe("RecordSet", func() {
 						}
 
 						var err error
-						recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+						recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 						Expect(err).ToNot(HaveOccurred())
 					})
@@ -1184,7 +1522,7 @@ var _ = Describe("RecordSet", func() {
 							resolutions, err := recordSet.Resolve("q-s0.alias2.")
 
 							Expect(err).ToNot(HaveOccurred())
-							Expect(resolutions).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
+							Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1", "2.2.2.2"}))
 						})
 
 						It("returns a non successful return code when a resolution fails", func() {
@@ -1200,7 +1538,7 @@ var _ = Describe("RecordSet", func() {
 							resolutions, err := recordSet.Resolve("alias2.")
 
 							Expect(err).ToNot(HaveOccurred())
-							Expect(resolutions).To(Equal([]string{"1.1.1.1"}))
+							Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1"}))
 						})
 
 						Context("when the alias is global", func() {
@@ -1208,7 +1546,7 @@ var _ = Describe("RecordSet", func() {
 								resolutions, err := recordSet.Resolve("globalalias.")
 
 								Expect(err).ToNot(HaveOccurred())
-								Expect(resolutions).To(Equal([]string{"1.1.1.1"}))
+								Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1"}))
 							})
 						})
 
@@ -1229,7 +1567,7 @@ var _ = Describe("RecordSet", func() {
 								resolutions, err := recordSet.Resolve("filteredalias.")
 
 								Expect(err).ToNot(HaveOccurred())
-								Expect(resolutions).To(Equal([]string{"1.1.1.1"}))
+								Expect(resolutions.IPs()).To(Equal([]string{"1.1.1.1"}))
 							})
 						})
 
@@ -1238,7 +1576,7 @@ var _ = Describe("RecordSet", func() {
 								resolutions, err := recordSet.Resolve("ipalias.")
 
 								Expect(err).ToNot(HaveOccurred())
-								Expect(resolutions).To(Equal([]string{"5.5.5.5"}))
+								Expect(resolutions.IPs()).To(Equal([]string{"5.5.5.5"}))
 							})
 						})
 
@@ -1247,7 +1585,7 @@ var _ = Describe("RecordSet", func() {
 								resolutions, err := recordSet.Resolve("alias1.")
 
 								Expect(err).ToNot(HaveOccurred())
-								Expect(resolutions).To(Equal([]string{"3.3.3.3", "4.4.4.4"}))
+								Expect(resolutions.IPs()).To(Equal([]string{"3.3.3.3", "4.4.4.4"}))
 							})
 
 							Context("and a subset of the resolutions fails", func() {
@@ -1255,7 +1593,7 @@ var _ = Describe("RecordSet", func() {
 									resolutions, err := recordSet.Resolve("aliaswithonefailure.")
 
 									Expect(err).ToNot(HaveOccurred())
-									Expect(resolutions).To(Equal([]string{"3.3.3.3"}))
+									Expect(resolutions.IPs()).To(Equal([]string{"3.3.3.3"}))
 								})
 							})
 
@@ -1307,7 +1645,7 @@ var _ = Describe("RecordSet", func() {
 			}
 
 			var err error
-			recordSet, err = records.NewRecordSet(fileReader, aliasList, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder)
+			recordSet, err = records.NewRecordSet(fileReader, aliasList, links, fakeHealthWatcher, uint(5), shutdownChan, fakeLogger, fakeFiltererFactory, fakeAliasQueryEncoder, nil)
 
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -1370,9 +1708,9 @@ var _ = Describe("RecordSet", func() {
 				go func() {
 					defer GinkgoRecover()
 					for j := 0; j < 10; j++ {
-						ips, err := recordSet.Resolve("a1.internal.")
+						resolution, err := recordSet.Resolve("a1.internal.")
 						Expect(err).ToNot(HaveOccurred())
-						Expect(ips).To(ConsistOf("123.123.123.123", "123.123.123.124"))
+						Expect(resolution.IPs()).To(ConsistOf("123.123.123.123", "123.123.123.124"))
 					}
 					wg.Done()
 				}()
@@ -8,11 +8,12 @@ import (
 )
 
 type FakeFiltererFactory struct {
-	NewHealthFiltererStub        func(chan record.Host, bool) records.Filterer
+	NewHealthFiltererStub        func(chan record.Host, bool, string) records.Filterer
 	newHealthFiltererMutex       sync.RWMutex
 	newHealthFiltererArgsForCall []struct {
 		arg1 chan record.Host
 		arg2 bool
+		arg3 string
 	}
 	newHealthFiltererReturns struct {
 		result1 records.Filterer
@@ -34,19 +35,20 @@ type FakeFiltererFactory struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeFiltererFactory) NewHealthFilterer(arg1 chan record.Host, arg2 bool) records.Filterer {
+func (fake *FakeFiltererFactory) NewHealthFilterer(arg1 chan record.Host, arg2 bool, arg3 string) records.Filterer {
 	fake.newHealthFiltererMutex.Lock()
 	ret, specificReturn := fake.newHealthFiltererReturnsOnCall[len(fake.newHealthFiltererArgsForCall)]
 	fake.newHealthFiltererArgsForCall = append(fake.newHealthFiltererArgsForCall, struct {
 		arg1 chan record.Host
 		arg2 bool
-	}{arg1, arg2})
+		arg3 string
+	}{arg1, arg2, arg3})
 	stub := fake.NewHealthFiltererStub
 	fakeReturns := fake.newHealthFiltererReturns
-	fake.recordInvocation("NewHealthFilterer", []interface{}{arg1, arg2})
+	fake.recordInvocation("NewHealthFilterer", []interface{}{arg1, arg2, arg3})
 	fake.newHealthFiltererMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2)
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
@@ -60,17 +62,17 @@ func (fake *FakeFiltererFactory) NewHealthFiltererCallCount() int {
 	return len(fake.newHealthFiltererArgsForCall)
 }
 
-func (fake *FakeFiltererFactory) NewHealthFiltererCalls(stub func(chan record.Host, bool) records.Filterer) {
+func (fake *FakeFiltererFactory) NewHealthFiltererCalls(stub func(chan record.Host, bool, string) records.Filterer) {
 	fake.newHealthFiltererMutex.Lock()
 	defer fake.newHealthFiltererMutex.Unlock()
 	fake.NewHealthFiltererStub = stub
 }
 
-func (fake *FakeFiltererFactory) NewHealthFiltererArgsForCall(i int) (chan record.Host, bool) {
+func (fake *FakeFiltererFactory) NewHealthFiltererArgsForCall(i int) (chan record.Host, bool, string) {
 	fake.newHealthFiltererMutex.RLock()
 	defer fake.newHealthFiltererMutex.RUnlock()
 	argsForCall := fake.newHealthFiltererArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeFiltererFactory) NewHealthFiltererReturns(result1 records.Filterer) {
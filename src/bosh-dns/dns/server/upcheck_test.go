@@ -67,7 +67,7 @@ var _ = Describe("Upcheck", func() {
 		ports = map[string]int{}
 		addresses = map[string]string{}
 		listenDomain = "127.0.0.1"
-		dnsHandler = handlers.NewUpcheckHandler(&boshlogf.FakeLogger{})
+		dnsHandler = handlers.NewUpcheckHandler(&boshlogf.FakeLogger{}, 0)
 	})
 
 	Context("when the upcheck target is a malformed address", func() {
@@ -115,7 +115,7 @@ var _ = Describe("DNS", func() {
 			healthWatcher := &healthinessfakes.FakeHealthWatcher{}
 			fs := boshsys.NewOsFileSystem(logger)
 			recordSetReader := records.NewFileReader("assets/records.json", fs, clock.NewClock(), logger, signal)
-			recordSet, err := records.NewRecordSet(recordSetReader, aliases.NewConfig(), healthWatcher, uint(5), shutdown, logger)
+			recordSet, err := records.NewRecordSet(recordSetReader, aliases.NewConfig(), nil, healthWatcher, uint(5), shutdown, logger, records.NewHealthFiltererFactory(healthWatcher, time.Second), records.NewAliasEncoder(), []string{})
 			Expect(err).ToNot(HaveOccurred())
 			Expect(recordSet.AllRecords()).To(HaveLen(102))
 
@@ -0,0 +1,105 @@
+package performance_test
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCP forwarding", func() {
+	var (
+		dnsServerAddress  = "127.0.0.2:9953"
+		durationInSeconds = 60 * 10
+		workers           = 10
+		requestsPerSecond = 400
+
+		upstream            *dns.Server
+		upstreamConnections *countingListener
+	)
+
+	BeforeEach(func() {
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", upstreamPort))
+		Expect(err).NotTo(HaveOccurred())
+		upstreamConnections = &countingListener{Listener: listener}
+
+		upstream = &dns.Server{
+			Listener: upstreamConnections,
+			Net:      "tcp",
+			Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+				m := &dns.Msg{}
+				m.SetReply(req)
+				m.Answer = []dns.RR{&dns.A{
+					Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 0},
+					A:   net.ParseIP("10.0.0.1"),
+				}}
+				w.WriteMsg(m) //nolint:errcheck
+			}),
+		}
+		go upstream.ActivateAndServe() //nolint:errcheck
+
+		setupServers()
+	})
+
+	AfterEach(func() {
+		shutdownServers()
+		Expect(upstream.Shutdown()).To(Succeed())
+	})
+
+	It("reuses upstream connections for queries received over TCP", func() {
+		PerformanceTest{
+			Application:       "dns",
+			Context:           "tcp-forwarding",
+			Workers:           workers,
+			RequestsPerSecond: requestsPerSecond,
+
+			ServerPID: dnsSession.Command.Process.Pid,
+
+			TimeThresholds:   tcpForwardingTimeThresholds(),
+			VitalsThresholds: tcpForwardingVitalsThresholds(),
+			SuccessStatus:    dns.RcodeSuccess,
+
+			WorkerFunc: func(resultChan chan<- Result) {
+				MakeTCPDNSRequest(dnsServerAddress, resultChan)
+			},
+		}.Setup().TestPerformance(durationInSeconds, "tcp forwarding")
+
+		Expect(upstreamConnections.Accepted()).To(BeNumerically("<=", 2))
+	})
+})
+
+func MakeTCPDNSRequest(server string, result chan<- Result) {
+	defer GinkgoRecover()
+	c := &dns.Client{Net: "tcp", Timeout: 3 * time.Second}
+	m := new(dns.Msg)
+	m.SetQuestion(fmt.Sprintf("host-%d.pipelined.internal.", time.Now().UnixNano()), dns.TypeA)
+
+	startTime := time.Now()
+	r, _, err := c.Exchange(m, server)
+	if err != nil {
+		Fail(fmt.Sprintf("failed TCP DNS request via server %s: %s", server, err.Error()))
+	}
+
+	result <- Result{status: r.Rcode, time: time.Now().Unix(), metricName: "response_time", value: time.Since(startTime)}
+}
+
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+func (l *countingListener) Accepted() int32 {
+	return atomic.LoadInt32(&l.accepted)
+}
@@ -31,6 +31,7 @@ var (
 	healthPort       = 8853
 	dnsPort          = 9953
 	apiPort          = 10053
+	upstreamPort     = 9954
 )
 
 func setupServers() {
@@ -61,11 +62,12 @@ func setupServers() {
 			"certificate_file": "../dns/api/assets/test_certs/test_server.pem",
 			"private_key_file": "../dns/api/assets/test_certs/test_server.key",
 		},
-		"records_file":     "assets/records.json",
-		"alias_files_glob": "assets/aliases.json",
-		"upcheck_domains":  []string{"upcheck.bosh-dns."},
-		"recursors":        []string{"34.194.75.123"},
-		"recursor_timeout": "2s",
+		"records_file":        "assets/records.json",
+		"alias_files_glob":    "assets/aliases.json",
+		"handlers_files_glob": "assets/handlers.json",
+		"upcheck_domains":     []string{"upcheck.bosh-dns."},
+		"recursors":           []string{"34.194.75.123"},
+		"recursor_timeout":    "2s",
 		"health": map[string]interface{}{
 			"enabled":          true,
 			"port":             healthPort,
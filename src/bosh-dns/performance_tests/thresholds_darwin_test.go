@@ -57,3 +57,19 @@ func upcheckTimeThresholds() TimeThresholds {
 		Pct95: 2 * time.Millisecond,
 	}
 }
+
+func tcpForwardingVitalsThresholds() VitalsThresholds {
+	return VitalsThresholds{
+		CPUPct99: 10,
+		MemPct99: 25,
+		MemMax:   27,
+	}
+}
+
+func tcpForwardingTimeThresholds() TimeThresholds {
+	return TimeThresholds{
+		Med:   2 * time.Millisecond,
+		Pct90: 3 * time.Millisecond,
+		Pct95: 4 * time.Millisecond,
+	}
+}
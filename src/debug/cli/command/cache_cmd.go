@@ -0,0 +1,153 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cloudfoundry/bosh-cli/v7/ui"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	"bosh-dns/dns/api"
+	"bosh-dns/tlsclient"
+)
+
+type CacheCmd struct {
+	Args               CacheArgs `positional-args:"true"`
+	API                string    `long:"api" env:"DNS_API_ADDRESS" description:"API address to talk to"`
+	TLSCACertPath      string    `long:"ca-cert-path" env:"DNS_API_TLS_CA_CERT_PATH" description:"CA certificate to use for mutual LS"`
+	TLSCertificatePath string    `long:"certificate-path" env:"DNS_API_TLS_CERTIFICATE_PATH" description:"Client certificate to use for mutual LS"`
+	TLSPrivateKeyPath  string    `long:"private-key-path" env:"DNS_API_TLS_PRIVATE_KEY_PATH" description:"Client key to use for mutual LS"`
+
+	UI ui.UI
+}
+
+type CacheArgs struct {
+	Name string `positional-arg-name:"NAME" description:"Only show the cached answers for this name"`
+}
+
+func (o *CacheCmd) Execute(args []string) error {
+	logger := boshlog.NewLogger(boshlog.LevelNone)
+	if o.UI == nil {
+		confUI := ui.NewConfUI(logger)
+		confUI.EnableColor()
+		o.UI = confUI
+	}
+
+	client, err := tlsclient.NewFromFiles("api.bosh-dns", o.TLSCACertPath, o.TLSCertificatePath, o.TLSPrivateKeyPath, 5*time.Second, logger)
+	if err != nil {
+		return err
+	}
+
+	requestURL := o.API + "/cache"
+
+	if o.Args.Name != "" {
+		requestURL = requestURL + "?" + url.Values{"name": {o.Args.Name}}.Encode()
+	}
+
+	response, err := client.Get(requestURL)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to retrieve cache entries: Got %s", response.Status)
+	}
+
+	table := boshtbl.Table{
+		Title: "Cached DNS answers",
+		Header: []boshtbl.Header{
+			boshtbl.NewHeader("Name"),
+			boshtbl.NewHeader("Type"),
+			boshtbl.NewHeader("Rcode"),
+			boshtbl.NewHeader("TTL"),
+			boshtbl.NewHeader("Stale"),
+			boshtbl.NewHeader("Records"),
+		},
+	}
+
+	decoder := json.NewDecoder(response.Body)
+
+	for decoder.More() {
+		var jsonRow api.CacheEntry
+
+		err := decoder.Decode(&jsonRow)
+		if err != nil {
+			return err
+		}
+
+		table.Rows = append(table.Rows, []boshtbl.Value{
+			boshtbl.NewValueString(jsonRow.Name),
+			boshtbl.NewValueString(jsonRow.Type),
+			boshtbl.NewValueString(jsonRow.Rcode),
+			boshtbl.NewValueInt(jsonRow.TTL),
+			boshtbl.NewValueBool(jsonRow.Stale),
+			boshtbl.NewValueStrings(jsonRow.Records),
+		})
+	}
+
+	o.UI.PrintTable(table)
+
+	return nil
+}
+
+type FlushCacheCmd struct {
+	Name               string `long:"name" description:"Only flush the cached answers for this name"`
+	Domain             string `long:"domain" description:"Only flush the cached answers for this domain and its subdomains"`
+	API                string `long:"api" env:"DNS_API_ADDRESS" description:"API address to talk to"`
+	TLSCACertPath      string `long:"ca-cert-path" env:"DNS_API_TLS_CA_CERT_PATH" description:"CA certificate to use for mutual LS"`
+	TLSCertificatePath string `long:"certificate-path" env:"DNS_API_TLS_CERTIFICATE_PATH" description:"Client certificate to use for mutual LS"`
+	TLSPrivateKeyPath  string `long:"private-key-path" env:"DNS_API_TLS_PRIVATE_KEY_PATH" description:"Client key to use for mutual LS"`
+
+	UI ui.UI
+}
+
+func (o *FlushCacheCmd) Execute(args []string) error {
+	logger := boshlog.NewLogger(boshlog.LevelNone)
+	if o.UI == nil {
+		confUI := ui.NewConfUI(logger)
+		confUI.EnableColor()
+		o.UI = confUI
+	}
+
+	if o.Name != "" && o.Domain != "" {
+		return fmt.Errorf("only one of --name and --domain may be given")
+	}
+
+	client, err := tlsclient.NewFromFiles("api.bosh-dns", o.TLSCACertPath, o.TLSCertificatePath, o.TLSPrivateKeyPath, 5*time.Second, logger)
+	if err != nil {
+		return err
+	}
+
+	requestURL := o.API + "/cache"
+
+	if o.Name != "" {
+		requestURL = requestURL + "?" + url.Values{"name": {o.Name}}.Encode()
+	} else if o.Domain != "" {
+		requestURL = requestURL + "?" + url.Values{"domain": {o.Domain}}.Encode()
+	}
+
+	response, err := client.Delete(requestURL)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to flush cache: Got %s", response.Status)
+	}
+
+	var flush api.CacheFlush
+	err = json.NewDecoder(response.Body).Decode(&flush)
+	if err != nil {
+		return err
+	}
+
+	o.UI.PrintLinef("Flushed %d cached answers", flush.Flushed)
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package command_test
+
+import (
+	"net/http"
+
+	uifakes "github.com/cloudfoundry/bosh-cli/v7/ui/fakes"
+	boshtbl "github.com/cloudfoundry/bosh-cli/v7/ui/table"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+
+	"debug/cli/command"
+)
+
+var _ = Describe("CacheCmd", func() {
+	var (
+		server *ghttp.Server
+		ui     *uifakes.FakeUI
+		cmd    command.CacheCmd
+	)
+
+	BeforeEach(func() {
+		server = newFakeAPIServer()
+
+		ui = &uifakes.FakeUI{}
+		cmd = command.CacheCmd{
+			UI:                 ui,
+			API:                server.URL(),
+			TLSCACertPath:      "../../../bosh-dns/dns/api/assets/test_certs/test_ca.pem",
+			TLSCertificatePath: "../../../bosh-dns/dns/api/assets/test_certs/test_wrong_cn_client.pem",
+			TLSPrivateKeyPath:  "../../../bosh-dns/dns/api/assets/test_certs/test_client.key",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when the DNS server responds with some cached answers", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/cache"),
+					ghttp.RespondWith(http.StatusOK, `
+						{"name": "missing.example.com.", "type": "A", "rcode": "NXDOMAIN", "ttl": 5, "records": []}
+						{"name": "www.example.com.", "type": "A", "rcode": "NOERROR", "ttl": 0, "stale": true, "records": ["www.example.com.\t60\tIN\tA\t10.0.0.1"]}
+					`),
+				),
+			)
+		})
+
+		It("formats the contents like a table", func() {
+			Expect(cmd.Execute(nil)).To(Succeed())
+
+			Expect(ui.Table).To(Equal(boshtbl.Table{
+				Title: "Cached DNS answers",
+				Header: []boshtbl.Header{
+					boshtbl.NewHeader("Name"),
+					boshtbl.NewHeader("Type"),
+					boshtbl.NewHeader("Rcode"),
+					boshtbl.NewHeader("TTL"),
+					boshtbl.NewHeader("Stale"),
+					boshtbl.NewHeader("Records"),
+				},
+				Rows: [][]boshtbl.Value{
+					{
+						boshtbl.NewValueString("missing.example.com."),
+						boshtbl.NewValueString("A"),
+						boshtbl.NewValueString("NXDOMAIN"),
+						boshtbl.NewValueInt(5),
+						boshtbl.NewValueBool(false),
+						boshtbl.NewValueStrings([]string{}),
+					},
+					{
+						boshtbl.NewValueString("www.example.com."),
+						boshtbl.NewValueString("A"),
+						boshtbl.NewValueString("NOERROR"),
+						boshtbl.NewValueInt(0),
+						boshtbl.NewValueBool(true),
+						boshtbl.NewValueStrings([]string{"www.example.com.\t60\tIN\tA\t10.0.0.1"}),
+					},
+				},
+			}))
+		})
+	})
+
+	Context("when a name arg is given", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/cache", "name=www.example.com"),
+					ghttp.RespondWith(http.StatusOK, ""),
+				),
+			)
+		})
+
+		It("includes the name as a query param", func() {
+			cmd.Args = command.CacheArgs{Name: "www.example.com"}
+			Expect(cmd.Execute(nil)).To(Succeed())
+			Expect(ui.Table).To(BeAssignableToTypeOf(boshtbl.Table{}))
+		})
+	})
+
+	Context("when the server does not respond 200", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/cache"),
+					ghttp.RespondWith(http.StatusNotFound, []byte{}),
+				),
+			)
+		})
+
+		It("raises an error", func() {
+			Expect(cmd.Execute(nil)).ToNot(Succeed())
+		})
+	})
+})
+
+var _ = Describe("FlushCacheCmd", func() {
+	var (
+		server *ghttp.Server
+		ui     *uifakes.FakeUI
+		cmd    command.FlushCacheCmd
+	)
+
+	BeforeEach(func() {
+		server = newFakeAPIServer()
+
+		ui = &uifakes.FakeUI{}
+		cmd = command.FlushCacheCmd{
+			UI:                 ui,
+			API:                server.URL(),
+			TLSCACertPath:      "../../../bosh-dns/dns/api/assets/test_certs/test_ca.pem",
+			TLSCertificatePath: "../../../bosh-dns/dns/api/assets/test_certs/test_wrong_cn_client.pem",
+			TLSPrivateKeyPath:  "../../../bosh-dns/dns/api/assets/test_certs/test_client.key",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("flushes everything", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/cache", ""),
+				ghttp.RespondWith(http.StatusOK, `{"flushed": 3}`),
+			),
+		)
+
+		Expect(cmd.Execute(nil)).To(Succeed())
+		Expect(ui.Said).To(Equal([]string{"Flushed 3 cached answers"}))
+	})
+
+	It("flushes a single name", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/cache", "name=www.example.com"),
+				ghttp.RespondWith(http.StatusOK, `{"flushed": 1}`),
+			),
+		)
+
+		cmd.Name = "www.example.com"
+		Expect(cmd.Execute(nil)).To(Succeed())
+		Expect(ui.Said).To(Equal([]string{"Flushed 1 cached answers"}))
+	})
+
+	It("flushes a domain", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/cache", "domain=example.com"),
+				ghttp.RespondWith(http.StatusOK, `{"flushed": 2}`),
+			),
+		)
+
+		cmd.Domain = "example.com"
+		Expect(cmd.Execute(nil)).To(Succeed())
+		Expect(ui.Said).To(Equal([]string{"Flushed 2 cached answers"}))
+	})
+
+	It("rejects both a name and a domain", func() {
+		cmd.Name = "www.example.com"
+		cmd.Domain = "example.com"
+		Expect(cmd.Execute(nil)).ToNot(Succeed())
+		Expect(server.ReceivedRequests()).To(BeEmpty())
+	})
+
+	It("raises an error when the server does not respond 200", func() {
+		server.AppendHandlers(
+			ghttp.CombineHandlers(
+				ghttp.VerifyRequest("DELETE", "/cache"),
+				ghttp.RespondWith(http.StatusNotFound, []byte{}),
+			),
+		)
+
+		Expect(cmd.Execute(nil)).ToNot(Succeed())
+	})
+})
@@ -7,6 +7,8 @@ import (
 type Commands struct {
 	Instances   InstancesCmd   `command:"instances" description:"Show known instances"`
 	LocalGroups LocalGroupsCmd `command:"local-groups" description:"Show health status and link details for groups local to the current instance"`
+	Cache       CacheCmd       `command:"cache" description:"Show the cached answers to recursive queries"`
+	FlushCache  FlushCacheCmd  `command:"flush-cache" description:"Flush the cached answers to recursive queries"`
 
 	UI ui.UI
 }
@@ -147,6 +147,55 @@ var _ = Describe("Main", func() {
 			Expect(session.Out).To(HaveTableRow("consul", "agent", "conn", "6", "-"))
 		})
 	})
+
+	Describe("cache", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("GET", "/cache"),
+					ghttp.RespondWith(http.StatusOK, `
+							{
+								"name": "www.example.com.",
+								"type": "A",
+								"rcode": "NOERROR",
+								"ttl": 42,
+								"records": ["www.example.com.\t60\tIN\tA\t10.0.0.1"]
+							}
+						`),
+				),
+			)
+		})
+
+		It("renders the cached answers", func() {
+			cmd := exec.Command(pathToCli, "cache")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), string(session.Err.Contents()))
+			Expect(session.Out).To(HaveTableRow("Name", "Type", "Rcode", "TTL", "Stale", "Records"))
+			Expect(session.Out).To(HaveTableRow(`www\.example\.com\.`, "A", "NOERROR", "42", "false", `www\.example\.com\.\s+60\s+IN\s+A\s+10\.0\.0\.1`))
+		})
+	})
+
+	Describe("flush-cache", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest("DELETE", "/cache", "domain=example.com"),
+					ghttp.RespondWith(http.StatusOK, `{"flushed": 2}`),
+				),
+			)
+		})
+
+		It("flushes the cached answers", func() {
+			cmd := exec.Command(pathToCli, "flush-cache", "--domain", "example.com")
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0), string(session.Err.Contents()))
+			Expect(session.Out).To(gbytes.Say("Flushed 2 cached answers"))
+		})
+	})
 })
 
 func newFakeAPIServer() *ghttp.Server {